@@ -1,46 +1,89 @@
 package cmd
 
 import (
-	"bytes"
+	"cronitor/lib/api"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var before string
 var only string
+var activitySelection monitorSelection
+var activitySince string
+var activityUntil string
+var activityEvents []string
+var activityHosts []string
+var activityLimit int
+var activityFollow bool
+var activityInterval time.Duration
+var activityJson bool
 
 var activityCmd = &cobra.Command{
-	Use:   "activity",
+	Use:   "activity [code...]",
 	Short: "View monitor activity",
 	Long: `
-View monitor pings and alerts
+View monitor pings and alerts as a timeline, oldest first, in local time. Older pages of activity are requested
+automatically until --since or --limit is reached.
 
 Examples:
-  View combined pings and alerts in reverse chronological order:
+  View the latest pings and alerts for a monitor:
   $ cronitor activity d3x0c1
 
   View only alerts:
   $ cronitor activity d3x0c1 --only alerts
 
-  View only pings:
-  $ cronitor activity d3x0c1 --only pings
+  View failures from the last day for every monitor tagged "database", merged into one timeline:
+  $ cronitor activity --tag database --since 24h --event fail
+
+  View pings from web1 during a maintenance window:
+  $ cronitor activity d3x0c1 --only pings --host web1 --since 2020-06-01T22:00:00Z --until 2020-06-01T23:00:00Z
 
-  View only pings before a certain timestamp:
-  $ cronitor activity d3x0c1 --only pings --before 1510971199.905
+  Keep printing new activity as it happens:
+  $ cronitor activity d3x0c1 --follow
+      > New events are checked for every 10 seconds; use --interval to change this
+
+  Print the events as JSON:
+  $ cronitor activity d3x0c1 --since 7d --limit 0 --json
+      > --since and --until accept a duration before now like 30m, 24h or 7d, a date like 2020-06-01, an RFC3339 time,
+        or a unix timestamp. --limit 0 removes the limit on the number of events.
 `,
 
 	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 1 {
-			return errors.New("a unique monitor code is required")
+		if len(args) < 1 && !activitySelection.hasFilters() {
+			return errors.New("a monitor code, --tag, --name or --this-host is required")
 		}
 
 		if len(only) > 0 && !isValidOnlyFilter() {
 			return errors.New("invalid argument supplied to 'only'. Expecting 'pings' or 'alerts'")
 		}
 
+		if _, err := createActivityOptions(); err != nil {
+			return err
+		}
+
+		if _, err := createActivityQuery(time.Now()); err != nil {
+			return err
+		}
+
+		if activityFollow && (len(activityUntil) > 0 || len(before) > 0) {
+			return errors.New("--follow cannot be used with --until")
+		}
+
+		if activityInterval < time.Second {
+			return errors.New("--interval must be at least 1s")
+		}
+
 		if len(viper.GetString(varApiKey)) < 10 {
 			return errors.New("you must provide an API key with this command or save a key using 'cronitor configure'")
 		}
@@ -49,48 +92,392 @@ Examples:
 	},
 
 	Run: func(cmd *cobra.Command, args []string) {
-		url := createActivityApiUrl(args[0])
-		response, err := getCronitorApi().GetRawResponse(url)
+		options, _ := createActivityOptions()
+		query, _ := createActivityQuery(time.Now())
+
+		monitors, err := selectMonitors(args, activitySelection)
 		if err != nil {
-			fatal(fmt.Sprintf("Request to %s failed: %s", url, err), 1)
+			fatal(err.Error(), 1)
 		}
 
-		buf := new(bytes.Buffer)
-		json.Indent(buf, response, "", "  ")
-		fmt.Println(url)
-		if bufString := buf.String(); bufString != "[]" {
-			fmt.Println(bufString)
-		} else {
+		if len(monitors) == 0 {
+			printWarningText("No monitors matched", false)
+			return
+		}
+
+		events, err := fetchActivity(getCronitorApi(), monitors, options, query)
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		labels := activityLabels(monitors)
+		if activityFollow {
+			printFollowedActivity(os.Stdout, events, labels)
+		} else if activityJson {
+			printActivityJson(events)
+		} else if len(events) == 0 {
 			fmt.Println("No activity")
+		} else {
+			printActivity(os.Stdout, events, labels)
+		}
+
+		if !activityFollow {
+			return
+		}
+
+		// Follow from the newest event seen for each monitor, or from now if a monitor had no activity
+		since := map[string]float64{}
+		for _, event := range events {
+			since[event.Monitor.Key] = event.Event.Stamp
+		}
+		for _, monitor := range monitors {
+			if _, ok := since[monitor.Key]; !ok {
+				since[monitor.Key] = float64(time.Now().Unix())
+			}
+		}
+
+		for waitForNextPoll(activityInterval) {
+
+			var newEvents []monitorEvent
+			for _, monitor := range monitors {
+				followQuery := query
+				followQuery.Since = since[monitor.Key]
+				followQuery.Limit = 0
+				events, err := fetchActivity(getCronitorApi(), []api.Monitor{monitor}, options, followQuery)
+				if apiContext().Err() != nil {
+					return
+				}
+				if err != nil {
+					printErrorText("Could not check for new activity: "+err.Error(), false)
+					continue
+				}
+				for _, event := range events {
+					if event.Event.Stamp > followQuery.Since {
+						newEvents = append(newEvents, event)
+						if event.Event.Stamp > since[monitor.Key] {
+							since[monitor.Key] = event.Event.Stamp
+						}
+					}
+				}
+			}
+
+			sortActivity(newEvents)
+			printFollowedActivity(os.Stdout, newEvents, labels)
 		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(activityCmd)
+	addMonitorSelectionFlags(activityCmd, &activitySelection)
 	activityCmd.Flags().StringVar(&only, "only", only, "Accepted values: pings, alerts")
+	activityCmd.Flags().StringVar(&activitySince, "since", "", "Show events after this time, e.g. 24h, 7d, 2020-06-01 or 2020-06-01T22:00:00Z")
+	activityCmd.Flags().StringVar(&activityUntil, "until", "", "Show events before this time, in the same formats as --since")
+	activityCmd.Flags().StringSliceVar(&activityEvents, "event", nil, "Show only this type of event, e.g. run, complete, fail or alert. Can be used more than once.")
+	activityCmd.Flags().StringSliceVar(&activityHosts, "host", nil, "Show only events from hosts matching this pattern, e.g. 'web*'. Can be used more than once.")
+	activityCmd.Flags().IntVar(&activityLimit, "limit", 100, "Show at most this many of the latest events, or 0 for no limit")
+	activityCmd.Flags().BoolVarP(&activityFollow, "follow", "f", false, "Keep checking for new events until interrupted")
+	activityCmd.Flags().DurationVar(&activityInterval, "interval", 10*time.Second, "With --follow, how often to check for new events")
+	activityCmd.Flags().BoolVar(&activityJson, "json", false, "Print the events as JSON, or with --follow, one JSON object per line")
 	activityCmd.Flags().StringVar(&before, "before", before, "Return events before provided timestamp")
+	activityCmd.Flags().MarkDeprecated("before", "use --until instead")
+}
+
+// activityQuery narrows the events fetched for each monitor. Since and Until are unix timestamps, and zero when not set.
+type activityQuery struct {
+	Since  float64
+	Until  float64
+	Events []string
+	Hosts  []string
+	Limit  int
+}
+
+// monitorEvent is an event together with the monitor it belongs to, so activity from many monitors can be merged
+type monitorEvent struct {
+	Monitor api.Monitor
+	Event   api.Event
 }
 
-func createActivityApiUrl(uniqueIdentifier string) string {
-	endpoint := "activity"
+func (e monitorEvent) json() interface{} {
+	return struct {
+		Monitor string `json:"monitor"`
+		api.Event
+	}{e.Monitor.Code, e.Event}
+}
+
+func createActivityOptions() (api.ActivityOptions, error) {
+	options := api.ActivityOptions{Kind: api.ACTIVITY_ALL}
 	if len(only) > 0 {
-		endpoint = only
+		options.Kind = only
 	}
 
-	beforeFilter := ""
 	if len(before) > 0 {
-		beforeFilter = fmt.Sprintf("?before=%s", before)
+		stamp, err := strconv.ParseFloat(before, 64)
+		if err != nil {
+			return options, errors.New("invalid argument supplied to 'before'. Expecting a timestamp like 1510971199.905")
+		}
+		options.Before = stamp
+	}
+
+	return options, nil
+}
+
+func createActivityQuery(now time.Time) (activityQuery, error) {
+	query := activityQuery{Events: activityEvents, Hosts: activityHosts, Limit: activityLimit}
+	var err error
+
+	if query.Since, err = parseActivityTime(activitySince, now); err != nil {
+		return query, errors.New(fmt.Sprintf("invalid --since %s: %s", activitySince, err.Error()))
+	}
+
+	if query.Until, err = parseActivityTime(activityUntil, now); err != nil {
+		return query, errors.New(fmt.Sprintf("invalid --until %s: %s", activityUntil, err.Error()))
+	}
+
+	if query.Since > 0 && query.Until > 0 && query.Since >= query.Until {
+		return query, errors.New("--since must be before --until")
+	}
+
+	if query.Limit < 0 {
+		return query, errors.New("--limit cannot be negative")
+	}
+
+	for _, host := range query.Hosts {
+		if _, err := filepath.Match(host, ""); err != nil {
+			return query, errors.New(fmt.Sprintf("invalid --host pattern %s", host))
+		}
+	}
+
+	return query, nil
+}
+
+// parseActivityTime converts a duration before now (30m, 24h, 7d, 2w), a date, an RFC3339 time or a unix timestamp to
+// a unix timestamp. An empty value is zero.
+func parseActivityTime(value string, now time.Time) (float64, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	if stamp, err := strconv.ParseFloat(value, 64); err == nil {
+		return stamp, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return float64(t.UnixNano()) / 1e9, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return float64(t.Unix()), nil
+	}
+
+	if d, err := parseHumanDuration(value); err == nil {
+		return float64(now.Add(-d).UnixNano()) / 1e9, nil
+	}
+
+	return 0, errors.New("expecting a duration like 24h or 7d, a date like 2020-06-01, an RFC3339 time or a unix timestamp")
+}
+
+// parseHumanDuration is time.ParseDuration with d (days) and w (weeks) units
+func parseHumanDuration(value string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if strings.HasSuffix(value, suffix) {
+			count, err := strconv.ParseFloat(strings.TrimSuffix(value, suffix), 64)
+			if err != nil || count < 0 {
+				return 0, errors.New("invalid duration " + value)
+			}
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err == nil && d < 0 {
+		return 0, errors.New("invalid duration " + value)
+	}
+	return d, err
+}
+
+// matchesActivityQuery reports whether an event has one of the event types and was sent from one of the hosts in the
+// query. Times are not checked here because they decide when to stop paging.
+func matchesActivityQuery(event api.Event, query activityQuery) bool {
+	if len(query.Events) > 0 {
+		matched := false
+		for _, kind := range query.Events {
+			if strings.EqualFold(kind, event.Event) || strings.EqualFold(kind, event.Type) {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(query.Hosts) > 0 {
+		matched := false
+		for _, pattern := range query.Hosts {
+			if ok, _ := filepath.Match(pattern, event.Host); ok {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fetchActivity pages through each monitor's activity, newest first, until it reaches query.Since or has query.Limit
+// matching events, and returns the latest query.Limit events across all monitors, oldest first
+func fetchActivity(client *api.Client, monitors []api.Monitor, options api.ActivityOptions, query activityQuery) ([]monitorEvent, error) {
+	if query.Until > 0 && (options.Before == 0 || query.Until < options.Before) {
+		options.Before = query.Until
+	}
+
+	var events []monitorEvent
+	for _, monitor := range monitors {
+		count := 0
+		it := client.ListActivity(apiContext(), monitor.Key, options)
+		for it.Next() {
+			event := it.Event()
+			if query.Since > 0 && event.Stamp < query.Since {
+				break
+			}
+			if !matchesActivityQuery(event, query) {
+				continue
+			}
+
+			events = append(events, monitorEvent{monitor, event})
+			if count++; query.Limit > 0 && count >= query.Limit {
+				break
+			}
+		}
+
+		if err := it.Err(); err != nil {
+			if api.IsNotFound(err) {
+				return nil, errors.New(fmt.Sprintf("monitor %s does not exist", monitor.Code))
+			}
+			return nil, err
+		}
+	}
+
+	sortActivity(events)
+	if query.Limit > 0 && len(events) > query.Limit {
+		events = events[len(events)-query.Limit:]
+	}
+
+	return events, nil
+}
+
+// sortActivity orders events from many monitors oldest first
+func sortActivity(events []monitorEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Event.Stamp < events[j].Event.Stamp
+	})
+}
+
+// activityLabels names each monitor in a merged timeline, padded to the same width. A single monitor has no label.
+func activityLabels(monitors []api.Monitor) map[string]string {
+	labels := map[string]string{}
+	if len(monitors) < 2 {
+		return labels
+	}
+
+	width := 0
+	for _, monitor := range monitors {
+		name := monitor.Name
+		if len(name) == 0 {
+			name = monitor.Code
+		}
+		labels[monitor.Key] = name
+		if n := len([]rune(name)); n > width {
+			width = n
+		}
+	}
+
+	if width > 30 {
+		width = 30
+	}
+	for key, name := range labels {
+		labels[key] = fitText(name, width)
+	}
+	return labels
+}
+
+func printActivity(w io.Writer, events []monitorEvent, labels map[string]string) {
+	for _, event := range events {
+		if label, ok := labels[event.Monitor.Key]; ok {
+			fmt.Fprintln(w, formatActivityEvent(event.Event, label))
+		} else {
+			fmt.Fprintln(w, formatActivityEvent(event.Event))
+		}
+	}
+}
+
+// printFollowedActivity prints events oldest first with --follow, as a timeline or, with --json, as one JSON object
+// per line so the first batch and later events can be read the same way
+func printFollowedActivity(w io.Writer, events []monitorEvent, labels map[string]string) {
+	if !activityJson {
+		printActivity(w, events, labels)
+		return
+	}
+
+	for _, event := range events {
+		b, _ := json.Marshal(event.json())
+		fmt.Fprintln(w, string(b))
+	}
+}
+
+// printActivityJson prints events newest first, as they are returned by the API
+func printActivityJson(events []monitorEvent) {
+	output := make([]interface{}, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		output = append(output, events[i].json())
+	}
+
+	b, _ := json.MarshalIndent(output, "", "  ")
+	fmt.Println(string(b))
+}
+
+// formatActivityEvent summarizes an event on one line: when it happened in local time, any labels such as the monitor
+// name, what happened, where, how long it took and the start of its message
+func formatActivityEvent(event api.Event, labels ...string) string {
+	sec := int64(event.Stamp)
+	stamp := time.Unix(sec, int64((event.Stamp-float64(sec))*1e9)).Format("2006-01-02 15:04:05")
+
+	kind := event.Event
+	if len(kind) == 0 {
+		kind = event.Type
+	}
+
+	parts := append([]string{stamp}, labels...)
+	parts = append(parts, fmt.Sprintf("%-9s", kind))
+	if len(event.Host) > 0 {
+		parts = append(parts, event.Host)
+	}
+	if event.Duration > 0 {
+		parts = append(parts, fmt.Sprintf("%.2fs", event.Duration))
+	}
+
+	description := event.Description
+	if len(description) == 0 {
+		description = event.Message
+	}
+	if description = strings.Join(strings.Fields(description), " "); len(description) > 0 {
+		if runes := []rune(description); len(runes) > 120 {
+			description = string(runes[:119]) + "…"
+		}
+		parts = append(parts, description)
 	}
 
-	return fmt.Sprintf("%s/%s/%s%s", getCronitorApi().Url(), uniqueIdentifier, endpoint, beforeFilter)
+	return strings.Join(parts, "  ")
 }
 
 func isValidOnlyFilter() bool {
 	switch only {
 	case
-		"pings",
-		"alerts":
+		api.ACTIVITY_PINGS,
+		api.ACTIVITY_ALERTS:
 		return true
 	}
 
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bytes"
+	"cronitor/lib/api"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseActivityTime(t *testing.T) {
+	now := time.Date(2020, 6, 2, 12, 0, 0, 0, time.UTC)
+	tests := map[string]float64{
+		"":                     0,
+		"1510971199.905":       1510971199.905,
+		"24h":                  float64(now.Add(-24 * time.Hour).Unix()),
+		"7d":                   float64(now.Add(-7 * 24 * time.Hour).Unix()),
+		"2w":                   float64(now.Add(-14 * 24 * time.Hour).Unix()),
+		"2020-06-01T22:00:00Z": float64(time.Date(2020, 6, 1, 22, 0, 0, 0, time.UTC).Unix()),
+		"2020-06-01":           float64(time.Date(2020, 6, 1, 0, 0, 0, 0, time.Local).Unix()),
+	}
+
+	for value, expected := range tests {
+		if actual, err := parseActivityTime(value, now); err != nil || actual != expected {
+			t.Errorf("parseActivityTime(%q) = %f %v, expected %f", value, actual, err, expected)
+		}
+	}
+
+	for _, value := range []string{"yesterday", "-5m", "xd"} {
+		if _, err := parseActivityTime(value, now); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+func TestMatchesActivityQuery(t *testing.T) {
+	event := api.Event{Event: "fail", Type: "ping", Host: "web1"}
+	tests := []struct {
+		query    activityQuery
+		expected bool
+	}{
+		{activityQuery{}, true},
+		{activityQuery{Events: []string{"complete", "FAIL"}}, true},
+		{activityQuery{Events: []string{"ping"}}, true},
+		{activityQuery{Events: []string{"alert"}}, false},
+		{activityQuery{Hosts: []string{"web*"}}, true},
+		{activityQuery{Hosts: []string{"db*"}}, false},
+		{activityQuery{Events: []string{"fail"}, Hosts: []string{"db*"}}, false},
+	}
+
+	for _, test := range tests {
+		if actual := matchesActivityQuery(event, test.query); actual != test.expected {
+			t.Errorf("Query %+v: expected %v, got %v", test.query, test.expected, actual)
+		}
+	}
+}
+
+func TestFormatActivityEvent(t *testing.T) {
+	event := api.Event{Stamp: 1600000000, Event: "complete", Host: "web1", Duration: 12.5, Message: "backed up\n  42 tables"}
+	expected := time.Unix(1600000000, 0).Format("2006-01-02 15:04:05") + "  Backup  complete   web1  12.50s  backed up 42 tables"
+	if actual := formatActivityEvent(event, "Backup"); actual != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+
+	event.Message = strings.Repeat("x", 200)
+	if actual := formatActivityEvent(event); !strings.HasSuffix(actual, strings.Repeat("x", 119)+"…") {
+		t.Errorf("Expected a long message to be shortened, got %q", actual)
+	}
+}
+
+func TestFetchActivityPagesAndMerges(t *testing.T) {
+	// Each monitor has an event every 100 seconds from 1000 to 1900, served two per page
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		before := 2000.0
+		if value := r.URL.Query().Get("before"); len(value) > 0 {
+			before, _ = strconv.ParseFloat(value, 64)
+		}
+
+		host := "web1"
+		if strings.Contains(r.URL.Path, "/b/") {
+			host = "web2"
+		}
+
+		var events []string
+		for stamp := 1900.0; stamp >= 1000 && len(events) < 2; stamp -= 100 {
+			if stamp < before {
+				events = append(events, fmt.Sprintf(`{"stamp": %f, "event": "complete", "host": "%s"}`, stamp, host))
+			}
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(events, ","))
+	}))
+	defer server.Close()
+
+	client := &api.Client{BaseUrl: server.URL, ApiKey: "test", HttpClient: server.Client()}
+	monitors := []api.Monitor{{Key: "a", Code: "a"}, {Key: "b", Code: "b"}}
+
+	events, err := fetchActivity(client, monitors, api.ActivityOptions{}, activityQuery{Since: 1350, Until: 1800})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(events) != 8 || events[0].Event.Stamp != 1400 || events[len(events)-1].Event.Stamp != 1700 {
+		t.Fatalf("Expected events from 1400 to 1700 for both monitors, got %+v", events)
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Event.Stamp < events[i-1].Event.Stamp {
+			t.Errorf("Expected events oldest first, got %+v", events)
+		}
+	}
+
+	events, _ = fetchActivity(client, monitors, api.ActivityOptions{}, activityQuery{Hosts: []string{"web2"}, Limit: 3})
+	if len(events) != 3 || events[0].Monitor.Key != "b" || events[2].Event.Stamp != 1900 {
+		t.Errorf("Expected the latest 3 events from web2, got %+v", events)
+	}
+}
+
+func TestPrintFollowedActivityJson(t *testing.T) {
+	activityJson = true
+	defer func() { activityJson = false }()
+
+	events := []monitorEvent{
+		{Monitor: api.Monitor{Key: "a", Code: "abc123"}, Event: api.Event{Stamp: 1591099200, Event: "run"}},
+		{Monitor: api.Monitor{Key: "a", Code: "abc123"}, Event: api.Event{Stamp: 1591099260, Event: "complete"}},
+	}
+
+	var buf bytes.Buffer
+	printFollowedActivity(&buf, events, map[string]string{"a": "Backup"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one line per event, got %q", buf.String())
+	}
+
+	for _, line := range lines {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil || event["monitor"] != "abc123" {
+			t.Errorf("Expected a JSON event for abc123, got %q", line)
+		}
+	}
+}
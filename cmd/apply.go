@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var applyFiles []string
+var applyYes bool
+var applyDryRun bool
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create or update monitors from YAML or JSON files",
+	Long: `
+Cronitor apply reads monitor definitions from YAML or JSON files, shows a plan of the monitors that will be created or
+updated, and applies it. Monitors are matched by key, so files can be kept in source control and reviewed like any
+other change. Monitors that are not in the files are left alone.
+
+A monitors file looks like this (the same shape is written by 'cronitor export'):
+
+  monitors:
+    - key: nightly-billing
+      name: Nightly billing
+      type: job
+      rules:
+        - rule_type: complete_ping_not_received
+          value: 0 2 * * *
+          grace_seconds: 600
+      tags: [billing, prod]
+      timezone: America/New_York
+      notifications:
+        templates: [oncall]
+      note: Runs the billing batch
+
+Example:
+  $ cronitor apply -f monitors.yaml
+      > Show the plan and apply it after confirmation
+
+  $ cronitor apply -f ./monitors/ --dry-run
+      > Show the plan for every .yaml, .yml and .json file in a directory without changing anything
+
+  $ cronitor apply -f monitors.yaml --yes
+      > Apply without a confirmation prompt, e.g. from a CI pipeline
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(applyFiles) == 0 {
+			return errors.New("supply at least one file or directory with -f")
+		}
+
+		if len(viper.GetString(varApiKey)) < 10 {
+			return errors.New("you must provide a valid API key with this command or save a key using 'cronitor configure'")
+		}
+
+		return nil
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		definitions, err := lib.ReadMonitorDefinitions(applyFiles)
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		if len(definitions) == 0 {
+			printWarningText("No monitors defined in "+strings.Join(applyFiles, ", "), false)
+			return
+		}
+
+		existing, err := getMonitorDefinitions()
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		changes := lib.PlanMonitorChanges(definitions, existing)
+		var pending []*lib.MonitorDefinition
+		counts := map[string]int{}
+		for _, change := range changes {
+			counts[change.Action]++
+			if change.Action != lib.MONITOR_UNCHANGED {
+				pending = append(pending, change.Definition)
+			}
+		}
+
+		printApplyPlan(changes)
+		printSuccessText(fmt.Sprintf("Plan: %d to create, %d to update, %d unchanged", counts[lib.MONITOR_CREATE], counts[lib.MONITOR_UPDATE], counts[lib.MONITOR_UNCHANGED]), false)
+
+		if len(pending) == 0 {
+			printDoneText("Nothing to apply", false)
+			return
+		}
+
+		if applyDryRun {
+			printWarningText("This is a DRY-RUN. No monitors were changed.", false)
+			return
+		}
+
+		if !applyYes {
+			prompt := promptui.Prompt{
+				Label:     "Apply these changes",
+				IsConfirm: true,
+			}
+
+			if _, err := prompt.Run(); err != nil {
+				printWarningText("Cancelled", false)
+				return
+			}
+		}
+
+		if _, err := getCronitorApi().PutMonitorDefinitions(apiContext(), pending); err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		printDoneText(fmt.Sprintf("Applied %d monitors", len(pending)), false)
+	},
+}
+
+func printApplyPlan(changes []lib.MonitorChange) {
+	for _, change := range changes {
+		name := change.Definition.Key
+		if change.Definition.Name != "" {
+			name = fmt.Sprintf("%s (%s)", change.Definition.Key, change.Definition.Name)
+		}
+
+		switch change.Action {
+		case lib.MONITOR_CREATE:
+			fmt.Println(fmt.Sprintf("  + %s", name))
+		case lib.MONITOR_UPDATE:
+			fmt.Println(fmt.Sprintf("  ~ %s: %s", name, strings.Join(change.Fields, ", ")))
+		default:
+			log(fmt.Sprintf("  = %s", name))
+		}
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().StringArrayVarP(&applyFiles, "file", "f", applyFiles, "A YAML or JSON monitors file, or a directory of them. Can be repeated.")
+	applyCmd.Flags().BoolVarP(&applyYes, "yes", "y", applyYes, "Apply the plan without asking for confirmation")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", applyDryRun, "Show the plan without changing any monitors")
+}
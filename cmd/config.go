@@ -0,0 +1,762 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// configKey describes a value that can be set in the config file
+type configKey struct {
+	Name        string
+	Flag        string
+	Description string
+	IsSecret    bool
+	IsList      bool
+	IsInt       bool
+	Validate    func(string) error
+}
+
+var apiKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var configKeys = []configKey{
+	{Name: varApiKey, Flag: "api-key", Description: "Cronitor API key", IsSecret: true, Validate: validateApiKey},
+	{Name: varApiKeyFile, Description: "File to read the Cronitor API key from", Validate: validateApiKeyFile},
+	{Name: varPingApiKey, Flag: "ping-api-key", Description: "Ping API key", IsSecret: true, Validate: validatePingApiKey},
+	{Name: varHostname, Flag: "hostname", Description: "A unique identifier for this host"},
+	{Name: varLog, Flag: "log", Description: "Debug log file", Validate: validateWritableFile},
+	{Name: varExcludeText, Description: "Text to exclude from discovered monitor names", IsList: true},
+	{Name: varKeyVersion, Description: "Monitor key version for discover", IsInt: true, Validate: validateKeyVersion},
+	{Name: varNameTemplate, Description: "Template for discovered monitor names", Validate: validateNameTemplate},
+	{Name: varNameRewrite, Description: "Rewrite rules for discovered monitor names", IsList: true, Validate: validateNameRewrite},
+	{Name: varNamesFile, Description: "Names file for discover", Validate: validateNamesFile},
+	{Name: varTags, Description: "Tags for discovered monitors", IsList: true},
+	{Name: varTagRules, Description: "Tag rules for discovered monitors", IsList: true, Validate: validateTagRule},
+	{Name: varEnvironment, Description: "Environment tag for discovered monitors"},
+	{Name: varGrace, Description: "Grace period for discovered monitors", Validate: validateGrace},
+	{Name: varRules, Description: "Extra rules for discovered monitors", IsList: true, Validate: validateRule},
+	{Name: varTimeout, Description: "Timeout for API requests, including retries", Validate: validateDuration},
+	{Name: varPingTimeout, Description: "Timeout for each attempt to send a ping, and the longest a ping waits out a rate limit", Validate: validateDuration},
+	{Name: varRetries, Description: "Times to retry API requests that fail", IsInt: true, Validate: validateRetries},
+	{Name: varProxy, Description: "Proxy URL, used instead of HTTPS_PROXY and NO_PROXY", Validate: validateProxy},
+	{Name: varCaBundle, Description: "PEM file of extra certificate authorities to trust", Validate: validateReadableFile},
+	{Name: varClientCert, Description: "PEM client certificate for TLS", Validate: validateReadableFile},
+	{Name: varClientKey, Description: "PEM client key for TLS", Validate: validateReadableFile},
+	{Name: varTextfile, Description: "node_exporter textfile that exec writes job metrics to", Validate: validateWritableFile},
+	{Name: varStateDir, Description: "Directory for the run history kept by exec", Validate: validateDirectory},
+	{Name: varHistoryMaxRuns, Description: "Runs to keep in the run history, or 0 to keep none", IsInt: true, Validate: validateRetries},
+	{Name: varHistoryMaxAge, Description: "How long to keep runs in the run history, e.g. 30d", Validate: validateAge},
+	{Name: varLogDir, Description: "Directory where exec keeps the output of each run", Validate: validateDirectory},
+	{Name: varLogMaxRuns, Description: "Run logs to keep for each job, or 0 for no limit", IsInt: true, Validate: validateRetries},
+	{Name: varLogMaxSize, Description: "Space the run logs of each job can use, e.g. 100MB", Validate: validateByteSize},
+	{Name: varLogMaxAge, Description: "How long to keep run logs, e.g. 14d", Validate: validateAge},
+	{Name: varLogCompress, Description: "Compress run logs once the run finishes, true or false", Validate: validateBool},
+}
+
+// The config file key that holds named profiles, each with its own config values
+const configProfilesKey = "profiles"
+const defaultProfile = "default"
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and manage configuration",
+}
+
+var configProfilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List the profiles in the config file",
+	Long: `
+List the profiles in the config file. Values at the top level of the config file are the "default" profile, and each
+named profile overrides them. Select a profile with --profile or the CRONITOR_PROFILE environment variable.
+
+Example:
+  $ cronitor configure --profile staging --api-key 4319e94e890a013dbaca57c2df2ff60c2
+      > Save an API key for the staging account
+
+  $ cronitor --profile staging status
+      > Use the staging account
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configMap := loadedConfigMap()
+		activeProfile := effectiveProfile()
+		rows := [][]string{{markActiveProfile(defaultProfile, activeProfile), redactSecret(configString(configMap[varApiKey]))}}
+		profiles := configFileProfiles(configMap)
+		for _, name := range sortedProfileNames(profiles) {
+			rows = append(rows, []string{markActiveProfile(name, activeProfile), redactSecret(configString(profiles[name][varApiKey]))})
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Profile", "API Key"})
+		table.SetAutoWrapText(false)
+		table.AppendBulk(rows)
+		table.Render()
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration and where each value comes from",
+	Long: `
+Show the value of each configuration setting and its source: a command line flag, an environment variable, the
+selected profile, one of the config files, or the default. API keys are redacted. Nothing is written.
+
+Example:
+  $ cronitor config show
+  $ cronitor --profile staging config show
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		profileValues := configFileProfiles(loadedConfigMap())[effectiveProfile()]
+
+		var rows [][]string
+		for _, key := range configKeys {
+			value := formatConfigValue(key, viper.Get(key.Name))
+			if key.IsSecret {
+				value = redactSecret(value)
+			}
+			rows = append(rows, []string{key.Name, value, configValueSource(key, profileValues)})
+		}
+
+		for _, file := range loadedConfigFiles {
+			fmt.Println(fmt.Sprintf("Config file: %s", file.Filename))
+		}
+		fmt.Println(fmt.Sprintf("Saving to: %s", configFilePath()))
+		fmt.Println(fmt.Sprintf("Profile: %s", effectiveProfile()))
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Key", "Value", "Source"})
+		table.SetAutoWrapText(false)
+		table.AppendBulk(rows)
+		table.Render()
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file and the effective configuration for problems",
+	Long: `
+Check that the config file can be read, that each value is valid, that the debug log can be written, and that the
+system timezone is known. The exit code is 1 if any problems are found.
+
+Example:
+  $ cronitor config validate
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		problems, warnings := validateConfig()
+		for _, warning := range warnings {
+			printWarningText(warning, false)
+		}
+
+		if len(problems) == 0 {
+			printDoneText("Configuration is valid", false)
+			return
+		}
+
+		for _, problem := range problems {
+			printErrorText(problem, false)
+		}
+		os.Exit(1)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>...",
+	Short: "Set a single value in the config file",
+	Long: `
+Set one value in the config file without changing anything else in it. Keys can be written as CRONITOR_API_KEY,
+api_key or api-key. Settings that hold a list take one or more values. With --profile, the value is set in that profile,
+which is created if it does not exist.
+
+Example:
+  $ cronitor config set api-key 4319e94e890a013dbaca57c2df2ff60c2
+  $ cronitor config set tags team:billing production
+  $ cronitor --profile staging config set hostname staging-worker
+	`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		key, err := findConfigKey(args[0])
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		value, err := parseConfigValue(key, args[1:])
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		if err := updateConfigValue(key.Name, value, effectiveProfile()); err != nil {
+			fatal(err.Error(), 126)
+		}
+
+		printDoneText(fmt.Sprintf("Set %s in %s", key.Name, configFilePath()), false)
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a single value from the config file",
+	Long: `
+Remove one value from the config file without changing anything else in it. Unknown keys can be removed by their exact name.
+
+Example:
+  $ cronitor config unset hostname
+  $ cronitor --profile staging config unset grace
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if key, err := findConfigKey(name); err == nil {
+			name = key.Name
+		}
+
+		if err := updateConfigValue(name, nil, effectiveProfile()); err != nil {
+			fatal(err.Error(), 126)
+		}
+
+		printDoneText(fmt.Sprintf("Removed %s from %s", name, configFilePath()), false)
+	},
+}
+
+func effectiveProfile() string {
+	if profile := viper.GetString(varProfile); len(profile) > 0 {
+		return profile
+	}
+
+	return defaultProfile
+}
+
+// applyProfile merges the selected profile over the top level of the config files. Flags and environment variables
+// still take precedence.
+func applyProfile() error {
+	profile := effectiveProfile()
+	if profile == defaultProfile {
+		return nil
+	}
+
+	values, ok := configFileProfiles(loadedConfigMap())[profile]
+	if !ok {
+		return errors.New(fmt.Sprintf("profile \"%s\" does not exist in %s. Create it with 'cronitor configure --profile %s'", profile, configFilePath(), profile))
+	}
+
+	log(fmt.Sprintf("Using profile %s", profile))
+	return viper.MergeConfigMap(values)
+}
+
+// saveConfigValues writes values to the config file, keeping keys it does not know about. For a named profile, only
+// values that differ from the top level of the file are saved in the profile.
+func saveConfigValues(values map[string]interface{}, profile string) error {
+	filename := configFilePath()
+	configMap, err := readConfigFileMap(filename)
+	if err != nil {
+		return err
+	}
+
+	if profile == defaultProfile {
+		for key, value := range values {
+			configMap[key] = value
+		}
+	} else {
+		profiles := configFileProfiles(configMap)
+		profileValues, exists := profiles[profile]
+		if !exists {
+			profileValues = map[string]interface{}{}
+		}
+
+		for key, value := range values {
+			if _, inProfile := profileValues[key]; inProfile || (!isEmptyConfigValue(value) && !isSameConfigValue(value, configMap[key])) {
+				profileValues[key] = value
+			}
+		}
+
+		profiles[profile] = profileValues
+		configMap[configProfilesKey] = profiles
+	}
+
+	return writeConfigFileMap(filename, configMap)
+}
+
+// configValueSource reports where the effective value of a setting comes from, in order of precedence
+func configValueSource(key configKey, profileValues map[string]interface{}) string {
+	if len(key.Flag) > 0 {
+		if flag := RootCmd.PersistentFlags().Lookup(key.Flag); flag != nil && flag.Changed {
+			return "flag --" + key.Flag
+		}
+	}
+
+	if _, ok := os.LookupEnv(key.Name); ok {
+		return "env " + key.Name
+	}
+
+	if _, ok := profileValues[key.Name]; ok {
+		return "profile " + effectiveProfile()
+	}
+
+	for i := len(loadedConfigFiles) - 1; i >= 0; i-- {
+		if _, ok := loadedConfigFiles[i].Values[key.Name]; ok {
+			return "file " + loadedConfigFiles[i].Filename
+		}
+	}
+
+	if key.Name == varApiKey && len(apiKeyFromFile) > 0 {
+		return "file " + apiKeyFromFile
+	}
+
+	return "default"
+}
+
+// validateConfig returns problems that will cause errors, and warnings about unknown keys, which are kept but ignored
+func validateConfig() ([]string, []string) {
+	var problems, warnings []string
+	knownKeys := map[string]bool{configProfilesKey: true}
+	for _, key := range configKeys {
+		knownKeys[key.Name] = true
+	}
+
+	checkUnknownKeys := func(values map[string]interface{}, location string) {
+		for name := range values {
+			if !knownKeys[name] {
+				warnings = append(warnings, fmt.Sprintf("Unknown key %s in %s is ignored", name, location))
+			}
+		}
+	}
+	for _, filename := range configFileCandidates() {
+		configMap, err := readConfigFileMap(filename)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		checkUnknownKeys(configMap, filename)
+		for name, values := range configFileProfiles(configMap) {
+			checkUnknownKeys(values, fmt.Sprintf("profile %s in %s", name, filename))
+		}
+
+		if configFileContainsSecrets(configMap) && !isPrivateFile(filename) {
+			warnings = append(warnings, fmt.Sprintf("%s contains an API key and can be read by other users; run 'chmod 600 %s'", filename, filename))
+		}
+	}
+
+	if filename := viper.GetString(varApiKeyFile); len(filename) > 0 && !isPrivateFile(filename) {
+		warnings = append(warnings, fmt.Sprintf("The API key file %s can be read by other users; run 'chmod 600 %s'", filename, filename))
+	}
+
+	for _, key := range configKeys {
+		if key.Validate == nil {
+			continue
+		}
+
+		values := []string{}
+		if key.IsList {
+			values = viper.GetStringSlice(key.Name)
+		} else if value := viper.GetString(key.Name); len(value) > 0 {
+			values = []string{value}
+		}
+
+		for _, value := range values {
+			if err := key.Validate(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", key.Name, err.Error()))
+			}
+		}
+	}
+
+	if timezone := effectiveTimezoneLocationName(); len(timezone.Name) == 0 {
+		problems = append(problems, "The system timezone could not be determined; set TZ or CRON_TZ")
+	} else if _, err := time.LoadLocation(strings.TrimSpace(timezone.Name)); err != nil {
+		problems = append(problems, fmt.Sprintf("The system timezone %s is not a known timezone: %s", timezone.Name, err.Error()))
+	}
+
+	return problems, warnings
+}
+
+// findConfigKey accepts a key as CRONITOR_API_KEY, api_key or api-key
+func findConfigKey(name string) (configKey, error) {
+	normalized := strings.ToUpper(strings.Replace(name, "-", "_", -1))
+	if !strings.HasPrefix(normalized, "CRONITOR_") {
+		normalized = "CRONITOR_" + normalized
+	}
+
+	var names []string
+	for _, key := range configKeys {
+		if key.Name == normalized {
+			return key, nil
+		}
+		names = append(names, key.Name)
+	}
+
+	return configKey{}, errors.New(fmt.Sprintf("unknown config key %s. Expecting one of: %s", name, strings.Join(names, ", ")))
+}
+
+func parseConfigValue(key configKey, args []string) (interface{}, error) {
+	if !key.IsList && len(args) > 1 {
+		return nil, errors.New(fmt.Sprintf("%s takes a single value", key.Name))
+	}
+
+	for _, value := range args {
+		if key.Validate != nil {
+			if err := key.Validate(value); err != nil {
+				return nil, errors.New(fmt.Sprintf("%s: %s", key.Name, err.Error()))
+			}
+		}
+	}
+
+	if key.IsList {
+		return args, nil
+	}
+
+	if key.IsInt {
+		return strconv.Atoi(args[0])
+	}
+
+	return args[0], nil
+}
+
+// updateConfigValue sets or, if the value is nil, removes a single key in the config file or one of its profiles
+func updateConfigValue(name string, value interface{}, profile string) error {
+	filename := configFilePath()
+	configMap, err := readConfigFileMap(filename)
+	if err != nil {
+		return err
+	}
+
+	values := configMap
+	profiles := configFileProfiles(configMap)
+	if profile != defaultProfile {
+		if _, exists := profiles[profile]; !exists {
+			profiles[profile] = map[string]interface{}{}
+		}
+		values = profiles[profile]
+		configMap[configProfilesKey] = profiles
+	}
+
+	if value == nil {
+		delete(values, name)
+	} else {
+		values[name] = value
+	}
+
+	return writeConfigFileMap(filename, configMap)
+}
+
+func formatConfigValue(key configKey, value interface{}) string {
+	if key.IsList {
+		return strings.Join(cast.ToStringSlice(value), ", ")
+	}
+
+	return configString(value)
+}
+
+func validateApiKey(value string) error {
+	if len(value) < 10 || !apiKeyRegex.MatchString(value) {
+		return errors.New("expecting at least 10 letters and numbers")
+	}
+
+	return nil
+}
+
+func validatePingApiKey(value string) error {
+	if !apiKeyRegex.MatchString(value) {
+		return errors.New("expecting letters and numbers")
+	}
+
+	return nil
+}
+
+func validateKeyVersion(value string) error {
+	if version, err := strconv.Atoi(value); err != nil || !lib.IsValidKeyVersion(version) {
+		return errors.New("expecting 1 or 2")
+	}
+
+	return nil
+}
+
+func validateNameTemplate(value string) error {
+	_, err := parseNameTemplate(value)
+	return err
+}
+
+func validateNameRewrite(value string) error {
+	_, err := parseNameRewriteRules([]string{value})
+	return err
+}
+
+func validateTagRule(value string) error {
+	_, err := parseTagRules([]string{value})
+	return err
+}
+
+func validateGrace(value string) error {
+	_, err := lib.ParseGraceSeconds(value)
+	return err
+}
+
+func validateRule(value string) error {
+	_, err := lib.ParseRule(value)
+	return err
+}
+
+func validateApiKeyFile(value string) error {
+	b, err := ioutil.ReadFile(value)
+	if err != nil {
+		return errors.New(fmt.Sprintf("%s cannot be read", value))
+	}
+
+	return validateApiKey(strings.TrimSpace(string(b)))
+}
+
+func validateDuration(value string) error {
+	if duration, err := time.ParseDuration(value); err != nil || duration < 0 {
+		return errors.New("expecting a duration like 30s or 2m")
+	}
+
+	return nil
+}
+
+func validateRetries(value string) error {
+	if retries, err := strconv.Atoi(value); err != nil || retries < 0 {
+		return errors.New("expecting 0 or more")
+	}
+
+	return nil
+}
+
+func validateProxy(value string) error {
+	if proxyUrl, err := url.Parse(value); err != nil || len(proxyUrl.Host) == 0 {
+		return errors.New("expecting a URL like http://proxy.example.com:3128")
+	}
+
+	return nil
+}
+
+func validateReadableFile(value string) error {
+	f, err := os.Open(value)
+	if err != nil {
+		return errors.New(fmt.Sprintf("%s cannot be read", value))
+	}
+
+	return f.Close()
+}
+
+func validateNamesFile(value string) error {
+	_, err := lib.ReadNamesFile(value)
+	return err
+}
+
+// validateWritableFile checks that a file can be appended to, or created if it does not exist, without changing it
+func validateWritableFile(value string) error {
+	if _, err := os.Stat(value); err == nil {
+		f, err := os.OpenFile(value, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return errors.New(fmt.Sprintf("%s is not writable", value))
+		}
+		return f.Close()
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(value), ".cronitor-check")
+	if err != nil {
+		return errors.New(fmt.Sprintf("%s cannot be created in %s", filepath.Base(value), filepath.Dir(value)))
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// validateDirectory checks that a directory is not a file. A directory that does not exist is created when it is needed.
+func validateDirectory(value string) error {
+	if info, err := os.Stat(value); err == nil && !info.IsDir() {
+		return errors.New(fmt.Sprintf("%s is not a directory", value))
+	}
+
+	return nil
+}
+
+func validateAge(value string) error {
+	if _, err := parseHumanDuration(value); err != nil {
+		return errors.New("expecting a duration like 12h or 30d")
+	}
+
+	return nil
+}
+
+func validateByteSize(value string) error {
+	_, err := parseByteSize(value)
+	return err
+}
+
+func validateBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return errors.New("expecting true or false")
+	}
+
+	return nil
+}
+
+func readConfigFileMap(filename string) (map[string]interface{}, error) {
+	configMap := map[string]interface{}{}
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return configMap, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return configMap, nil
+	}
+
+	if isYamlConfigFile(filename) {
+		if err := yaml.Unmarshal(b, &configMap); err != nil {
+			return nil, errors.New(fmt.Sprintf("the configuration file %s is not valid YAML: %s", filename, err.Error()))
+		}
+
+		return normalizeYamlMap(configMap), nil
+	}
+
+	if err := json.Unmarshal(b, &configMap); err != nil {
+		return nil, errors.New(fmt.Sprintf("the configuration file %s is not valid JSON: %s", filename, err.Error()))
+	}
+
+	return configMap, nil
+}
+
+func isYamlConfigFile(filename string) bool {
+	extension := strings.ToLower(filepath.Ext(filename))
+	return extension == ".yaml" || extension == ".yml"
+}
+
+// normalizeYamlMap converts the map[interface{}]interface{} values YAML produces for nested maps, such as profiles
+func normalizeYamlMap(values map[string]interface{}) map[string]interface{} {
+	for key, value := range values {
+		if nested, ok := value.(map[interface{}]interface{}); ok {
+			converted := map[string]interface{}{}
+			for nestedKey, nestedValue := range nested {
+				converted[fmt.Sprint(nestedKey)] = nestedValue
+			}
+			values[key] = normalizeYamlMap(converted)
+		}
+	}
+
+	return values
+}
+
+func writeConfigFileMap(filename string, configMap map[string]interface{}) error {
+	var b []byte
+	var err error
+	if isYamlConfigFile(filename) {
+		b, err = yaml.Marshal(configMap)
+	} else {
+		b, err = json.MarshalIndent(configMap, "", "    ")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Keep API keys private to the user that owns the file
+	var perm os.FileMode = 0644
+	if configFileContainsSecrets(configMap) {
+		perm = 0600
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+
+	// The file is replaced instead of rewritten, so an existing file's mode never exposes a new API key
+	return lib.WriteFileAtomically(filename, b, perm)
+}
+
+func configFileProfiles(configMap map[string]interface{}) map[string]map[string]interface{} {
+	// Profiles added before the file is written are already stored in this form
+	if profiles, ok := configMap[configProfilesKey].(map[string]map[string]interface{}); ok {
+		return profiles
+	}
+
+	profiles := map[string]map[string]interface{}{}
+	if raw, ok := configMap[configProfilesKey].(map[string]interface{}); ok {
+		for name, values := range raw {
+			if profileValues, ok := values.(map[string]interface{}); ok {
+				profiles[name] = profileValues
+			}
+		}
+	}
+
+	return profiles
+}
+
+func sortedProfileNames(profiles map[string]map[string]interface{}) []string {
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func markActiveProfile(name, activeProfile string) string {
+	if name == activeProfile {
+		return name + " *"
+	}
+
+	return name
+}
+
+// redactSecret shows only the last 4 characters of an API key
+func redactSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+
+	return strings.Repeat("*", len(secret)-4) + secret[len(secret)-4:]
+}
+
+func configString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+func isEmptyConfigValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	}
+
+	return v.IsZero()
+}
+
+// isSameConfigValue compares values as they would be written to the config file
+func isSameConfigValue(a, b interface{}) bool {
+	aJson, _ := json.Marshal(a)
+	bJson, _ := json.Marshal(b)
+	return string(aJson) == string(bJson)
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configProfilesCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// A config file that was read, with ${ENV} references already replaced
+type loadedConfigFile struct {
+	Filename string
+	Values   map[string]interface{}
+}
+
+// Config files in the order they were read. Later files take precedence.
+var loadedConfigFiles []loadedConfigFile
+
+// The file the API key was read from, if it was not set directly
+var apiKeyFromFile string
+
+var configFileNames = []string{"cronitor.json", "cronitor.yaml", "cronitor.yml"}
+var configInterpolationRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// configFileDirectories is the config file search path, in order of precedence: the system-wide directory, then the
+// per-user directory
+func configFileDirectories() []string {
+	if runtime.GOOS == "windows" {
+		directories := []string{fmt.Sprintf("%s\\ProgramData\\Cronitor", os.Getenv("SYSTEMDRIVE"))}
+		if appData := os.Getenv("APPDATA"); len(appData) > 0 {
+			directories = append(directories, filepath.Join(appData, "Cronitor"))
+		}
+		return directories
+	}
+
+	directories := []string{"/etc/cronitor"}
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); len(configHome) > 0 {
+		directories = append(directories, filepath.Join(configHome, "cronitor"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		directories = append(directories, filepath.Join(home, ".config", "cronitor"))
+	}
+
+	return directories
+}
+
+// findConfigFile returns the first config file in a directory, if there is one
+func findConfigFile(directory string) (string, bool) {
+	for _, name := range configFileNames {
+		filename := filepath.Join(directory, name)
+		if _, err := os.Stat(filename); err == nil {
+			return filename, true
+		}
+	}
+
+	return "", false
+}
+
+// configFilePath is the file that configure and config set write to: the --config file, otherwise the first existing
+// file in the search path that can be written, otherwise the system file for root and the per-user file for others
+func configFilePath() string {
+	if configFile := viper.GetString(varConfig); len(configFile) > 0 {
+		return configFile
+	}
+
+	directories := configFileDirectories()
+	for _, directory := range directories {
+		if filename, ok := findConfigFile(directory); ok && validateWritableFile(filename) == nil {
+			return filename
+		}
+	}
+
+	directory := directories[0]
+	if runtime.GOOS != "windows" && os.Geteuid() != 0 && len(directories) > 1 {
+		directory = directories[1]
+	}
+
+	return filepath.Join(directory, configFileNames[0])
+}
+
+// configFileCandidates returns the config files to read, in order of increasing precedence: the --config file alone,
+// or the per-user file followed by the system file
+func configFileCandidates() []string {
+	if configFile := viper.GetString(varConfig); len(configFile) > 0 {
+		return []string{configFile}
+	}
+
+	var filenames []string
+	directories := configFileDirectories()
+	for i := len(directories) - 1; i >= 0; i-- {
+		if filename, ok := findConfigFile(directories[i]); ok {
+			filenames = append(filenames, filename)
+		}
+	}
+
+	return filenames
+}
+
+// readConfigFiles reads each config file, replacing ${ENV} references. A file that cannot be read is skipped;
+// 'cronitor config validate' reports why.
+func readConfigFiles() {
+	loadedConfigFiles = nil
+	for _, filename := range configFileCandidates() {
+		values, err := readConfigFileMap(filename)
+		if err != nil {
+			log(err.Error())
+			continue
+		}
+
+		log("Reading config from " + filename)
+		loadedConfigFiles = append(loadedConfigFiles, loadedConfigFile{
+			Filename: filename,
+			Values:   interpolateConfigValue(values).(map[string]interface{}),
+		})
+	}
+}
+
+// loadedConfigMap returns a copy of the config files that were read, with the system file merged over the per-user file
+func loadedConfigMap() map[string]interface{} {
+	var maps []map[string]interface{}
+	for _, file := range loadedConfigFiles {
+		maps = append(maps, file.Values)
+	}
+
+	return mergeConfigMaps(maps...)
+}
+
+// readApiKeyFile reads the API key from CRONITOR_API_KEY_FILE, so the key does not have to be in the config file or
+// on the command line
+func readApiKeyFile() error {
+	apiKeyFromFile = ""
+	filename := viper.GetString(varApiKeyFile)
+	if len(filename) == 0 || len(viper.GetString(varApiKey)) > 0 {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.New(fmt.Sprintf("the API key file %s could not be read: %s", filename, err.Error()))
+	}
+
+	if !isPrivateFile(filename) {
+		log(fmt.Sprintf("Warning: the API key file %s can be read by other users", filename))
+	}
+
+	viper.Set(varApiKey, strings.TrimSpace(string(b)))
+	apiKeyFromFile = filename
+	return nil
+}
+
+// interpolateConfigValue replaces ${NAME} in strings with the value of the environment variable NAME
+func interpolateConfigValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		return configInterpolationRegex.ReplaceAllStringFunc(typed, func(match string) string {
+			return os.Getenv(configInterpolationRegex.FindStringSubmatch(match)[1])
+		})
+	case []interface{}:
+		interpolated := make([]interface{}, len(typed))
+		for i, item := range typed {
+			interpolated[i] = interpolateConfigValue(item)
+		}
+		return interpolated
+	case map[string]interface{}:
+		interpolated := map[string]interface{}{}
+		for key, item := range typed {
+			interpolated[key] = interpolateConfigValue(item)
+		}
+		return interpolated
+	}
+
+	return value
+}
+
+// mergeConfigMaps returns a copy of the maps, each merged over the one before it. Nested maps such as profiles are
+// merged rather than replaced.
+func mergeConfigMaps(maps ...map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for _, values := range maps {
+		for key, value := range values {
+			if nested, ok := value.(map[string]interface{}); ok {
+				existing, _ := merged[key].(map[string]interface{})
+				merged[key] = mergeConfigMaps(existing, nested)
+			} else {
+				merged[key] = value
+			}
+		}
+	}
+
+	return merged
+}
+
+// configFileContainsSecrets reports whether a config file has an API key at the top level or in a profile
+func configFileContainsSecrets(configMap map[string]interface{}) bool {
+	for _, name := range []string{varApiKey, varPingApiKey} {
+		if len(configString(configMap[name])) > 0 {
+			return true
+		}
+	}
+
+	for _, values := range configFileProfiles(configMap) {
+		if configFileContainsSecrets(values) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPrivateFile reports whether only the owner of a file can read it. File modes are not checked on Windows.
+func isPrivateFile(filename string) bool {
+	info, err := os.Stat(filename)
+	return err != nil || runtime.GOOS == "windows" || info.Mode().Perm()&0077 == 0
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestInterpolateConfigValue(t *testing.T) {
+	os.Setenv("CRONITOR_TEST_SECRET", "s3cret")
+	defer os.Unsetenv("CRONITOR_TEST_SECRET")
+
+	values := map[string]interface{}{
+		varApiKey:         "${CRONITOR_TEST_SECRET}",
+		varTags:           []interface{}{"team:${CRONITOR_TEST_SECRET}", "${CRONITOR_TEST_MISSING}"},
+		configProfilesKey: map[string]interface{}{"staging": map[string]interface{}{varHostname: "web-${CRONITOR_TEST_SECRET}"}},
+		varKeyVersion:     2,
+	}
+
+	interpolated := interpolateConfigValue(values).(map[string]interface{})
+	if interpolated[varApiKey] != "s3cret" {
+		t.Errorf("Expected s3cret, got %v", interpolated[varApiKey])
+	}
+
+	tags := interpolated[varTags].([]interface{})
+	if tags[0] != "team:s3cret" || tags[1] != "" {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+
+	if configFileProfiles(interpolated)["staging"][varHostname] != "web-s3cret" {
+		t.Errorf("Expected profiles to be interpolated, got %v", interpolated[configProfilesKey])
+	}
+
+	if interpolated[varKeyVersion] != 2 || values[varApiKey] != "${CRONITOR_TEST_SECRET}" {
+		t.Errorf("Expected non-string values to be kept and the original map unchanged, got %v", interpolated)
+	}
+}
+
+func TestMergeConfigMaps(t *testing.T) {
+	user := map[string]interface{}{
+		varHostname:       "laptop",
+		varLog:            "/tmp/cronitor.log",
+		configProfilesKey: map[string]interface{}{"staging": map[string]interface{}{varApiKey: "userkey", varGrace: "5m"}},
+	}
+	system := map[string]interface{}{
+		varHostname:       "web1",
+		configProfilesKey: map[string]interface{}{"staging": map[string]interface{}{varApiKey: "systemkey"}},
+	}
+
+	merged := mergeConfigMaps(user, system)
+	if merged[varHostname] != "web1" || merged[varLog] != "/tmp/cronitor.log" {
+		t.Errorf("Expected the system file to be merged over the user file, got %v", merged)
+	}
+
+	staging := configFileProfiles(merged)["staging"]
+	if staging[varApiKey] != "systemkey" || staging[varGrace] != "5m" {
+		t.Errorf("Expected profiles to be merged, got %v", staging)
+	}
+
+	staging[varApiKey] = "changed"
+	if configFileProfiles(system)["staging"][varApiKey] != "systemkey" {
+		t.Error("Changing the merged map should not change the maps it was merged from")
+	}
+}
+
+func TestReadApiKeyFile(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+	filename := filepath.Join(directory, "api_key")
+	ioutil.WriteFile(filename, []byte("4319e94e890a013dbaca57c2df2ff60c2\n"), 0600)
+
+	defer viper.Set(varApiKey, "")
+	defer viper.Set(varApiKeyFile, "")
+
+	viper.Set(varApiKey, "")
+	viper.Set(varApiKeyFile, filename)
+	if err := readApiKeyFile(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if viper.GetString(varApiKey) != "4319e94e890a013dbaca57c2df2ff60c2" || apiKeyFromFile != filename {
+		t.Errorf("Expected the API key to be read from %s, got %q", filename, viper.GetString(varApiKey))
+	}
+
+	viper.Set(varApiKey, "fromflag")
+	if err := readApiKeyFile(); err != nil || viper.GetString(varApiKey) != "fromflag" || len(apiKeyFromFile) > 0 {
+		t.Errorf("Expected an API key that is set to take precedence over the file, got %q", viper.GetString(varApiKey))
+	}
+
+	viper.Set(varApiKey, "")
+	viper.Set(varApiKeyFile, filepath.Join(directory, "missing"))
+	if err := readApiKeyFile(); err == nil {
+		t.Error("Expected error for a missing API key file")
+	}
+}
+
+func TestWriteConfigFileMapPermissions(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+	filename := filepath.Join(directory, "nested", "cronitor.json")
+
+	if err := writeConfigFileMap(filename, map[string]interface{}{varHostname: "web1"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if info, _ := os.Stat(filename); info.Mode().Perm() != 0644 {
+		t.Errorf("Expected 0644 without keys, got %04o", info.Mode().Perm())
+	}
+
+	configMap := map[string]interface{}{
+		varHostname:       "web1",
+		configProfilesKey: map[string]interface{}{"staging": map[string]interface{}{varApiKey: "stagingkey"}},
+	}
+	if err := writeConfigFileMap(filename, configMap); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if info, _ := os.Stat(filename); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected 0600 with an API key in a profile, got %04o", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestSaveConfigValuesKeepsUnknownKeysAndProfiles(t *testing.T) {
+	file, _ := ioutil.TempFile("", "cronitor.json")
+	file.WriteString(`{"CRONITOR_API_KEY": "production", "CRONITOR_HOSTNAME": "web1", "CUSTOM": "keep"}`)
+	file.Close()
+	defer os.Remove(file.Name())
+
+	viper.Set(varConfig, file.Name())
+	defer viper.Set(varConfig, "")
+
+	values := map[string]interface{}{varApiKey: "staging", varHostname: "web1"}
+	if err := saveConfigValues(values, "staging"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	configMap, _ := readConfigFileMap(file.Name())
+	if configMap["CUSTOM"] != "keep" || configMap[varApiKey] != "production" {
+		t.Errorf("Top level values should be kept, got %v", configMap)
+	}
+
+	staging := configFileProfiles(configMap)["staging"]
+	if len(staging) != 1 || staging[varApiKey] != "staging" {
+		t.Errorf("Only values that differ from the top level should be saved in the profile, got %v", staging)
+	}
+
+	if err := saveConfigValues(map[string]interface{}{varHostname: "web2"}, defaultProfile); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	configMap, _ = readConfigFileMap(file.Name())
+	if configMap[varHostname] != "web2" || len(configFileProfiles(configMap)) != 1 {
+		t.Errorf("Saving the default profile should keep other profiles, got %v", configMap)
+	}
+}
+
+func TestRedactSecret(t *testing.T) {
+	tests := map[string]string{"": "", "abc": "***", "4319e94e890a": "********890a"}
+	for secret, expected := range tests {
+		if actual := redactSecret(secret); actual != expected {
+			t.Errorf("redactSecret(%q) = %q, expected %q", secret, actual, expected)
+		}
+	}
+}
+
+func TestFindConfigKey(t *testing.T) {
+	for _, name := range []string{"CRONITOR_API_KEY", "api_key", "api-key"} {
+		if key, err := findConfigKey(name); err != nil || key.Name != varApiKey {
+			t.Errorf("Expected %s to find %s, got %s %v", name, varApiKey, key.Name, err)
+		}
+	}
+
+	if _, err := findConfigKey("color"); err == nil {
+		t.Error("Expected error for unknown key")
+	}
+}
+
+func TestParseConfigValue(t *testing.T) {
+	tagsKey, _ := findConfigKey("tags")
+	if value, err := parseConfigValue(tagsKey, []string{"a", "b"}); err != nil || len(value.([]string)) != 2 {
+		t.Errorf("Expected a list value, got %v %v", value, err)
+	}
+
+	keyVersionKey, _ := findConfigKey("key-version")
+	if value, err := parseConfigValue(keyVersionKey, []string{"2"}); err != nil || value != 2 {
+		t.Errorf("Expected an int value, got %v %v", value, err)
+	}
+
+	if _, err := parseConfigValue(keyVersionKey, []string{"3"}); err == nil {
+		t.Error("Expected error for invalid key version")
+	}
+
+	hostnameKey, _ := findConfigKey("hostname")
+	if _, err := parseConfigValue(hostnameKey, []string{"a", "b"}); err == nil {
+		t.Error("Expected error for two values for a single value key")
+	}
+}
+
+func TestUpdateConfigValueYaml(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+	filename := directory + "/cronitor.yaml"
+	ioutil.WriteFile(filename, []byte("CRONITOR_HOSTNAME: web1\nprofiles:\n  staging:\n    CRONITOR_API_KEY: stagingkey\n"), 0644)
+
+	viper.Set(varConfig, filename)
+	defer viper.Set(varConfig, "")
+
+	if err := updateConfigValue(varGrace, "5m", "staging"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := updateConfigValue(varHostname, nil, defaultProfile); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	configMap, err := readConfigFileMap(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	staging := configFileProfiles(configMap)["staging"]
+	if _, exists := configMap[varHostname]; exists || staging[varGrace] != "5m" || staging[varApiKey] != "stagingkey" {
+		t.Errorf("Unexpected config after update: %v", configMap)
+	}
+}
+
+func TestConfigSetCanCreateMissingProfile(t *testing.T) {
+	profileErr = errors.New("the profile \"production\" was not found in the config file")
+	defer func() { profileErr = nil }()
+
+	if err := RootCmd.PersistentPreRunE(configSetCmd, []string{}); err != nil {
+		t.Errorf("Expected config set to run with a missing profile, got %s", err)
+	}
+	if err := RootCmd.PersistentPreRunE(configUnsetCmd, []string{}); err == nil {
+		t.Error("Expected config unset to be refused with a missing profile")
+	}
+
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+	filename := directory + "/cronitor.json"
+
+	viper.Set(varConfig, filename)
+	defer viper.Set(varConfig, "")
+
+	if err := updateConfigValue(varApiKey, "4319e94e890a013dbaca57c2df2ff60c2", "production"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	configMap, err := readConfigFileMap(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if configFileProfiles(configMap)["production"][varApiKey] != "4319e94e890a013dbaca57c2df2ff60c2" {
+		t.Errorf("Expected the profile to be created, got %v", configMap)
+	}
+	if info, err := os.Stat(filename); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a config file with an API key to be private, got %v %v", info.Mode(), err)
+	}
+}
+
+func TestValidateWritableFile(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+
+	if err := validateWritableFile(directory + "/cronitor.log"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	if _, err := os.Stat(directory + "/cronitor.log"); !os.IsNotExist(err) {
+		t.Error("Validating a log file should not create it")
+	}
+
+	if err := validateWritableFile(directory + "/missing/cronitor.log"); err == nil {
+		t.Error("Expected error for a directory that does not exist")
+	}
+}
+
+func TestConfigureKeepsEnvReferences(t *testing.T) {
+	file, _ := ioutil.TempFile("", "cronitor.json")
+	file.WriteString(`{"CRONITOR_API_KEY": "${CRONITOR_TEST_SECRET}", "CRONITOR_HOSTNAME": "a"}`)
+	file.Close()
+	defer os.Remove(file.Name())
+
+	viper.Set(varConfig, file.Name())
+	viper.Set(varApiKey, "s3cret")
+	defer viper.Set(varConfig, "")
+	defer viper.Set(varApiKey, "")
+
+	flags := pflag.NewFlagSet("configure", pflag.ContinueOnError)
+	flags.String("api-key", "", "")
+	flags.String("hostname", "", "")
+	flags.StringSlice("exclude-from-name", []string{}, "")
+	flags.Parse([]string{"--hostname", "b"})
+
+	if err := saveConfigValues(configureValues(flags), defaultProfile); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	configMap, _ := readConfigFileMap(file.Name())
+	if configMap[varApiKey] != "${CRONITOR_TEST_SECRET}" || configMap[varHostname] != "b" {
+		t.Errorf("Expected only the hostname to change, got %v", configMap)
+	}
+}
+
+func TestWriteConfigFileMapReplacesModeOfExistingFile(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+
+	filename := filepath.Join(directory, "cronitor.json")
+	ioutil.WriteFile(filename, []byte(`{"CRONITOR_HOSTNAME": "web1"}`), 0644)
+
+	if err := writeConfigFileMap(filename, map[string]interface{}{varApiKey: "4319e94e890a013dbaca57c2df2ff60c2"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if info, err := os.Stat(filename); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a config file with an API key to be private, got %v %v", info.Mode(), err)
+	}
+
+	if err := writeConfigFileMap(filepath.Join(filename, "nested", "cronitor.json"), map[string]interface{}{}); err == nil {
+		t.Error("Expected an error when the directory cannot be created")
+	}
+}
@@ -1,22 +1,13 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	"io/ioutil"
 	"os"
 )
 
-type ConfigFile struct {
-	ApiKey         string   `json:"CRONITOR_API_KEY"`
-	PingApiAuthKey string   `json:"CRONITOR_PING_API_KEY"`
-	ExcludeText    []string `json:"CRONITOR_EXCLUDE_TEXT,omitempty"`
-	Hostname       string   `json:"CRONITOR_HOSTNAME"`
-	Log            string   `json:"CRONITOR_LOG"`
-}
-
 // configureCmd represents the configure command
 var configureCmd = &cobra.Command{
 	Use:   "configure",
@@ -24,41 +15,91 @@ var configureCmd = &cobra.Command{
 	Long: `
 Optionally write configuration options to a JSON file.
 
-By default, configuration files are system-wide for ease of use in cron jobs and scripts. Default configuration file location varies by platform:
-  Linux        /etc/cronitor/cronitor.json
-  MacOS        /etc/cronitor/cronitor.json
-  Windows      %SystemDrive%\ProgramData\Cronitor\cronitor.json
+By default, configuration files are system-wide for ease of use in cron jobs and scripts. Configuration files are read from
+each of these locations, with the system-wide file taking precedence over the per-user file:
+  Linux        /etc/cronitor/cronitor.json, then $XDG_CONFIG_HOME/cronitor/cronitor.json (default ~/.config/cronitor)
+  MacOS        /etc/cronitor/cronitor.json, then $XDG_CONFIG_HOME/cronitor/cronitor.json (default ~/.config/cronitor)
+  Windows      %SystemDrive%\ProgramData\Cronitor\cronitor.json, then %APPDATA%\Cronitor\cronitor.json
+
+A cronitor.yaml file can be used in place of cronitor.json. When run as root, 'configure' writes the system-wide file;
+otherwise it writes the per-user file unless the system-wide file exists and is writable. Config files that contain
+an API key are written with 0600 permissions.
+
+Values in the config file can refer to environment variables with ${NAME}, and the API key can be read from a file
+with CRONITOR_API_KEY_FILE, for example a secret mounted by Docker or Kubernetes.
 
 CronitorCLI configuration can be supplied from a file, environment variables, or command line flags.
 You can use a default config file for some things and environment variables or command line arguments for others -- the goal is flexibility.
 
+Only the values given as flags are saved; values from environment variables and other config files are not copied
+into the file. To inspect your configuration without writing the file, use 'cronitor config show'. To change any other
+single value, use 'cronitor config set'.
+
 Environment variables that are read:
   CRONITOR_API_KEY
+  CRONITOR_API_KEY_FILE
+  CRONITOR_CA_BUNDLE
+  CRONITOR_CLIENT_CERT
+  CRONITOR_CLIENT_KEY
   CRONITOR_CONFIG
+  CRONITOR_ENVIRONMENT
   CRONITOR_EXCLUDE_TEXT
+  CRONITOR_GRACE
+  CRONITOR_HISTORY_MAX_AGE
+  CRONITOR_HISTORY_MAX_RUNS
   CRONITOR_HOSTNAME
+  CRONITOR_KEY_VERSION
   CRONITOR_LOG
+  CRONITOR_LOG_COMPRESS
+  CRONITOR_LOG_DIR
+  CRONITOR_LOG_MAX_AGE
+  CRONITOR_LOG_MAX_RUNS
+  CRONITOR_LOG_MAX_SIZE
+  CRONITOR_NAMES_FILE
+  CRONITOR_NAME_REWRITE
+  CRONITOR_NAME_TEMPLATE
   CRONITOR_PING_API_KEY
+  CRONITOR_PING_TIMEOUT
+  CRONITOR_PROFILE
+  CRONITOR_PROXY
+  CRONITOR_RETRIES
+  CRONITOR_RULES
+  CRONITOR_STATE_DIR
+  CRONITOR_TAG_RULES
+  CRONITOR_TAGS
+  CRONITOR_TEXTFILE
+  CRONITOR_TIMEOUT
 
 Example setting your API Key:
   $ cronitor configure --api-key 4319e94e890a013dbaca57c2df2ff60c2
 
+Example saving an API key for a second account in a named profile:
+  $ cronitor configure --profile staging --api-key 4319e94e890a013dbaca57c2df2ff60c2
+      > Only values that differ from the top level of the config file are saved in the profile
+      > Use the profile with 'cronitor --profile staging <command>' or CRONITOR_PROFILE=staging
+      > Keys in the config file that 'configure' does not know about are kept
+
+Example reading the API key from a file:
+  $ cronitor config set api_key_file /run/secrets/cronitor_api_key
+      > The file is read only when no API key is set with --api-key, CRONITOR_API_KEY or the config file
+
+Example sending requests through a TLS-intercepting proxy:
+  $ cronitor config set proxy http://egress.internal:3128
+  $ cronitor config set ca_bundle /etc/ssl/certs/egress-ca.pem
+      > HTTPS_PROXY and NO_PROXY are also honored when CRONITOR_PROXY is not set
+      > Use CRONITOR_CLIENT_CERT and CRONITOR_CLIENT_KEY if the proxy requires a client certificate
+
 Example setting common exclude text for use with 'cronitor discover':
   $ cronitor configure -e "/var/app/code/path/" -e "/var/app/bin/" -e "> /dev/null"`,
 	Run: func(cmd *cobra.Command, args []string) {
 
-		configData := ConfigFile{}
-		configData.ApiKey = viper.GetString(varApiKey)
-		configData.PingApiAuthKey = viper.GetString(varPingApiKey)
-		configData.ExcludeText = viper.GetStringSlice(varExcludeText)
-		configData.Hostname = viper.GetString(varHostname)
-		configData.Log = viper.GetString(varLog)
+		values := configureValues(cmd.Flags())
 
 		if verbose {
 			fmt.Println("\nAPI Key:")
-			fmt.Println(configData.ApiKey)
+			fmt.Println(viper.GetString(varApiKey))
 			fmt.Println("\nPing API Key:")
-			fmt.Println(configData.PingApiAuthKey)
+			fmt.Println(viper.GetString(varPingApiKey))
 			fmt.Println("\nHostname:")
 			fmt.Println(effectiveHostname())
 			fmt.Println("\nTimezone Location:")
@@ -67,29 +108,34 @@ Example setting common exclude text for use with 'cronitor discover':
 			fmt.Println(viper.GetString(varLog))
 		}
 
-		b, err := json.MarshalIndent(configData, "", "    ")
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
-		}
-
-		os.MkdirAll(defaultConfigFileDirectory(), os.ModePerm)
-		if ioutil.WriteFile(configFilePath(), b, 0644) != nil {
+		if err := saveConfigValues(values, effectiveProfile()); err != nil {
 			fmt.Fprintf(os.Stderr,
-				"\nERROR: The configuration file %s could not be written; check permissions and try again. "+
-					"\n\nBy default, configuration files are system-wide for ease of use in cron jobs and scripts. Specify an alternate config file using the --config argument or CRONITOR_CONFIG environment variable.\n\n", configFilePath())
+				"\nERROR: The configuration file %s could not be written: %s"+
+					"\n\nBy default, configuration files are system-wide for ease of use in cron jobs and scripts. Run as root, or specify an alternate config file using the --config argument or CRONITOR_CONFIG environment variable.\n\n", configFilePath(), err.Error())
 			os.Exit(126)
 		}
 	},
 }
 
-func configFilePath() string {
-	viperConfig := viper.ConfigFileUsed()
-	if len(viperConfig) > 0 {
-		return viperConfig
+// configureValues returns the config values given as flags. Values read from config files are never written back:
+// viper holds them with ${ENV} references replaced and the system and per-user files merged.
+func configureValues(flags *pflag.FlagSet) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, key := range configKeys {
+		if len(key.Flag) == 0 {
+			continue
+		}
+
+		if flag := flags.Lookup(key.Flag); flag != nil && flag.Changed {
+			values[key.Name] = flag.Value.String()
+		}
+	}
+
+	if flag := flags.Lookup("exclude-from-name"); flag != nil && flag.Changed {
+		values[varExcludeText], _ = flags.GetStringSlice("exclude-from-name")
 	}
 
-	return fmt.Sprintf("%s/cronitor.json", defaultConfigFileDirectory())
+	return values
 }
 
 func init() {
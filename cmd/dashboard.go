@@ -0,0 +1,528 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cronitor/lib/api"
+	"github.com/chzyer/readline"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var dashboardSelection monitorSelection
+var dashboardInterval time.Duration
+
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard [code...]",
+	Short: "Full-screen view of monitor status and activity",
+	Long: `
+A full-screen view of your monitors that refreshes until you quit. The activity of the selected monitor is shown below
+the list, and monitors whose state changed since the previous refresh are shown in bold.
+
+Keys:
+  up/down, j/k     Select a monitor
+  enter            Show the monitor's details and more of its activity; esc returns to the list
+  p                Pause alerts for the selected monitor until it is resumed
+  u                Resume alerts for the selected monitor
+  r                Refresh now
+  q                Quit
+
+Example:
+  $ cronitor dashboard
+      > Show every monitor, refreshing every 30 seconds
+
+  $ cronitor dashboard --tag production --interval 1m
+      > Show monitors tagged "production", refreshing every minute
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(viper.GetString(varApiKey)) < 10 {
+			return errors.New("you must provide an API key with this command or save a key using 'cronitor configure'")
+		}
+
+		if dashboardInterval < minimumWatchInterval {
+			return errors.New(fmt.Sprintf("--interval must be at least %s", minimumWatchInterval))
+		}
+
+		if !readline.IsTerminal(int(os.Stdin.Fd())) || !readline.IsTerminal(int(os.Stdout.Fd())) {
+			return errors.New("the dashboard must be run in a terminal; use 'cronitor status --watch' instead")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runDashboard(args); err != nil {
+			fatal(err.Error(), 1)
+		}
+	},
+}
+
+const (
+	dashboardList = iota
+	dashboardDetail
+)
+
+// dashboardActivityDelay is how long the selection must stay on a monitor before its activity is requested, so that
+// scrolling through the list does not send a request for every monitor passed
+const dashboardActivityDelay = 300 * time.Millisecond
+
+// dashboardState is everything shown on the dashboard. It is only changed by the event loop in runDashboard.
+type dashboardState struct {
+	view       int
+	monitors   []api.Monitor
+	states     map[string]string
+	changed    map[string]string
+	selected   int
+	offset     int
+	activity   map[string][]api.Event
+	message    string
+	err        error
+	loading    bool
+	lastUpdate time.Time
+}
+
+type dashboardMonitors struct {
+	monitors []api.Monitor
+	err      error
+}
+
+type dashboardActivity struct {
+	key    string
+	events []api.Event
+	err    error
+}
+
+// selectedMonitor returns the monitor under the cursor
+func (s *dashboardState) selectedMonitor() (api.Monitor, bool) {
+	if s.selected < 0 || s.selected >= len(s.monitors) {
+		return api.Monitor{}, false
+	}
+	return s.monitors[s.selected], true
+}
+
+// updateMonitors replaces the monitor list after a refresh, keeping the cursor on the same monitor
+func (s *dashboardState) updateMonitors(monitors []api.Monitor) {
+	selectedKey := ""
+	if monitor, ok := s.selectedMonitor(); ok {
+		selectedKey = monitor.Key
+	}
+
+	states := monitorStates(monitors)
+	if s.states != nil {
+		s.changed = changedMonitors(s.states, states)
+	}
+	s.states = states
+	s.monitors = monitors
+	s.selected = 0
+	for i, monitor := range monitors {
+		if monitor.Key == selectedKey {
+			s.selected = i
+		}
+	}
+}
+
+// move changes the selection by delta monitors, stopping at either end of the list
+func (s *dashboardState) move(delta int) {
+	s.selected += delta
+	if s.selected >= len(s.monitors) {
+		s.selected = len(s.monitors) - 1
+	}
+	if s.selected < 0 {
+		s.selected = 0
+	}
+}
+
+func runDashboard(args []string) error {
+	client := getCronitorApi()
+	stdin := int(os.Stdin.Fd())
+	stdout := int(os.Stdout.Fd())
+
+	rawState, err := readline.MakeRaw(stdin)
+	if err != nil {
+		return errors.New("cannot read keys from this terminal: " + err.Error())
+	}
+	defer readline.Restore(stdin, rawState)
+
+	// Use the alternate screen so the terminal is left as it was, and hide the cursor while the dashboard is shown
+	fmt.Print("\033[?1049h\033[?25l")
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	keys := make(chan string)
+	monitorResults := make(chan dashboardMonitors)
+	activityResults := make(chan dashboardActivity)
+	messages := make(chan string)
+
+	go readKeys(os.Stdin, keys)
+
+	refresh := func() {
+		go func() {
+			monitors, err := selectMonitorsOrAll(args, dashboardSelection)
+			monitorResults <- dashboardMonitors{monitors, err}
+		}()
+	}
+
+	loadActivity := func(key string) {
+		go func() {
+			events, err := client.Activity(apiContext(), key, api.ActivityOptions{})
+			activityResults <- dashboardActivity{key, events, err}
+		}()
+	}
+
+	update := func(monitor api.Monitor, verb, done string, change func() error) {
+		go func() {
+			if err := change(); err != nil {
+				messages <- fmt.Sprintf("Could not %s %s: %s", verb, monitor.Name, err.Error())
+				return
+			}
+			messages <- fmt.Sprintf("%s %s", done, monitor.Name)
+		}()
+	}
+
+	state := &dashboardState{activity: map[string][]api.Event{}, loading: true}
+	refreshTicker := time.NewTicker(dashboardInterval)
+	defer refreshTicker.Stop()
+	clock := time.NewTicker(time.Second)
+	defer clock.Stop()
+	var activityTimer <-chan time.Time
+
+	refresh()
+	for {
+		width, height, err := readline.GetSize(stdout)
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		drawDashboard(os.Stdout, renderDashboard(state, width, height))
+
+		previous, _ := state.selectedMonitor()
+		select {
+		case key := <-keys:
+			switch key {
+			case "q", "ctrl-c":
+				return nil
+			case "esc":
+				state.view = dashboardList
+			case "up", "k":
+				state.move(-1)
+			case "down", "j":
+				state.move(1)
+			case "pgup":
+				state.move(-10)
+			case "pgdn":
+				state.move(10)
+			case "enter":
+				if monitor, ok := state.selectedMonitor(); ok {
+					state.view = dashboardDetail
+					loadActivity(monitor.Key)
+				}
+			case "r":
+				state.loading = true
+				refresh()
+			case "p", "u":
+				monitor, ok := state.selectedMonitor()
+				if !ok {
+					break
+				}
+				if key == "p" {
+					state.message = "Pausing " + monitor.Name
+					update(monitor, "pause", "Paused", func() error { return client.PauseMonitor(apiContext(), monitor.Key, 0) })
+				} else {
+					state.message = "Resuming " + monitor.Name
+					update(monitor, "resume", "Resumed", func() error { return client.ResumeMonitor(apiContext(), monitor.Key) })
+				}
+			}
+		case result := <-monitorResults:
+			state.loading = false
+			state.err = result.err
+			if result.err == nil {
+				state.updateMonitors(result.monitors)
+				state.lastUpdate = time.Now()
+				if monitor, ok := state.selectedMonitor(); ok {
+					loadActivity(monitor.Key)
+				}
+			}
+		case result := <-activityResults:
+			if result.err != nil {
+				state.message = "Could not load activity: " + result.err.Error()
+			} else {
+				state.activity[result.key] = result.events
+			}
+		case message := <-messages:
+			state.message = message
+			state.loading = true
+			refresh()
+		case <-activityTimer:
+			activityTimer = nil
+			if monitor, ok := state.selectedMonitor(); ok {
+				loadActivity(monitor.Key)
+			}
+		case <-refreshTicker.C:
+			state.loading = true
+			refresh()
+		case <-clock.C:
+		case <-apiContext().Done():
+			return nil
+		}
+
+		if current, ok := state.selectedMonitor(); ok && current.Key != previous.Key {
+			activityTimer = time.After(dashboardActivityDelay)
+		}
+	}
+}
+
+// drawDashboard replaces the screen with lines, clearing what is left of each line and of the screen
+func drawDashboard(w io.Writer, lines []string) {
+	fmt.Fprint(w, "\033[H"+strings.Join(lines, "\033[K\r\n")+"\033[K\033[J")
+}
+
+// renderDashboard lays out the dashboard for a terminal of the given size, returning one string per screen line
+func renderDashboard(state *dashboardState, width, height int) []string {
+	if height < 4 {
+		height = 4
+	}
+
+	var lines []string
+
+	failing, paused := 0, 0
+	for _, monitor := range state.monitors {
+		switch monitorState(monitor) {
+		case "Failing":
+			failing++
+		case "Paused":
+			paused++
+		}
+	}
+
+	title := fmt.Sprintf(" Cronitor  %d monitors  %d failing  %d paused", len(state.monitors), failing, paused)
+	updated := " "
+	if state.loading {
+		updated = "refreshing... "
+	} else if !state.lastUpdate.IsZero() {
+		updated = "updated " + state.lastUpdate.Format("15:04:05") + " "
+	}
+	lines = append(lines, "\033[7m"+fitText(title, width-utf8.RuneCountInString(updated))+updated+"\033[0m")
+
+	if state.err != nil {
+		lines = append(lines, "\033[31m"+fitText(" Could not refresh: "+state.err.Error(), width)+"\033[0m")
+	} else {
+		lines = append(lines, fitText(" "+state.message, width))
+	}
+
+	var help string
+	if state.view == dashboardDetail {
+		lines = append(lines, renderDashboardDetail(state, width, height-3)...)
+		help = " esc back  p pause  u resume  r refresh  q quit"
+	} else {
+		lines = append(lines, renderDashboardList(state, width, height-3)...)
+		help = " ↑/↓ select  enter details  p pause  u resume  r refresh  q quit"
+	}
+
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines[:height-1], "\033[7m"+fitText(help, width)+"\033[0m")
+	return lines
+}
+
+// renderDashboardList shows the monitor list with the selected monitor's activity below it, in height lines
+func renderDashboardList(state *dashboardState, width, height int) []string {
+	activityHeight := height / 3
+	listHeight := height - activityHeight - 2
+	if listHeight < 1 {
+		listHeight, activityHeight = height-1, 0
+	}
+
+	nameWidth := (width - 22) / 2
+	statusWidth := width - 22 - nameWidth
+	row := func(health, name, code, status string) string {
+		return " " + fitText(health, 9) + fitText(name, nameWidth) + " " + fitText(code, 10) + " " + fitText(status, statusWidth)
+	}
+
+	lines := []string{"\033[1m" + row("Health", "Name", "Code", "Status") + "\033[0m"}
+	if len(state.monitors) == 0 && !state.loading {
+		lines = append(lines, " No monitors matched")
+	}
+
+	if state.selected < state.offset {
+		state.offset = state.selected
+	} else if state.selected >= state.offset+listHeight {
+		state.offset = state.selected - listHeight + 1
+	}
+
+	for i := state.offset; i < len(state.monitors) && i < state.offset+listHeight; i++ {
+		monitor := state.monitors[i]
+		health := monitorState(monitor)
+		style := ""
+		switch health {
+		case "Failing":
+			style = "\033[31m"
+		case "Paused":
+			style = "\033[33m"
+		}
+		if _, ok := state.changed[monitor.Code]; ok {
+			health += "*"
+			style += "\033[1m"
+		}
+		if i == state.selected {
+			style += "\033[7m"
+		}
+		lines = append(lines, style+row(health, monitor.Name, monitor.Code, monitor.Status)+"\033[0m")
+	}
+
+	if activityHeight == 0 {
+		return lines
+	}
+
+	for len(lines) < listHeight+1 {
+		lines = append(lines, "")
+	}
+
+	monitor, ok := state.selectedMonitor()
+	if !ok {
+		return lines
+	}
+
+	lines = append(lines, "\033[1m"+fitText(" Recent activity: "+monitor.Name, width)+"\033[0m")
+	return append(lines, renderDashboardActivity(state.activity[monitor.Key], width, activityHeight)...)
+}
+
+// renderDashboardDetail shows the selected monitor's settings followed by as much of its activity as fits
+func renderDashboardDetail(state *dashboardState, width, height int) []string {
+	monitor, ok := state.selectedMonitor()
+	if !ok {
+		return nil
+	}
+
+	health := monitorState(monitor)
+	if was, ok := state.changed[monitor.Code]; ok {
+		health = fmt.Sprintf("%s (was %s)", health, was)
+	}
+
+	lines := []string{
+		fitText(" Name:   "+monitor.Name, width),
+		fitText(fmt.Sprintf(" Code:   %s    Key: %s    Type: %s", monitor.Code, monitor.Key, monitor.Type), width),
+		fitText(" Health: "+health, width),
+		fitText(" Status: "+monitor.Status, width),
+		fitText(" Tags:   "+strings.Join(monitor.Tags, ", "), width),
+		"",
+		"\033[1m" + fitText(" Activity", width) + "\033[0m",
+	}
+
+	return append(lines, renderDashboardActivity(state.activity[monitor.Key], width, height-len(lines))...)
+}
+
+func renderDashboardActivity(events []api.Event, width, height int) []string {
+	if events == nil {
+		return []string{" Loading..."}
+	}
+	if len(events) == 0 {
+		return []string{" No activity"}
+	}
+
+	var lines []string
+	for i := 0; i < len(events) && i < height; i++ {
+		lines = append(lines, fitText(" "+formatActivityEvent(events[i]), width))
+	}
+	return lines
+}
+
+// fitText pads or truncates text to exactly width characters
+func fitText(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	runes := []rune(text)
+	if len(runes) > width {
+		if width == 1 {
+			return "…"
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
+
+// readKeys sends each key pressed to keys until reading fails
+func readKeys(r io.Reader, keys chan<- string) {
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+var dashboardKeySequences = map[string]string{
+	"\033[A":  "up",
+	"\033OA":  "up",
+	"\033[B":  "down",
+	"\033OB":  "down",
+	"\033[5~": "pgup",
+	"\033[6~": "pgdn",
+}
+
+// parseKeys names the keys in bytes read from a terminal in raw mode. Escape sequences that are not recognized are
+// dropped.
+func parseKeys(input []byte) []string {
+	var keys []string
+	for len(input) > 0 {
+		switch input[0] {
+		case 3:
+			keys = append(keys, "ctrl-c")
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 127, 8:
+			keys = append(keys, "esc")
+		case 27:
+			if len(input) == 1 {
+				keys = append(keys, "esc")
+				break
+			}
+
+			matched := false
+			for sequence, key := range dashboardKeySequences {
+				if strings.HasPrefix(string(input), sequence) {
+					keys = append(keys, key)
+					input = input[len(sequence)-1:]
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				// Skip an unknown CSI sequence up to its final byte
+				end := 1
+				if input[1] == '[' || input[1] == 'O' {
+					end = 2
+					for end < len(input) && (input[end] < 0x40 || input[end] > 0x7e) {
+						end++
+					}
+				}
+				if end >= len(input) {
+					end = len(input) - 1
+				}
+				input = input[end:]
+			}
+		default:
+			r, size := utf8.DecodeRune(input)
+			keys = append(keys, string(r))
+			input = input[size-1:]
+		}
+		input = input[1:]
+	}
+	return keys
+}
+
+func init() {
+	RootCmd.AddCommand(dashboardCmd)
+	addMonitorSelectionFlags(dashboardCmd, &dashboardSelection)
+	dashboardCmd.Flags().DurationVar(&dashboardInterval, "interval", 30*time.Second, "How often to refresh")
+}
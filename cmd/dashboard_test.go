@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"cronitor/lib/api"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChangedMonitors(t *testing.T) {
+	previous := monitorStates([]api.Monitor{
+		{Code: "c1", Passing: true},
+		{Code: "c2", Passing: false},
+		{Code: "c3", Passing: true},
+	})
+	current := monitorStates([]api.Monitor{
+		{Code: "c1", Passing: false},
+		{Code: "c2", Passing: false, Paused: true},
+		{Code: "c3", Passing: true},
+		{Code: "c4", Passing: false},
+	})
+
+	expected := map[string]string{"c1": "Ok", "c2": "Failing"}
+	if changed := changedMonitors(previous, current); !reflect.DeepEqual(changed, expected) {
+		t.Errorf("Expected %v, got %v", expected, changed)
+	}
+
+	if changed := changedMonitors(nil, current); len(changed) != 0 {
+		t.Errorf("Nothing should be reported as changed on the first poll, got %v", changed)
+	}
+}
+
+func TestRenderStatusTableShowsPreviousState(t *testing.T) {
+	var buf bytes.Buffer
+	monitors := []api.Monitor{{Code: "c1", Name: "Nightly backup", Passing: false}, {Code: "c2", Name: "Cleanup", Passing: true}}
+	renderStatusTable(&buf, monitors, map[string]string{"c1": "Ok"})
+
+	if !strings.Contains(buf.String(), "Failing (was Ok)") {
+		t.Errorf("Expected the changed monitor to show its previous state, got\n%s", buf.String())
+	}
+	if strings.Count(buf.String(), "(was") != 1 {
+		t.Errorf("Only the changed monitor should be highlighted, got\n%s", buf.String())
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := map[string][]string{
+		"q":              {"q"},
+		"\033[A\033[B":   {"up", "down"},
+		"\033OAjk\r":     {"up", "j", "k", "enter"},
+		"\033":           {"esc"},
+		"\033[1;5Cp\003": {"p", "ctrl-c"},
+		"\033[6~\033[5~": {"pgdn", "pgup"},
+	}
+
+	for input, expected := range tests {
+		if keys := parseKeys([]byte(input)); !reflect.DeepEqual(keys, expected) {
+			t.Errorf("parseKeys(%q) = %v, expected %v", input, keys, expected)
+		}
+	}
+}
+
+func TestFitText(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		expected string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abc…"},
+		{"héllo", 5, "héllo"},
+		{"abc", 0, ""},
+	}
+
+	for _, test := range tests {
+		if actual := fitText(test.text, test.width); actual != test.expected {
+			t.Errorf("fitText(%q, %d) = %q, expected %q", test.text, test.width, actual, test.expected)
+		}
+	}
+}
+
+func TestRenderDashboard(t *testing.T) {
+	state := &dashboardState{activity: map[string][]api.Event{}}
+	var monitors []api.Monitor
+	for _, code := range []string{"c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8", "c9", "c10"} {
+		monitors = append(monitors, api.Monitor{Key: "key-" + code, Code: code, Name: "Job " + code, Passing: true})
+	}
+	state.updateMonitors(monitors)
+	state.activity["key-c1"] = []api.Event{{Stamp: 1600000000, Event: "complete", Host: "web1", Duration: 1.5}}
+
+	lines := renderDashboard(state, 80, 15)
+	if len(lines) != 15 {
+		t.Fatalf("Expected 15 lines, got %d", len(lines))
+	}
+
+	screen := strings.Join(lines, "\n")
+	for _, expected := range []string{"10 monitors", "Job c1", "Recent activity: Job c1", "complete", "web1", "1.50s"} {
+		if !strings.Contains(screen, expected) {
+			t.Errorf("Expected dashboard to contain %q, got\n%s", expected, screen)
+		}
+	}
+
+	state.move(9)
+	screen = strings.Join(renderDashboard(state, 80, 15), "\n")
+	if !strings.Contains(screen, "Job c10") || strings.Contains(screen, "Job c1 ") {
+		t.Errorf("Expected the list to scroll to the last monitor, got\n%s", screen)
+	}
+
+	monitors[9].Passing = false
+	state.updateMonitors(append([]api.Monitor{{Key: "key-c0", Code: "c0", Name: "Job c0", Passing: true}}, monitors...))
+	if monitor, _ := state.selectedMonitor(); monitor.Code != "c10" {
+		t.Errorf("Expected the selection to stay on c10 after a refresh, got %s", monitor.Code)
+	}
+	if state.changed["c10"] != "Ok" {
+		t.Errorf("Expected c10 to be changed, got %v", state.changed)
+	}
+
+	state.view = dashboardDetail
+	screen = strings.Join(renderDashboard(state, 80, 15), "\n")
+	if !strings.Contains(screen, "Failing (was Ok)") || !strings.Contains(screen, "Key: key-c10") {
+		t.Errorf("Expected the details of c10, got\n%s", screen)
+	}
+}
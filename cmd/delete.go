@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"cronitor/lib/api"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var deleteSelection monitorSelection
+var deleteYes bool
+var deleteDryRun bool
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete [code...]",
+	Short: "Delete monitors",
+	Long: `
+Delete monitors and their history. Select monitors by code or key, or use --tag, --name and --this-host to select
+many at once. The selected monitors are listed and you are asked to confirm before anything is deleted.
+
+Example:
+  $ cronitor delete d3x0c1
+
+  $ cronitor delete --this-host --dry-run
+      > List the monitors for cron jobs on this host without deleting them
+
+  $ cronitor delete --tag decommissioned --yes
+      > Delete every monitor tagged "decommissioned" without asking to confirm
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(viper.GetString(varApiKey)) < 10 {
+			return errors.New("you must provide an API key with this command or save a key using 'cronitor configure'")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		monitors, err := selectMonitors(args, deleteSelection)
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		if len(monitors) == 0 {
+			printWarningText("No monitors matched", false)
+			return
+		}
+
+		printSuccessText(fmt.Sprintf("%d monitors selected", len(monitors)), false)
+		printSelectedMonitors(monitors)
+
+		if deleteDryRun {
+			printWarningText("This is a DRY-RUN. No monitors were deleted.", false)
+			return
+		}
+
+		if !deleteYes {
+			prompt := promptui.Prompt{
+				Label:     fmt.Sprintf("Delete %d monitors and their history", len(monitors)),
+				IsConfirm: true,
+			}
+
+			if _, err := prompt.Run(); err != nil {
+				printWarningText("Cancelled", false)
+				return
+			}
+		}
+
+		deleted := updateMonitors(monitors, func(client *api.Client, monitor api.Monitor) error {
+			return client.DeleteMonitor(apiContext(), monitor.Key)
+		})
+
+		printDoneText(fmt.Sprintf("Deleted %d monitors", deleted), false)
+		if deleted < len(monitors) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(deleteCmd)
+	addMonitorSelectionFlags(deleteCmd, &deleteSelection)
+	deleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Delete without asking to confirm")
+	deleteCmd.Flags().BoolVar(&deleteDryRun, "dry-run", false, "List the selected monitors without deleting them")
+}
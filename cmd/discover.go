@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"os"
 	"os/user"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
@@ -62,7 +65,17 @@ func (em ExistingMonitors) GetNameForCurrent() (string, error) {
 	return "", errors.New("does not exist")
 }
 
-func (em ExistingMonitors) AddName(name string) {
+func (em ExistingMonitors) GetByKey(key string) (lib.MonitorSummary, bool) {
+	for _, value := range em.Monitors {
+		if value.Key == key {
+			return value, true
+		}
+	}
+
+	return lib.MonitorSummary{}, false
+}
+
+func (em *ExistingMonitors) AddName(name string) {
 	em.Names = append(em.Names, name)
 }
 
@@ -75,6 +88,18 @@ var dryRun bool
 var timezone lib.TimezoneLocationName
 var maxNameLen = 75
 var notificationList string
+var discoverSystemd bool
+var kubernetesPath string
+var kubernetesOutput string
+var prune bool
+var migrateKeys bool
+var nameRewrites []string
+var graceSeconds uint
+var namesFile *lib.NamesFile
+var unmatchedJobs []string
+var extraRules []lib.Rule
+var ruleFlags []string
+var pruneAction string
 var existingMonitors = ExistingMonitors{}
 
 // To deprecate this feature we are hijacking this flag that will trigger removal of auto-discover lines from existing user's crontabs.
@@ -97,10 +122,23 @@ Example:
   $ cronitor discover /path/to/crontab
       > Instead of the user crontab, provide a crontab file (or directory of crontabs) to use
 
+  $ cronitor discover /etc/anacrontab
+      > Anacron jobs are monitored with an interval-based rule matching their period and delay
+
 Example that does not use an interactive shell:
   $ cronitor discover --auto
       > The only output to stdout will be your updated crontab file, suitable for piplines or writing to another crontab.
 
+Example discovering systemd timers:
+  $ cronitor discover --systemd
+      > Reads .timer units and their services from the standard systemd unit directories
+      > Adds Cronitor integration with a drop-in override in /etc/systemd/system/<service>.d/cronitor.conf
+
+Example discovering Kubernetes CronJobs:
+  $ cronitor discover --k8s ./deploy/cronjobs --k8s-output ./deploy/cronjobs-monitored.yaml
+      > Reads CronJob manifests (batch/v1 and batch/v1beta1) and creates a monitor for each, named by namespace/name
+      > Writes the manifests with each container command wrapped by cronitor exec. The image must include the cronitor binary.
+
 Example excluding secrets or common text from monitor names:
   $ cronitor discover /path/to/crontab -e "secret-token" -e "/var/common/app/path/"
       > Updates previously discovered monitors or creates new monitors, excluding the provided snippets from the monitor name.
@@ -109,12 +147,122 @@ Example excluding secrets or common text from monitor names:
 
   You can run the command as many times as you need, accumulating exclusion params until the job names on your Cronitor dashboard are clear and readable.
 
+Example naming monitors with a template:
+  $ cronitor discover --name-template "{{.Hostname}} {{.Script}}" --name-rewrite "^web-\d+=>web"
+      > Names each monitor from a Go template. Available fields are .Hostname, .RunAs, .Command, .Script (the name of
+        the program being run), .Crontab and .LineNumber (counted from 1)
+      > Rewrite rules are a regular expression and a replacement, separated by "=>", applied to every generated name
+      > Save CRONITOR_NAME_TEMPLATE and CRONITOR_NAME_REWRITE in your config file to use them on every host
+
+Example naming monitors from a file, for use with --auto:
+  $ cronitor discover --auto --names-file names.yaml
+      > Each entry matches jobs by monitor key, by a regular expression on the command, or by crontab and line number
+        (starting at 1), and sets the monitor name and optionally tags and a note, added after the discovery note
+      > When a command pattern matches several jobs, the line number is added to the name of each one after the first
+      > Jobs that match no entry are listed so the file can be filled in over time
+
+  names:
+    - key: 3c8e9f0a1b2c3d4e5f60718293a4b5c6d7e8f901
+      name: Nightly billing
+    - command: backup\.sh
+      name: Database backup
+      tags: [backup]
+    - crontab: /etc/cron.d/reports
+      line: 4
+      name: Weekly report
+      note: Owned by the data team
+
+Example tagging monitors:
+  $ cronitor discover --tag team:billing --environment production --tag-rule "backup=>backup" --auto-tags
+      > Every monitor is tagged cron-job, and --auto-tags adds host:<hostname>, crontab:<file name> and
+        user:<run-as user>
+      > --environment adds an env:<environment> tag, and --tag can be repeated to add your own tags
+      > Tag rules are a regular expression and a tag, separated by "=>". Jobs with a matching command get the tag.
+      > Save CRONITOR_TAGS, CRONITOR_TAG_RULES and CRONITOR_ENVIRONMENT in your config file to use them on every host
+
+Example adding grace periods and duration rules:
+  $ cronitor discover --grace 5m --rule "run_time_exceeds 30 minutes" --rule "ran_less_than 5 seconds"
+      > Allows 5 minutes after the scheduled time before a job is late, and alerts on jobs that run too long or too short
+      > Save CRONITOR_GRACE and CRONITOR_RULES in your config file to use them on every host
+      > Set them for one crontab with a comment anywhere in the file:
+        # cronitor-defaults: grace=10m rule="run_time_exceeds 1 hour" tags=reports notify=oncall
+
+Example controlling discovery with annotations:
+  # cronitor: name="Nightly billing" tags=billing,prod grace=10m rule="run_time_exceeds 2h" notify=oncall
+  0 2 * * * /var/app/bin/billing.sh
+
+  # cronitor: skip
+  */5 * * * * /var/app/bin/heartbeat.sh
+      > An annotation comment on the line above a job sets its monitor name, tags, grace period and notification lists
+      > Jobs annotated with "skip" are not monitored
+
+Example moving to schedule-independent keys:
+  $ cronitor discover --key-version 2 --migrate-keys
+      > Version 2 keys are based on the machine ID instead of the hostname and do not include the schedule, so a
+        schedule change updates the existing monitor instead of creating a new one
+      > Key versions apply to crontabs, anacron jobs and systemd timers; Kubernetes CronJob keys use namespace/name
+      > Existing monitors are moved from their old key to the new one so their history is kept
+      > Save the key version with CRONITOR_KEY_VERSION in your config file or environment
+      > A key can also be set for a single job with an annotation: # cronitor: key=nightly-billing
+
+Example removing monitors for jobs that were deleted from a crontab:
+  $ cronitor discover --prune --dry-run
+      > Lists monitors that were discovered on this host in the crontabs being read, but whose job is no longer there
+
+  $ cronitor discover --prune --prune-action delete
+      > Deletes those monitors after confirmation. By default they are paused instead.
+      > --prune cannot be used with --auto, because nobody would confirm which monitors are removed
+
 Example where you perform a dry-run without any crontab modifications:
   $ cronitor discover /path/to/crontab --dry-run
       > Steps line by line, creates or updates monitors
       > Checks permissions to ensure integration can be applied later
 	`,
 	Args: func(cmd *cobra.Command, args []string) error {
+		if !lib.IsValidKeyVersion(effectiveKeyVersion()) {
+			return errors.New("invalid key version. Expecting 1 or 2")
+		}
+
+		var err error
+		if nameTemplate, err = parseNameTemplate(viper.GetString(varNameTemplate)); err != nil {
+			return err
+		}
+
+		if nameRewriteRules, err = parseNameRewriteRules(append(viper.GetStringSlice(varNameRewrite), nameRewrites...)); err != nil {
+			return err
+		}
+
+		if tagRules, err = parseTagRules(append(viper.GetStringSlice(varTagRules), tagRuleFlags...)); err != nil {
+			return err
+		}
+
+		discoverTags = append(viper.GetStringSlice(varTags), discoverTags...)
+		discoverEnvironment = viper.GetString(varEnvironment)
+
+		if filename := viper.GetString(varNamesFile); len(filename) > 0 {
+			if namesFile, err = lib.ReadNamesFile(filename); err != nil {
+				return err
+			}
+		}
+
+		if grace := viper.GetString(varGrace); len(grace) > 0 {
+			if graceSeconds, err = lib.ParseGraceSeconds(grace); err != nil {
+				return err
+			}
+		}
+
+		extraRules = nil
+		for _, text := range append(viper.GetStringSlice(varRules), ruleFlags...) {
+			rule, err := lib.ParseRule(text)
+			if err != nil {
+				return err
+			}
+			extraRules = lib.MergeRules(extraRules, []lib.Rule{rule})
+		}
+
+		if pruneAction != "pause" && pruneAction != "delete" {
+			return errors.New("invalid argument supplied to 'prune-action'. Expecting 'pause' or 'delete'")
+		}
 
 		// If this is being run by cronitor exec, don't write anything to stdout
 		if os.Getenv("CRONITOR_EXEC") == "1" {
@@ -122,6 +270,11 @@ Example where you perform a dry-run without any crontab modifications:
 			isSilent = true
 		}
 
+		// Monitors are only pruned after a confirmation, so automated runs cannot prune
+		if prune && isAutoDiscover {
+			return errors.New("--prune cannot be used with --auto. Run 'cronitor discover --prune' interactively to confirm which monitors are paused or deleted")
+		}
+
 		if len(viper.GetString(varApiKey)) < 10 {
 			return errors.New("you must provide a valid API key with this command or save a key using 'cronitor configure'")
 		}
@@ -138,12 +291,30 @@ Example where you perform a dry-run without any crontab modifications:
 		printSuccessText("Scanning for cron jobs... (Use Ctrl-C to skip)", false)
 
 		// Fetch list of existing monitor names for easy unique name validation and prompt prefill later on
-		existingMonitors.Monitors, _ = getCronitorApi().GetMonitors()
+		var existingMonitorsErr error
+		existingMonitors.Monitors, existingMonitorsErr = getMonitorSummaries()
 
-		if len(args) > 0 {
+		if len(kubernetesPath) > 0 {
+			if processKubernetesManifests(kubernetesPath) {
+				importedCrontabs++
+			}
+		} else if discoverSystemd {
+			// Timers are read from the supplied directory, or the standard unit directories
+			if len(args) > 0 {
+				if processSystemdTimers([]string{args[0]}, args[0]) {
+					importedCrontabs++
+				}
+			} else if processSystemdTimers(lib.SYSTEMD_UNIT_DIRECTORIES, lib.SYSTEMD_OVERRIDE_DIRECTORY) {
+				importedCrontabs++
+			}
+		} else if len(args) > 0 {
 			// A supplied argument can be a specific file or a directory
 			if isPathToDirectory(args[0]) {
 				processDirectory(username, args[0])
+			} else if lib.IsAnacrontabFile(args[0]) {
+				if processAnacrontab(lib.AnacrontabFactory(args[0])) {
+					importedCrontabs++
+				}
 			} else {
 				if processCrontab(lib.CrontabFactory(username, args[0])) {
 					importedCrontabs++
@@ -162,12 +333,31 @@ Example where you perform a dry-run without any crontab modifications:
 			}
 
 			processDirectory(username, lib.DROP_IN_DIRECTORY)
+
+			if anacrontab := lib.AnacrontabFactory(lib.ANACRONTAB); anacrontab.Exists() {
+				if processAnacrontab(anacrontab) {
+					importedCrontabs++
+				}
+			}
+		}
+
+		if len(unmatchedJobs) > 0 {
+			reportUnmatchedJobs()
+		}
+
+		if prune {
+			if existingMonitorsErr != nil {
+				printErrorText("Cannot prune monitors: "+existingMonitorsErr.Error(), false)
+			} else {
+				pruneMonitors()
+			}
 		}
 
 		printDoneText("Discover complete", false)
 		if dryRun {
 			saveCommand := strings.Join(os.Args, " ")
 			saveCommand = strings.Replace(saveCommand, " --dry-run", "", -1)
+			saveCommand = lib.RemovePruneFlags(saveCommand)
 
 			if importedCrontabs > 0 {
 				printWarningText("Reminder: This is a DRY-RUN. Integration is not complete.", true)
@@ -217,12 +407,18 @@ func processCrontab(crontab *lib.Crontab) bool {
 		return false
 	}
 
+	if crontab.DefaultsError != nil {
+		printWarningText(crontab.DefaultsError.Error(), true)
+	}
+
 	// Before going further, ensure we aren't going to run into permissions problems writing the crontab later
 	if !crontab.IsWritable() {
 		printWarningText(fmt.Sprintf("This crontab is not writeable. Re-run command with sudo. Skipping"), true)
 		return false
 	}
 
+	recordDiscoveredKeys(crontab)
+
 	// If a timezone env var is set in the crontab it takes precedence over system tz
 	if crontab.TimezoneLocationName != nil {
 		timezone = *crontab.TimezoneLocationName
@@ -234,7 +430,7 @@ func processCrontab(crontab *lib.Crontab) bool {
 	if !isAutoDiscover {
 		count := 0
 		for _, line := range crontab.Lines {
-			if line.IsMonitorable() && !line.IsAutoDiscoverCommand() {
+			if line.IsMonitorable() && !line.IsAutoDiscoverCommand() && (line.Annotation == nil || !line.Annotation.Skip) {
 				count++
 			}
 		}
@@ -255,45 +451,93 @@ func processCrontab(crontab *lib.Crontab) bool {
 			continue
 		}
 
-		rules := []lib.Rule{createRule(line.CronExpression)}
+		if line.AnnotationError != nil {
+			printWarningText(fmt.Sprintf("Line %d: %s", line.LineNumber+1, line.AnnotationError.Error()), true)
+		}
+
+		if line.Annotation != nil && line.Annotation.Skip {
+			log(fmt.Sprintf("Skipping %s L%d: skip annotation", crontab.DisplayName(), line.LineNumber))
+			continue
+		}
+
+		rules := createRules(line.CronExpression, crontab.Defaults, line.Annotation)
 		defaultName := createDefaultName(line, crontab, effectiveHostname(), excludeFromName, allNameCandidates)
-		tags := createTags()
-		key := line.Key(crontab.CanonicalName())
+		tags := createTags(TagContext{Hostname: effectiveHostname(), RunAs: line.RunAs, Command: line.CommandToRun, Source: crontabTagName(crontab)})
+		key := crontab.Key(line, effectiveKeyVersion())
+		code := line.Code
 		name := defaultName
 		skip := false
+		notifications := []string{}
+		if notificationList != "" {
+			notifications = []string{notificationList}
+		}
+
+		// Sending the code of the monitor with the old key along with the new key moves the monitor to the new key
+		if migrateKeys && code == "" {
+			code = migrateKey(line.Key(crontab.CanonicalName()), key)
+		}
 
 		// If we know this monitor exists already, return the name
 		existingMonitors.CurrentKey = key
-		existingMonitors.CurrentCode = line.Code
+		existingMonitors.CurrentCode = code
 		if existingName, err := existingMonitors.GetNameForCurrent(); err == nil {
 			name = existingName
 		}
 
-		if !isAutoDiscover && !line.IsAutoDiscoverCommand() {
-			fmt.Println(fmt.Sprintf("\n    %s  %s", line.CronExpression, line.CommandToRun))
-			for {
-				prompt := promptui.Prompt{
-					Label:     "Job name",
-					Default:   name,
-					Validate:  validateName,
-					AllowEdit: name != defaultName,
-					Templates: promptTemplates(),
+		// A names file sets names without prompting, so automated runs don't have to use generated names
+		hasMappedName := false
+		note := createNote(line, crontab)
+		if namesFile != nil && !line.IsAutoDiscoverCommand() {
+			if mapping := namesFile.Match(key, line.CommandToRun, crontab, line.LineNumber+1); mapping != nil {
+				if len(mapping.Name) > 0 {
+					if mappedName, err := createMappedName(mapping, line); err != nil {
+						printWarningText(fmt.Sprintf("Line %d: the name \"%s\" from %s cannot be used: %s", line.LineNumber+1, mapping.Name, namesFile.Filename, err.Error()), true)
+					} else {
+						name = mappedName
+						hasMappedName = true
+					}
+				}
+				// The discovery note is kept first because prune relies on it
+				if len(mapping.Note) > 0 {
+					note = note + "\n" + mapping.Note
 				}
+				tags = uniqueTags(append(tags, mapping.Tags...))
+			} else if line.Annotation == nil || len(line.Annotation.Name) == 0 {
+				unmatchedJobs = append(unmatchedJobs, fmt.Sprintf("key: %s  crontab: %s  line: %d  command: %s", key, crontab.DisplayName(), line.LineNumber+1, line.CommandToRun))
+			}
+		}
+
+		// Settings from a cronitor-defaults comment apply to every job in the crontab
+		if defaults := crontab.Defaults; defaults != nil {
+			tags = uniqueTags(append(tags, defaults.Tags...))
+			if len(defaults.Notify) > 0 {
+				notifications = defaults.Notify
+			}
+		}
 
-				if result, err := prompt.Run(); err == nil {
-					name = result
-				} else if err == promptui.ErrInterrupt {
-					printWarningText("Skipped", true)
-					skip = true
-					break
+		// Settings from an annotation comment take precedence, and a named job doesn't need to be prompted for
+		hasAnnotatedName := false
+		if annotation := line.Annotation; annotation != nil {
+			if annotation.Name != "" {
+				if err := validateName(annotation.Name); err != nil {
+					printWarningText(fmt.Sprintf("Line %d: the name \"%s\" from the annotation cannot be used: %s", line.LineNumber+1, annotation.Name, err.Error()), true)
 				} else {
-					printErrorText("Error: "+err.Error()+"\n", false)
+					name = annotation.Name
+					hasAnnotatedName = true
 				}
+			}
 
-				break
+			tags = uniqueTags(append(tags, annotation.Tags...))
+			if len(annotation.Notify) > 0 {
+				notifications = annotation.Notify
 			}
 		}
 
+		if !isAutoDiscover && !line.IsAutoDiscoverCommand() && !hasAnnotatedName && !hasMappedName {
+			fmt.Println(fmt.Sprintf("\n    %s  %s", line.CronExpression, line.CommandToRun))
+			name, skip = promptForName(name, defaultName)
+		}
+
 		if skip {
 			continue
 		}
@@ -305,8 +549,8 @@ func processCrontab(crontab *lib.Crontab) bool {
 		}
 
 		notificationListMap := map[string][]string{}
-		if notificationList != "" {
-			notificationListMap = map[string][]string{"templates": {notificationList}}
+		if len(notifications) > 0 {
+			notificationListMap = map[string][]string{"templates": notifications}
 		}
 
 		line.Mon = lib.Monitor{
@@ -316,9 +560,9 @@ func processCrontab(crontab *lib.Crontab) bool {
 			Rules:            rules,
 			Tags:             tags,
 			Type:             "heartbeat",
-			Code:             line.Code,
+			Code:             code,
 			Timezone:         timezone.Name,
-			Note:             createNote(line, crontab),
+			Note:             note,
 			Notifications:    notificationListMap,
 			NoStdoutPassthru: noStdoutPassthru,
 		}
@@ -333,7 +577,7 @@ func processCrontab(crontab *lib.Crontab) bool {
 	}
 
 	var err error
-	monitors, err = getCronitorApi().PutMonitors(monitors)
+	monitors, err = putMonitors(monitors)
 	if err != nil {
 		fatal(err.Error(), 1)
 	}
@@ -362,12 +606,225 @@ func processCrontab(crontab *lib.Crontab) bool {
 	return len(monitors) > 0
 }
 
+func processAnacrontab(anacrontab *lib.Anacrontab) bool {
+	defer printLn()
+	printSuccessText(fmt.Sprintf("Checking %s", anacrontab.DisplayName()), false)
+
+	if !anacrontab.Exists() {
+		printWarningText("This anacrontab does not exist. Skipping.", true)
+		return false
+	}
+
+	if err := anacrontab.Parse(); err != nil {
+		printWarningText("This anacrontab is empty. Skipping.", true)
+		log(fmt.Sprintf("Skipping %s: %s", anacrontab.DisplayName(), err.Error()))
+		return false
+	}
+
+	if !anacrontab.IsWritable() {
+		printWarningText(fmt.Sprintf("This anacrontab is not writeable. Re-run command with sudo. Skipping"), true)
+		return false
+	}
+
+	if anacrontab.TimezoneLocationName != nil {
+		timezone = *anacrontab.TimezoneLocationName
+	} else {
+		timezone = effectiveTimezoneLocationName()
+	}
+
+	if !isAutoDiscover {
+		count := 0
+		for _, job := range anacrontab.Lines {
+			if job.IsMonitorable() {
+				count++
+			}
+		}
+
+		label := "jobs"
+		if count == 1 {
+			label = "job"
+		}
+		printSuccessText(fmt.Sprintf("Found %d anacron %s:", count, label), true)
+	}
+
+	monitors := map[string]*lib.Monitor{}
+	allNameCandidates := map[string]bool{}
+
+	for _, job := range anacrontab.Lines {
+		if !job.IsMonitorable() {
+			continue
+		}
+
+		rules := append([]lib.Rule{createAnacronRule(job, anacrontab.RandomDelayMinutes())}, extraRules...)
+		defaultName := createAnacronDefaultName(job, effectiveHostname(), allNameCandidates)
+		key := job.Key(effectiveKeyVersion())
+		code := job.Code
+		name := defaultName
+		skip := false
+
+		if migrateKeys && code == "" {
+			code = migrateKey(job.Key(lib.KEY_VERSION_1), key)
+		}
+
+		existingMonitors.CurrentKey = key
+		existingMonitors.CurrentCode = code
+		if existingName, err := existingMonitors.GetNameForCurrent(); err == nil {
+			name = existingName
+		}
+
+		if !isAutoDiscover {
+			fmt.Println(fmt.Sprintf("\n    %s  %s", job.Schedule(), job.CommandToRun))
+			name, skip = promptForName(name, defaultName)
+		}
+
+		if skip {
+			continue
+		}
+
+		existingMonitors.AddName(name)
+
+		if name == defaultName {
+			name = ""
+		}
+
+		notificationListMap := map[string][]string{}
+		if notificationList != "" {
+			notificationListMap = map[string][]string{"templates": {notificationList}}
+		}
+
+		job.Mon = lib.Monitor{
+			Name:             name,
+			DefaultName:      defaultName,
+			Key:              key,
+			Rules:            rules,
+			Tags:             append(createTags(TagContext{Hostname: effectiveHostname(), Command: job.CommandToRun, Source: filepath.Base(anacrontab.Filename)}), "anacron"),
+			Type:             "heartbeat",
+			Code:             code,
+			Timezone:         timezone.Name,
+			Note:             discoveryNote(anacrontab.DisplayName(), job.LineNumber, effectiveHostname()),
+			Notifications:    notificationListMap,
+			NoStdoutPassthru: noStdoutPassthru,
+		}
+
+		monitors[key] = &job.Mon
+	}
+
+	printLn()
+
+	if len(monitors) > 0 {
+		printDoneText("Sending to Cronitor", true)
+	}
+
+	var err error
+	monitors, err = putMonitors(monitors)
+	if err != nil {
+		fatal(err.Error(), 1)
+	}
+
+	updatedAnacrontabLines := anacrontab.Write()
+
+	if !isSilent && isAutoDiscover {
+		fmt.Println(strings.TrimSpace(updatedAnacrontabLines))
+	}
+
+	if !dryRun && len(monitors) > 0 {
+		if err := anacrontab.Save(updatedAnacrontabLines); err == nil {
+			if !isSilent {
+				printDoneText("Integration complete", true)
+			}
+		} else {
+			if !isSilent {
+				printErrorText("Problem saving anacrontab: "+err.Error(), true)
+			}
+			return false
+		}
+	}
+
+	return len(monitors) > 0
+}
+
+// reportUnmatchedJobs lists jobs that had no entry in the names file so the file can be filled in over time
+func reportUnmatchedJobs() {
+	message := fmt.Sprintf("%d jobs did not match an entry in %s:", len(unmatchedJobs), namesFile.Filename)
+	if isSilent {
+		log(message)
+		for _, job := range unmatchedJobs {
+			log("    " + job)
+		}
+		return
+	}
+
+	// In --auto mode stdout is the updated crontab, so the report goes to stderr
+	if isAutoDiscover {
+		fmt.Fprintln(os.Stderr, message)
+		for _, job := range unmatchedJobs {
+			fmt.Fprintln(os.Stderr, "    "+job)
+		}
+		return
+	}
+
+	printWarningText(message, false)
+	for _, job := range unmatchedJobs {
+		fmt.Println("      " + job)
+	}
+	printLn()
+}
+
+// migrateKey returns the code of the monitor with the old key if it should be moved to the new key
+func migrateKey(oldKey, newKey string) string {
+	if oldKey == newKey {
+		return ""
+	}
+
+	if _, exists := existingMonitors.GetByKey(newKey); exists {
+		return ""
+	}
+
+	monitor, exists := existingMonitors.GetByKey(oldKey)
+	if !exists {
+		return ""
+	}
+
+	printSuccessText(fmt.Sprintf("Migrating monitor key %s to %s", oldKey, newKey), true)
+	return monitor.Code
+}
+
+func effectiveKeyVersion() int {
+	if version := viper.GetInt(varKeyVersion); version != 0 {
+		return version
+	}
+
+	return lib.KEY_VERSION_1
+}
+
+// promptForName asks the user to confirm or edit a monitor name. The second return value is true if the job was skipped.
+func promptForName(name, defaultName string) (string, bool) {
+	prompt := promptui.Prompt{
+		Label:     "Job name",
+		Default:   name,
+		Validate:  validateName,
+		AllowEdit: name != defaultName,
+		Templates: promptTemplates(),
+	}
+
+	if result, err := prompt.Run(); err == nil {
+		name = result
+	} else if err == promptui.ErrInterrupt {
+		printWarningText("Skipped", true)
+		return name, true
+	} else {
+		printErrorText("Error: "+err.Error()+"\n", false)
+	}
+
+	return name, false
+}
+
 func createNote(line *lib.Line, crontab *lib.Crontab) string {
 	if line.IsAutoDiscoverCommand() {
 		return fmt.Sprintf("Watching for schedule changes and new entries in %s", crontab.DisplayName())
 	}
 
-	return fmt.Sprintf("Discovered in %s L%d", crontab.DisplayName(), line.LineNumber)
+	return discoveryNote(crontab.DisplayName(), line.LineNumber, effectiveHostname())
 }
 
 func createDefaultName(line *lib.Line, crontab *lib.Crontab, effectiveHostname string, excludeFromName []string, allNameCandidates map[string]bool) string {
@@ -382,7 +839,8 @@ func createDefaultName(line *lib.Line, crontab *lib.Crontab, effectiveHostname s
 	excludeFromName = append(excludeFromName, "\"")
 	excludeFromName = append(excludeFromName, "\\")
 
-	// Limit the visible hostname portion to 21 chars
+	// Limit the visible hostname portion to 21 chars. Name templates get the full hostname.
+	templateHostname := effectiveHostname
 	formattedHostname := ""
 	if effectiveHostname != "" {
 		if len(effectiveHostname) > 21 {
@@ -420,22 +878,45 @@ func createDefaultName(line *lib.Line, crontab *lib.Crontab, effectiveHostname s
 	}
 
 	candidate := formattedHostname + formattedRunAs + CommandToRun
+	commandPrefixLen := 20 + len(formattedHostname) + len(formattedRunAs)
 
-	if _, exists := allNameCandidates[candidate]; exists {
-		candidate = fmt.Sprintf("%s%s", candidate, lineNumSuffix)
-	} else {
+	if nameTemplate != nil || len(nameRewriteRules) > 0 {
+		custom := candidate
+		if nameTemplate != nil {
+			custom = renderNameTemplate(nameTemplate, NameTemplateData{
+				Hostname:   templateHostname,
+				RunAs:      line.RunAs,
+				Command:    CommandToRun,
+				Script:     scriptName(line.CommandToRun),
+				Crontab:    crontab.DisplayName(),
+				LineNumber: line.LineNumber + 1,
+			})
+		}
+
+		// A template or rewrite that leaves nothing would give the monitor an empty name, so keep the default
+		if custom = applyNameRewriteRules(custom, nameRewriteRules); len(custom) > 0 {
+			if nameTemplate != nil {
+				commandPrefixLen = 20
+			}
+			candidate = custom
+		} else {
+			log(fmt.Sprintf("Name template or rewrite rules left no name for %s L%d, using the default name", crontab.DisplayName(), line.LineNumber))
+		}
+	}
+
+	if _, exists := allNameCandidates[candidate]; !exists {
 		allNameCandidates[candidate] = true
+		lineNumSuffix = ""
 	}
 
 	// Return if short, truncate if necessary.
-	if maxNameLen >= len(candidate) {
-		return candidate
+	if maxNameLen >= len(candidate)+len(lineNumSuffix) {
+		return candidate + lineNumSuffix
 	}
 
 	// Keep the first and last portion of the command
 	separator := "..."
 
-	commandPrefixLen := 20 + len(formattedHostname) + len(formattedRunAs)
 	commandSuffixLen := maxNameLen - len(lineNumSuffix) - commandPrefixLen - len(separator)
 	return fmt.Sprintf(
 		"%s%s%s%s",
@@ -444,14 +925,100 @@ func createDefaultName(line *lib.Line, crontab *lib.Crontab, effectiveHostname s
 		strings.TrimSpace(candidate[len(candidate)-commandSuffixLen:]), lineNumSuffix)
 }
 
-func createTags() []string {
-	var tags []string
-	tags = append(tags, "cron-job")
-	return tags
+func createAnacronDefaultName(job *lib.AnacronJob, effectiveHostname string, allNameCandidates map[string]bool) string {
+	// Anacron requires job identifiers to be unique, so they make a natural name
+	candidate := fmt.Sprintf("%sanacron %s", formatHostnameForName(effectiveHostname), job.Identifier)
+	if _, exists := allNameCandidates[candidate]; exists {
+		candidate = fmt.Sprintf("%s L%d", candidate, job.LineNumber)
+	}
+	allNameCandidates[candidate] = true
+
+	return truncateString(candidate, maxNameLen)
+}
+
+// formatHostnameForName returns the "[hostname] " prefix used in default names, limited to 21 visible chars
+func formatHostnameForName(effectiveHostname string) string {
+	if effectiveHostname == "" {
+		return ""
+	}
+
+	if len(effectiveHostname) > 21 {
+		effectiveHostname = fmt.Sprintf("%s...%s", effectiveHostname[:9], effectiveHostname[len(effectiveHostname)-9:])
+	}
+
+	return fmt.Sprintf("[%s] ", effectiveHostname)
+}
+
+// crontabTagName is the crontab file name used in the automatic crontab tag
+func crontabTagName(crontab *lib.Crontab) string {
+	if crontab.IsUserCrontab {
+		return "user"
+	}
+
+	return filepath.Base(crontab.Filename)
 }
 
 func createRule(cronExpression string) lib.Rule {
-	return lib.Rule{"not_on_schedule", cronExpression, "", 0}
+	return lib.Rule{"not_on_schedule", cronExpression, "", graceSeconds}
+}
+
+// createRules returns the schedule rule and any extra rules for a job. Grace periods and rules from the command line
+// or config file are overridden by a cronitor-defaults comment in the crontab, which is overridden by an annotation on
+// the job. Extra rules replace any rule of the same type from a less specific setting.
+func createRules(cronExpression string, settings ...*lib.Annotation) []lib.Rule {
+	scheduleRule := createRule(cronExpression)
+	rules := extraRules
+	for _, setting := range settings {
+		if setting == nil {
+			continue
+		}
+
+		if setting.HasGrace {
+			scheduleRule.GraceSeconds = setting.GraceSeconds
+		}
+		rules = lib.MergeRules(rules, setting.Rules)
+	}
+
+	return append([]lib.Rule{scheduleRule}, rules...)
+}
+
+// createAnacronRule expects a completion at least once per anacron period. Anacron waits the job delay plus any
+// RANDOM_DELAY before starting a job, so that time is allowed as grace.
+func createAnacronRule(job *lib.AnacronJob, randomDelayMinutes int) lib.Rule {
+	interval := time.Duration(job.PeriodDays) * 24 * time.Hour
+	return createIntervalRule(interval, uint((job.DelayMinutes+randomDelayMinutes)*60)+graceSeconds)
+}
+
+// createIntervalRule expects a completion at least once per interval, expressed in the largest whole time unit
+func createIntervalRule(interval time.Duration, graceSeconds uint) lib.Rule {
+	value, timeUnit := int64(interval/time.Second), "seconds"
+	for _, unit := range []struct {
+		name     string
+		duration time.Duration
+	}{{"days", 24 * time.Hour}, {"hours", time.Hour}, {"minutes", time.Minute}} {
+		if interval%unit.duration == 0 {
+			value, timeUnit = int64(interval/unit.duration), unit.name
+			break
+		}
+	}
+
+	return lib.Rule{
+		RuleType:     "complete_ping_not_received",
+		Value:        strconv.FormatInt(value, 10),
+		TimeUnit:     timeUnit,
+		GraceSeconds: graceSeconds,
+	}
+}
+
+// createMappedName returns the name a names file mapping gives a job. A command pattern can match several jobs, so
+// when its name is taken the line number is added, as it is for generated names.
+func createMappedName(mapping *lib.NameMapping, line *lib.Line) (string, error) {
+	name := mapping.Name
+	if len(mapping.Command) > 0 && existingMonitors.HasMonitorByName(name) {
+		name = fmt.Sprintf("%s L%d", name, line.LineNumber)
+	}
+
+	return name, validateName(name)
 }
 
 func validateName(candidateName string) error {
@@ -491,6 +1058,27 @@ func init() {
 	discoverCmd.Flags().BoolVar(&noAutoDiscover, "no-auto-discover", noAutoDiscover, "Do not attach an automatic discover job to this crontab, or remove if already attached.")
 	discoverCmd.Flags().BoolVar(&noStdoutPassthru, "no-stdout", noStdoutPassthru, "Do not send cron job output to Cronitor when your job completes.")
 	discoverCmd.Flags().StringVar(&notificationList, "notification-list", notificationList, "Use the provided notification list when creating or updating monitors, or \"default\" list if omitted.")
+	discoverCmd.Flags().BoolVar(&discoverSystemd, "systemd", discoverSystemd, "Discover systemd timers instead of cron jobs. Optionally provide a directory of unit files to read.")
+	discoverCmd.Flags().StringVar(&kubernetesPath, "k8s", kubernetesPath, "Discover Kubernetes CronJobs in the provided manifest file or directory instead of cron jobs.")
+	discoverCmd.Flags().StringVar(&kubernetesOutput, "k8s-output", kubernetesOutput, "Write manifests with container commands wrapped by cronitor exec to this file. Written to stdout if omitted.")
+	discoverCmd.Flags().String("name-template", "", "Go template for generated monitor names, e.g. \"{{.Hostname}} {{.Script}}\"; .LineNumber counts from 1")
+	discoverCmd.Flags().StringArrayVar(&nameRewrites, "name-rewrite", nameRewrites, "Rewrite generated monitor names with a \"pattern=>replacement\" regular expression rule. Can be repeated.")
+	viper.BindPFlag(varNameTemplate, discoverCmd.Flags().Lookup("name-template"))
+	discoverCmd.Flags().String("names-file", "", "YAML file that sets monitor names, tags and notes for matching jobs without prompting")
+	viper.BindPFlag(varNamesFile, discoverCmd.Flags().Lookup("names-file"))
+	discoverCmd.Flags().String("grace", "", "Grace period after the scheduled time before a job is late, e.g. 5m or 300")
+	discoverCmd.Flags().StringArrayVar(&ruleFlags, "rule", ruleFlags, "Add a rule to every discovered monitor, e.g. \"run_time_exceeds 30 minutes\". Can be repeated.")
+	viper.BindPFlag(varGrace, discoverCmd.Flags().Lookup("grace"))
+	discoverCmd.Flags().StringArrayVar(&discoverTags, "tag", discoverTags, "Add a tag to every discovered monitor. Can be repeated.")
+	discoverCmd.Flags().StringArrayVar(&tagRuleFlags, "tag-rule", tagRuleFlags, "Tag monitors whose command matches a \"pattern=>tag\" regular expression rule. Can be repeated.")
+	discoverCmd.Flags().BoolVar(&autoTags, "auto-tags", autoTags, "Add host, crontab and user tags to discovered monitors.")
+	discoverCmd.Flags().String("environment", "", "Add an env:<environment> tag to every discovered monitor, e.g. production")
+	viper.BindPFlag(varEnvironment, discoverCmd.Flags().Lookup("environment"))
+	discoverCmd.Flags().Int("key-version", lib.KEY_VERSION_1, "Monitor key version. 1 uses the hostname and schedule, 2 uses the machine ID and is unaffected by schedule changes.")
+	discoverCmd.Flags().BoolVar(&migrateKeys, "migrate-keys", migrateKeys, "Move existing monitors from their version 1 key to the key for the selected key version.")
+	viper.BindPFlag(varKeyVersion, discoverCmd.Flags().Lookup("key-version"))
+	discoverCmd.Flags().BoolVar(&prune, "prune", prune, "Pause or delete monitors for cron jobs that have been removed from the crontabs being read.")
+	discoverCmd.Flags().StringVar(&pruneAction, "prune-action", "pause", "What to do with monitors found by --prune. Accepted values: pause, delete")
 	discoverCmd.Flags().BoolVar(&isAutoDiscover, "auto", isAutoDiscover, "Do not use an interactive shell. Write updated crontab to stdout.")
 
 	discoverCmd.Flags().BoolVar(&isSilent, "silent", isSilent, "")
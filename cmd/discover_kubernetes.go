@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+func processKubernetesManifests(path string) bool {
+	defer printLn()
+	printSuccessText(fmt.Sprintf("Checking Kubernetes manifests in %s", path), false)
+
+	manifests, err := lib.ReadKubernetesManifests(path)
+	if err != nil {
+		printWarningText(err.Error()+". Skipping.", true)
+		return false
+	}
+
+	if len(manifests) == 0 {
+		printWarningText("No CronJobs found. Skipping.", true)
+		return false
+	}
+
+	if !isAutoDiscover {
+		count := 0
+		for _, manifest := range manifests {
+			count += len(manifest.CronJobs)
+		}
+
+		label := "CronJobs"
+		if count == 1 {
+			label = "CronJob"
+		}
+		printSuccessText(fmt.Sprintf("Found %d %s:", count, label), true)
+	}
+
+	monitors := map[string]*lib.Monitor{}
+
+	for _, manifest := range manifests {
+		for _, job := range manifest.CronJobs {
+			if !job.IsMonitorable() {
+				continue
+			}
+
+			// Without spec.timeZone, schedules are interpreted by kube-controller-manager, which almost always runs in UTC
+			timezone := job.TimeZone
+			if timezone == "" {
+				timezone = "UTC"
+			}
+
+			defaultName := truncateString(job.QualifiedName(), maxNameLen)
+			key := job.Key()
+			name := defaultName
+			skip := false
+
+			existingMonitors.CurrentKey = key
+			existingMonitors.CurrentCode = job.Code
+			if existingName, err := existingMonitors.GetNameForCurrent(); err == nil {
+				name = existingName
+			}
+
+			if !isAutoDiscover {
+				fmt.Println(fmt.Sprintf("\n    %s  %s  %s", job.QualifiedName(), job.Schedule, job.CommandToRun()))
+				if !job.CanBeWrapped() && len(job.Code) == 0 {
+					printWarningText("This CronJob uses the image entrypoint, add cronitor exec to it by hand", true)
+				}
+				name, skip = promptForName(name, defaultName)
+			}
+
+			if skip {
+				continue
+			}
+
+			existingMonitors.AddName(name)
+
+			if name == defaultName {
+				name = ""
+			}
+
+			notificationListMap := map[string][]string{}
+			if notificationList != "" {
+				notificationListMap = map[string][]string{"templates": {notificationList}}
+			}
+
+			note := fmt.Sprintf("Discovered in %s", job.Filename)
+			if job.Suspend {
+				note += " (suspended)"
+			}
+
+			job.Mon = lib.Monitor{
+				Name:             name,
+				DefaultName:      defaultName,
+				Key:              key,
+				Rules:            createRules(job.Schedule),
+				Tags:             append(createTags(TagContext{Command: job.CommandToRun()}), "kubernetes"),
+				Type:             "heartbeat",
+				Code:             job.Code,
+				Timezone:         timezone,
+				Note:             note,
+				Notifications:    notificationListMap,
+				NoStdoutPassthru: noStdoutPassthru,
+			}
+
+			monitors[key] = &job.Mon
+		}
+	}
+
+	printLn()
+
+	if len(monitors) > 0 {
+		printDoneText("Sending to Cronitor", true)
+	}
+
+	monitors, err = putMonitors(monitors)
+	if err != nil {
+		fatal(err.Error(), 1)
+	}
+
+	// Manifests live in source control, so the rewritten manifests are written out for review rather than applied
+	var updatedManifests []string
+	for _, manifest := range manifests {
+		updated, err := manifest.Write()
+		if err != nil {
+			printErrorText(fmt.Sprintf("Problem updating %s: %s", manifest.Filename, err.Error()), true)
+			return false
+		}
+		updatedManifests = append(updatedManifests, fmt.Sprintf("# Source: %s\n%s", manifest.Filename, updated))
+	}
+
+	output := strings.Join(updatedManifests, "---\n")
+	if len(kubernetesOutput) > 0 {
+		if !dryRun {
+			if err := ioutil.WriteFile(kubernetesOutput, []byte(output), 0644); err != nil {
+				printErrorText(fmt.Sprintf("Problem writing manifests to %s: %s", kubernetesOutput, err.Error()), true)
+				return false
+			}
+
+			printDoneText(fmt.Sprintf("Updated manifests written to %s", kubernetesOutput), true)
+		}
+	} else if !isSilent {
+		// In --auto mode the manifests are the only output, so they can be redirected to a file
+		if !isAutoDiscover {
+			printDoneText("Updated manifests:", true)
+			printLn()
+		}
+		fmt.Println(strings.TrimSpace(output))
+	}
+
+	return len(monitors) > 0
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// NameTemplateData holds the fields available to --name-template
+type NameTemplateData struct {
+	Hostname   string
+	RunAs      string
+	Command    string
+	Script     string
+	Crontab    string
+	LineNumber int // counted from 1, as in an editor
+}
+
+// NameRewriteRule replaces matches of a regular expression in discovered monitor names
+type NameRewriteRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+var nameTemplate *template.Template
+var nameRewriteRules []NameRewriteRule
+
+// Interpreters whose first argument is the script being run, used to find .Script
+var nameScriptInterpreters = map[string]bool{"bash": true, "sh": true, "zsh": true, "python": true, "python2": true, "python3": true, "php": true, "node": true, "ruby": true, "perl": true, "nohup": true}
+var nameEnvironmentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// Commands that prepare the shell for the job, skipped when looking for .Script in a command list like "cd /x && ./run.sh"
+var nameShellSetupCommands = map[string]bool{"cd": true, "export": true, "source": true, ".": true, "umask": true, "set": true}
+var nameCommandSeparatorRegex = regexp.MustCompile(`&&|\|\||;|\|`)
+
+func parseNameTemplate(text string) (*template.Template, error) {
+	if len(text) == 0 {
+		return nil, nil
+	}
+
+	parsed, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid name template: %s", err.Error()))
+	}
+
+	// Render once with empty data so a misspelled field is reported now instead of on every job
+	if err := parsed.Execute(&bytes.Buffer{}, NameTemplateData{}); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid name template: %s", err.Error()))
+	}
+
+	return parsed, nil
+}
+
+// parseNameRewriteRules reads rules written as "pattern=>replacement". The replacement can use $1 style references.
+func parseNameRewriteRules(rules []string) ([]NameRewriteRule, error) {
+	var parsed []NameRewriteRule
+	for _, rule := range rules {
+		separator := strings.Index(rule, "=>")
+		if separator < 0 {
+			return nil, errors.New(fmt.Sprintf("invalid name rewrite rule \"%s\", expecting pattern=>replacement", rule))
+		}
+
+		pattern, err := regexp.Compile(rule[:separator])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid name rewrite rule \"%s\": %s", rule, err.Error()))
+		}
+
+		parsed = append(parsed, NameRewriteRule{Pattern: pattern, Replacement: rule[separator+2:]})
+	}
+
+	return parsed, nil
+}
+
+func renderNameTemplate(nameTemplate *template.Template, data NameTemplateData) string {
+	var buf bytes.Buffer
+	if err := nameTemplate.Execute(&buf, data); err != nil {
+		log(fmt.Sprintf("Problem rendering name template: %s", err.Error()))
+		return data.Command
+	}
+
+	return buf.String()
+}
+
+func applyNameRewriteRules(name string, rules []NameRewriteRule) string {
+	for _, rule := range rules {
+		name = rule.Pattern.ReplaceAllString(name, rule.Replacement)
+	}
+
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// scriptName returns the basename of the program a command runs, looking past environment assignments, interpreters
+// and commands such as cd that only prepare the shell
+func scriptName(command string) string {
+	for _, segment := range nameCommandSeparatorRegex.Split(command, -1) {
+		for _, field := range strings.Fields(segment) {
+			field = strings.Trim(field, "\"'")
+			if len(field) == 0 || nameEnvironmentRegex.MatchString(field) || strings.HasPrefix(field, "-") {
+				continue
+			}
+
+			base := filepath.Base(field)
+			if nameScriptInterpreters[base] {
+				continue
+			}
+
+			if nameShellSetupCommands[base] {
+				break
+			}
+
+			return base
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// The keys of every job found in each crontab read by discover, by crontab display name
+var discoveredCrontabKeys = map[string]map[string]bool{}
+
+// recordDiscoveredKeys remembers the jobs currently in a crontab so monitors for removed jobs can be pruned
+func recordDiscoveredKeys(crontab *lib.Crontab) {
+	keys := map[string]bool{}
+	for _, line := range crontab.Lines {
+		if line.IsMonitorable() {
+			keys[crontab.Key(line, effectiveKeyVersion())] = true
+
+			// Monitors are moved to their new key during this run, so they are not removed from the crontab
+			if migrateKeys {
+				keys[line.Key(crontab.CanonicalName())] = true
+			}
+		}
+	}
+
+	discoveredCrontabKeys[crontab.DisplayName()] = keys
+}
+
+// discoveryNote identifies the crontab, line and host a job was discovered on. Prune finds the monitors of removed jobs
+// by this note and their key, because a name template or rewrite can change the name.
+func discoveryNote(source string, lineNumber int, hostname string) string {
+	note := fmt.Sprintf("Discovered in %s L%d", source, lineNumber)
+	if len(hostname) > 0 {
+		note = fmt.Sprintf("%s on %s", note, hostname)
+	}
+
+	return note
+}
+
+var discoveryNoteRegex = regexp.MustCompile(`^\d+(?: on (.+))?$`)
+
+// isDiscoveredIn reports whether a monitor was discovered in the crontab on this host. Notes written before the host
+// was recorded are matched by the hostname prefix of the default name.
+func isDiscoveredIn(monitor lib.MonitorSummary, crontabName, hostname string) bool {
+	prefix := fmt.Sprintf("Discovered in %s L", crontabName)
+	if !strings.HasPrefix(monitor.Note, prefix) {
+		return false
+	}
+
+	firstLine := strings.SplitN(strings.TrimPrefix(monitor.Note, prefix), "\n", 2)[0]
+	matches := discoveryNoteRegex.FindStringSubmatch(firstLine)
+	if matches == nil {
+		return false
+	}
+
+	if len(matches[1]) > 0 {
+		return matches[1] == hostname
+	}
+
+	return strings.HasPrefix(monitor.DefaultName, formatHostnameForName(hostname))
+}
+
+// findPrunableMonitors returns cron job monitors that were discovered on this host in one of the crontabs that was
+// just read, but whose key is no longer in that crontab.
+func findPrunableMonitors(monitors []lib.MonitorSummary, crontabKeys map[string]map[string]bool, hostname string) []lib.MonitorSummary {
+	var prunable []lib.MonitorSummary
+
+	for _, monitor := range monitors {
+		if !hasTag(monitor.Tags, "cron-job") {
+			continue
+		}
+
+		for crontabName, keys := range crontabKeys {
+			if isDiscoveredIn(monitor, crontabName, hostname) && !keys[monitor.Key] {
+				prunable = append(prunable, monitor)
+				break
+			}
+		}
+	}
+
+	return prunable
+}
+
+func pruneMonitors() {
+	defer printLn()
+	printSuccessText("Checking for monitors whose cron job was removed", false)
+
+	prunable := findPrunableMonitors(existingMonitors.Monitors, discoveredCrontabKeys, effectiveHostname())
+	if len(prunable) == 0 {
+		printDoneText("Nothing to prune", true)
+		return
+	}
+
+	verb := "Pause"
+	if pruneAction == "delete" {
+		verb = "Delete"
+	}
+
+	printWarningText(fmt.Sprintf("Found %d monitors for jobs that are no longer in their crontab:", len(prunable)), true)
+	for _, monitor := range prunable {
+		name := monitor.Name
+		if name == "" {
+			name = monitor.DefaultName
+		}
+		if !isAutoDiscover && !isSilent {
+			fmt.Println(fmt.Sprintf("      %s  %s  (%s)", monitor.Key, name, monitor.Note))
+		}
+		log(fmt.Sprintf("Prunable monitor %s: %s", monitor.Key, name))
+	}
+
+	if dryRun {
+		printWarningText(fmt.Sprintf("This is a DRY-RUN. Re-run without --dry-run to %s these monitors.", strings.ToLower(verb)), true)
+		return
+	}
+
+	if !isAutoDiscover {
+		prompt := promptui.Prompt{
+			Label:     fmt.Sprintf("%s %d monitors", verb, len(prunable)),
+			IsConfirm: true,
+		}
+
+		if _, err := prompt.Run(); err != nil {
+			printWarningText("Skipped", true)
+			return
+		}
+	}
+
+	client := getCronitorApi()
+	for _, monitor := range prunable {
+		var err error
+		if pruneAction == "delete" {
+			err = client.DeleteMonitor(apiContext(), monitor.Key)
+		} else {
+			err = client.PauseMonitor(apiContext(), monitor.Key, 0)
+		}
+
+		if err != nil {
+			printErrorText(err.Error(), true)
+		}
+	}
+
+	printDoneText(fmt.Sprintf("Pruned %d monitors", len(prunable)), true)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, value := range tags {
+		if value == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+func processSystemdTimers(directories []string, overrideDirectory string) bool {
+	defer printLn()
+	printSuccessText(fmt.Sprintf("Checking systemd timers in %s", strings.Join(directories, ", ")), false)
+
+	timers := lib.ReadSystemdTimers(directories, overrideDirectory)
+	if len(timers) == 0 {
+		printWarningText("No timers found. Skipping.", true)
+		return false
+	}
+
+	// Before going further, ensure we aren't going to run into permissions problems writing drop-ins later
+	os.MkdirAll(overrideDirectory, 0755)
+	if testFile, err := ioutil.TempFile(overrideDirectory, ".cronitor-"); err == nil {
+		testFile.Close()
+		os.Remove(testFile.Name())
+	} else {
+		printWarningText(fmt.Sprintf("Directory %s is not writeable. Re-run command with sudo. Skipping", overrideDirectory), true)
+		return false
+	}
+
+	if !isAutoDiscover {
+		count := 0
+		for _, timer := range timers {
+			if timer.IsMonitorable() {
+				count++
+			}
+		}
+
+		label := "timers"
+		if count == 1 {
+			label = "timer"
+		}
+		printSuccessText(fmt.Sprintf("Found %d systemd %s:", count, label), true)
+	}
+
+	monitors := map[string]*lib.Monitor{}
+	allNameCandidates := map[string]bool{}
+	systemTimezone := effectiveTimezoneLocationName()
+
+	for _, timer := range timers {
+		if !timer.IsMonitorable() {
+			log(fmt.Sprintf("Skipping %s: no service with a single ExecStart or no supported schedule", timer.DisplayName()))
+			continue
+		}
+
+		grace := uint(timer.RandomizedDelay()/time.Second) + graceSeconds
+		timezone := systemTimezone
+		schedule := ""
+
+		var rules []lib.Rule
+		if expression, calendarTimezone, ok := timer.CronExpression(); ok {
+			rule := createRule(expression)
+			rule.GraceSeconds = grace
+			rules = append(rules, rule)
+			schedule = expression
+			if calendarTimezone != "" {
+				timezone = lib.TimezoneLocationName{Name: calendarTimezone}
+			}
+		} else {
+			interval, _ := timer.Interval()
+			rules = append(rules, createIntervalRule(interval, grace))
+			schedule = fmt.Sprintf("every %s", interval)
+		}
+
+		defaultName := createSystemdDefaultName(timer, effectiveHostname(), allNameCandidates)
+		key := timer.Key(effectiveKeyVersion())
+		code := timer.Code
+		name := defaultName
+		skip := false
+
+		if migrateKeys && code == "" {
+			code = migrateKey(timer.Key(lib.KEY_VERSION_1), key)
+		}
+
+		existingMonitors.CurrentKey = key
+		existingMonitors.CurrentCode = code
+		if existingName, err := existingMonitors.GetNameForCurrent(); err == nil {
+			name = existingName
+		}
+
+		if !isAutoDiscover {
+			fmt.Println(fmt.Sprintf("\n    %s  %s  %s", timer.Timer.Name, schedule, timer.ExecStart()))
+			name, skip = promptForName(name, defaultName)
+		}
+
+		if skip {
+			continue
+		}
+
+		existingMonitors.AddName(name)
+
+		if name == defaultName {
+			name = ""
+		}
+
+		notificationListMap := map[string][]string{}
+		if notificationList != "" {
+			notificationListMap = map[string][]string{"templates": {notificationList}}
+		}
+
+		note := fmt.Sprintf("Discovered in %s", timer.DisplayName())
+		if timer.IsPersistent() {
+			note += " (Persistent=true, missed runs are started at boot)"
+		}
+
+		timer.Mon = lib.Monitor{
+			Name:             name,
+			DefaultName:      defaultName,
+			Key:              key,
+			Rules:            rules,
+			Tags:             append(createTags(TagContext{Hostname: effectiveHostname(), RunAs: timer.RunAs(), Command: timer.ExecStart()}), "systemd-timer"),
+			Type:             "heartbeat",
+			Code:             code,
+			Timezone:         timezone.Name,
+			Note:             note,
+			Notifications:    notificationListMap,
+			NoStdoutPassthru: noStdoutPassthru,
+		}
+
+		monitors[key] = &timer.Mon
+	}
+
+	printLn()
+
+	if len(monitors) > 0 {
+		printDoneText("Sending to Cronitor", true)
+	}
+
+	var err error
+	monitors, err = putMonitors(monitors)
+	if err != nil {
+		fatal(err.Error(), 1)
+	}
+
+	executable := "cronitor"
+	if path, err := os.Executable(); err == nil {
+		executable = path
+	}
+
+	overridesWritten := 0
+	for _, timer := range timers {
+		// Timers that were already integrated keep their existing drop-in
+		if len(timer.Mon.Code) == 0 || len(timer.Code) > 0 {
+			continue
+		}
+
+		override := timer.WriteOverride(executable)
+		if !isSilent && isAutoDiscover {
+			fmt.Println(fmt.Sprintf("# %s\n%s", timer.OverrideFilename(), override))
+		}
+
+		if dryRun {
+			continue
+		}
+
+		if err := timer.SaveOverride(override); err != nil {
+			if !isSilent {
+				printErrorText("Problem saving drop-in: "+err.Error(), true)
+			}
+			continue
+		}
+
+		overridesWritten++
+	}
+
+	if overridesWritten > 0 {
+		// systemd only reads drop-ins from the override directory after a reload
+		if overrideDirectory == lib.SYSTEMD_OVERRIDE_DIRECTORY {
+			if output, err := exec.Command("systemctl", "daemon-reload").CombinedOutput(); err != nil {
+				printWarningText(fmt.Sprintf("Could not reload systemd, run 'systemctl daemon-reload' to apply: %s", strings.TrimSpace(string(output))), true)
+			}
+		}
+
+		if !isSilent {
+			printDoneText("Integration complete", true)
+		}
+	}
+
+	return len(monitors) > 0
+}
+
+func createSystemdDefaultName(timer *lib.SystemdTimer, effectiveHostname string, allNameCandidates map[string]bool) string {
+	candidate := fmt.Sprintf("%ssystemd %s", formatHostnameForName(effectiveHostname), timer.Timer.Name)
+	if _, exists := allNameCandidates[candidate]; exists {
+		candidate = fmt.Sprintf("%s %s", candidate, timer.Timer.Filename)
+	}
+	allNameCandidates[candidate] = true
+
+	return truncateString(candidate, maxNameLen)
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TagContext describes a discovered job for automatic tags and tagging rules
+type TagContext struct {
+	Hostname string
+	RunAs    string
+	Command  string
+	Source   string
+}
+
+// TagRule adds a tag to jobs whose command matches a regular expression
+type TagRule struct {
+	Pattern *regexp.Regexp
+	Tag     string
+}
+
+var discoverTags []string
+var tagRules []TagRule
+var tagRuleFlags []string
+var autoTags bool
+var discoverEnvironment string
+
+// parseTagRules reads rules written as "pattern=>tag"
+func parseTagRules(rules []string) ([]TagRule, error) {
+	var parsed []TagRule
+	for _, rule := range rules {
+		separator := strings.Index(rule, "=>")
+		if separator < 0 || len(strings.TrimSpace(rule[separator+2:])) == 0 {
+			return nil, errors.New(fmt.Sprintf("invalid tag rule \"%s\", expecting pattern=>tag", rule))
+		}
+
+		pattern, err := regexp.Compile(strings.TrimSpace(rule[:separator]))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid tag rule \"%s\": %s", rule, err.Error()))
+		}
+
+		parsed = append(parsed, TagRule{Pattern: pattern, Tag: strings.TrimSpace(rule[separator+2:])})
+	}
+
+	return parsed, nil
+}
+
+// createTags returns the cron-job tag, with --auto-tags automatic tags for the host, source and user, the environment,
+// tags from --tag and the config file, and tags from any matching tagging rules
+func createTags(job TagContext) []string {
+	tagList := []string{"cron-job"}
+
+	if autoTags {
+		if len(job.Hostname) > 0 {
+			tagList = append(tagList, "host:"+job.Hostname)
+		}
+		if len(job.Source) > 0 {
+			tagList = append(tagList, "crontab:"+job.Source)
+		}
+		if len(job.RunAs) > 0 {
+			tagList = append(tagList, "user:"+job.RunAs)
+		}
+	}
+
+	if len(discoverEnvironment) > 0 {
+		tagList = append(tagList, "env:"+discoverEnvironment)
+	}
+
+	tagList = append(tagList, discoverTags...)
+
+	for _, rule := range tagRules {
+		if rule.Pattern.MatchString(job.Command) {
+			tagList = append(tagList, rule.Tag)
+		}
+	}
+
+	return uniqueTags(tagList)
+}
+
+// uniqueTags removes empty and repeated tags, keeping the first occurrence
+func uniqueTags(tagList []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, tag := range tagList {
+		if tag = strings.TrimSpace(tag); len(tag) > 0 && !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+
+	return unique
+}
@@ -1,10 +1,17 @@
 package cmd
 
-import "testing"
+import (
+	"cronitor/lib"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestCreateDefaultNameHasAddCandidateSideEffect(t *testing.T) {
 	allNameCandidates := map[string]bool{"something": true}
-	createDefaultName("/var/some/command arg1 arg2", "", 11, false, "localhost", nil, allNameCandidates)
+	line := &lib.Line{CommandToRun: "/var/some/command arg1 arg2", LineNumber: 11}
+	createDefaultName(line, &lib.Crontab{}, "localhost", nil, allNameCandidates)
 
 	if len(allNameCandidates) == 0 || allNameCandidates["[localhost] /var/some/command arg1 arg2"] != true {
 		t.Error("Name candidate not added to allNameCandidates")
@@ -13,7 +20,11 @@ func TestCreateDefaultNameHasAddCandidateSideEffect(t *testing.T) {
 
 func TestCreateDefaultName(t *testing.T) {
 
-	crontabPath = "/discover/test"
+	crontab := &lib.Crontab{Filename: "/discover/test"}
+
+	// Expected names below were written against a 100 char limit
+	defer func(previous int) { maxNameLen = previous }(maxNameLen)
+	maxNameLen = 100
 
 	allNameCandidates := map[string]bool{
 		"[localhost] /var/some/command arg1 arg2": true,
@@ -132,7 +143,7 @@ func TestCreateDefaultName(t *testing.T) {
 			"/var/some/command arg1 arg2"},
 
 		{"auto discover name is created",
-			"cronitor d3x0c1 cronitor discover /discover/test",
+			"cronitor discover --auto /discover/test",
 			"",
 			11,
 			true,
@@ -183,9 +194,206 @@ func TestCreateDefaultName(t *testing.T) {
 	}
 
 	for _, table := range tables {
-		defaultName := createDefaultName(table.command, table.runAs, table.lineNumber, table.isAutoDiscoverCommand, table.hostname, table.excludeFromName, table.allNameCandidates)
+		line := &lib.Line{CommandToRun: table.command, RunAs: table.runAs, LineNumber: table.lineNumber}
+		if line.IsAutoDiscoverCommand() != table.isAutoDiscoverCommand {
+			t.Errorf("Test case '%s' failed, auto discover command not detected as expected", table.caseName)
+		}
+
+		defaultName := createDefaultName(line, crontab, table.hostname, table.excludeFromName, table.allNameCandidates)
 		if defaultName != table.expected {
 			t.Errorf("Test case '%s' failed, got: %s, expected: %s.", table.caseName, defaultName, table.expected)
 		}
 	}
 }
+
+func TestCreateIntervalRule(t *testing.T) {
+	tables := []struct {
+		interval time.Duration
+		value    string
+		timeUnit string
+	}{
+		{7 * 24 * time.Hour, "7", "days"},
+		{90 * time.Minute, "90", "minutes"},
+		{2 * time.Hour, "2", "hours"},
+		{45 * time.Second, "45", "seconds"},
+	}
+
+	for _, table := range tables {
+		rule := createIntervalRule(table.interval, 60)
+		if rule.RuleType != "complete_ping_not_received" || rule.Value != table.value || rule.TimeUnit != table.timeUnit || rule.GraceSeconds != 60 {
+			t.Errorf("Interval rule for %s is incorrect, got: %+v", table.interval, rule)
+		}
+	}
+}
+
+func TestFindPrunableMonitors(t *testing.T) {
+	monitors := []lib.MonitorSummary{
+		{Key: "current", DefaultName: "[web1] /bin/current", Tags: []string{"cron-job"}, Note: "Discovered in /etc/crontab L3"},
+		{Key: "removed", DefaultName: "[web1] /bin/removed", Tags: []string{"cron-job"}, Note: "Discovered in /etc/crontab L4"},
+		{Key: "other-host", DefaultName: "[web2] /bin/removed", Tags: []string{"cron-job"}, Note: "Discovered in /etc/crontab L4"},
+		{Key: "other-crontab", DefaultName: "[web1] /bin/other", Tags: []string{"cron-job"}, Note: "Discovered in /etc/crontab2 L1"},
+		{Key: "not-discovered", DefaultName: "[web1] /bin/manual", Note: "Discovered in /etc/crontab L5"},
+		{Key: "templated", DefaultName: "nightly backup", Tags: []string{"cron-job"}, Note: "Discovered in /etc/crontab L6 on web1\nOwned by the data team"},
+		{Key: "templated-other-host", DefaultName: "nightly backup", Tags: []string{"cron-job"}, Note: "Discovered in /etc/crontab L6 on web2"},
+		{Key: "other-line-format", DefaultName: "[web1] /bin/other", Tags: []string{"cron-job"}, Note: "Discovered in /etc/crontab Lx"},
+	}
+	crontabKeys := map[string]map[string]bool{"/etc/crontab": {"current": true}}
+
+	prunable := findPrunableMonitors(monitors, crontabKeys, "web1")
+	if len(prunable) != 2 || prunable[0].Key != "removed" || prunable[1].Key != "templated" {
+		t.Errorf("Expected only the removed jobs on this host to be prunable, got %+v", prunable)
+	}
+}
+
+func TestPruneCannotBeUsedWithAuto(t *testing.T) {
+	prune, isAutoDiscover = true, true
+	defer func() { prune, isAutoDiscover = false, false }()
+
+	if err := discoverCmd.Args(discoverCmd, []string{}); err == nil || !strings.Contains(err.Error(), "--prune") {
+		t.Errorf("Expected --prune with --auto to be refused, got %v", err)
+	}
+}
+
+func TestCreateDefaultNameWithTemplate(t *testing.T) {
+	var err error
+	if nameTemplate, err = parseNameTemplate("{{.Hostname}} {{.Script}} ({{.RunAs}})"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if nameRewriteRules, err = parseNameRewriteRules([]string{`^web-\d+=>web`}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer func() {
+		nameTemplate = nil
+		nameRewriteRules = nil
+	}()
+
+	line := &lib.Line{CommandToRun: "MAILTO=ops /usr/bin/php /var/app/bin/cleanup.php --all > /dev/null", RunAs: "www-data"}
+	name := createDefaultName(line, &lib.Crontab{Filename: "/etc/cron.d/app"}, "web-12", []string{}, map[string]bool{})
+	if name != "web cleanup.php (www-data)" {
+		t.Errorf("Unexpected name from template: %s", name)
+	}
+
+	if nameTemplate, err = parseNameTemplate("{{.RunAs}}"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	line = &lib.Line{CommandToRun: "/var/app/bin/cleanup.php"}
+	if name := createDefaultName(line, &lib.Crontab{Filename: "/etc/cron.d/app"}, "db1", []string{}, map[string]bool{}); name != "[db1] /var/app/bin/cleanup.php" {
+		t.Errorf("Expected the default name when the template renders blank, got %q", name)
+	}
+
+	if nameTemplate, err = parseNameTemplate("{{.Script}} L{{.LineNumber}}"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	line = &lib.Line{CommandToRun: "/var/app/bin/cleanup.php", LineNumber: 4}
+	if name := createDefaultName(line, &lib.Crontab{Filename: "/etc/cron.d/app"}, "db1", []string{}, map[string]bool{}); name != "cleanup.php L5" {
+		t.Errorf("Expected the template line number to count from 1, got %q", name)
+	}
+
+	if _, err := parseNameTemplate("{{.Hostnme}}"); err == nil {
+		t.Error("Expected error for unknown template field")
+	}
+
+	if _, err := parseNameRewriteRules([]string{"no separator"}); err == nil {
+		t.Error("Expected error for rewrite rule without a separator")
+	}
+}
+
+func TestScriptName(t *testing.T) {
+	tests := map[string]string{
+		"/var/app/bin/backup.sh --full":        "backup.sh",
+		"FOO=bar bash -c /opt/run.sh":          "run.sh",
+		"python3 /srv/jobs/report.py > /tmp/x": "report.py",
+		"cd /x && ./run.sh":                    "run.sh",
+		"source /etc/profile; php artisan run": "artisan",
+		"":                                     "",
+	}
+
+	for command, expected := range tests {
+		if actual := scriptName(command); actual != expected {
+			t.Errorf("scriptName(%q) = %q, expected %q", command, actual, expected)
+		}
+	}
+}
+
+func TestCreateTags(t *testing.T) {
+	var err error
+	if tagRules, err = parseTagRules([]string{"backup=>backup", `\.php\b=>php`}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	discoverTags = []string{"team:ops", "backup"}
+	discoverEnvironment = "production"
+	autoTags = true
+	defer func() {
+		tagRules = nil
+		discoverTags = nil
+		discoverEnvironment = ""
+		autoTags = false
+	}()
+
+	job := TagContext{Hostname: "web1", RunAs: "root", Command: "/usr/bin/backup.sh", Source: "app"}
+	expected := []string{"cron-job", "host:web1", "crontab:app", "user:root", "env:production", "team:ops", "backup"}
+	if actual := createTags(job); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected tags: %v", actual)
+	}
+
+	autoTags = false
+	expected = []string{"cron-job", "env:production", "team:ops", "backup"}
+	if actual := createTags(job); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected tags without auto tags: %v", actual)
+	}
+
+	if _, err := parseTagRules([]string{"backup=>"}); err == nil {
+		t.Error("Expected error for tag rule without a tag")
+	}
+}
+
+func TestCreateRules(t *testing.T) {
+	graceSeconds = 60
+	extraRules = []lib.Rule{{RuleType: "run_time_exceeds", Value: "30", TimeUnit: "minutes"}}
+	defer func() {
+		graceSeconds = 0
+		extraRules = nil
+	}()
+
+	defaults := &lib.Annotation{GraceSeconds: 300, HasGrace: true, Rules: []lib.Rule{{RuleType: "ran_less_than", Value: "5", TimeUnit: "seconds"}}}
+	annotation := &lib.Annotation{GraceSeconds: 0, HasGrace: true, Rules: []lib.Rule{{RuleType: "run_time_exceeds", Value: "2", TimeUnit: "hours"}}}
+
+	rules := createRules("0 2 * * *", nil)
+	if len(rules) != 2 || rules[0].GraceSeconds != 60 {
+		t.Errorf("Expected global grace and rules, got %+v", rules)
+	}
+
+	rules = createRules("0 2 * * *", defaults, annotation)
+	expected := []lib.Rule{
+		{RuleType: "not_on_schedule", Value: "0 2 * * *"},
+		{RuleType: "run_time_exceeds", Value: "2", TimeUnit: "hours"},
+		{RuleType: "ran_less_than", Value: "5", TimeUnit: "seconds"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected annotation settings to take precedence, got %+v", rules)
+	}
+}
+
+func TestCreateMappedName(t *testing.T) {
+	existingMonitors = ExistingMonitors{Monitors: []lib.MonitorSummary{{Key: "abc", Name: "Taken"}}}
+	defer func() { existingMonitors = ExistingMonitors{} }()
+
+	pattern := &lib.NameMapping{Command: "backup", Name: "Backup"}
+	first, err := createMappedName(pattern, &lib.Line{LineNumber: 3})
+	if err != nil || first != "Backup" {
+		t.Fatalf("Expected the mapped name, got %q %v", first, err)
+	}
+	existingMonitors.AddName(first)
+
+	if second, err := createMappedName(pattern, &lib.Line{LineNumber: 7}); err != nil || second != "Backup L7" {
+		t.Errorf("Expected a second job matched by the pattern to get a line suffix, got %q %v", second, err)
+	}
+
+	if _, err := createMappedName(&lib.NameMapping{Key: "def", Name: "Taken"}, &lib.Line{}); err == nil {
+		t.Error("Expected a name used by another monitor to be refused")
+	}
+
+	if _, err := createMappedName(&lib.NameMapping{Key: "def", Name: strings.Repeat("x", maxNameLen+1)}, &lib.Line{}); err == nil {
+		t.Error("Expected a name that is too long to be refused")
+	}
+}
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"cronitor/lib"
 	"errors"
 	"fmt"
 	"github.com/kballard/go-shellquote"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 	"io"
 	"io/ioutil"
 	"os"
@@ -20,6 +23,11 @@ import (
 
 var monitorCode string
 var commandParts []string
+var pauseDuring []string
+
+// Monitors paused with --pause-during resume on their own after this long if exec is killed before it resumes them
+const pauseDuringMaxHours = 24
+
 var execCmd = &cobra.Command{
 	Use:   "exec",
 	Short: "Execute a command with monitoring",
@@ -34,10 +42,27 @@ Example:
 
 Example with no command output send to Cronitor:
   By default, stdout and stderr messages are sent to Cronitor when your job completes. To prevent any output from being sent to cronitor, use the --no-stdout flag:
-  $ cronitor exec --no-stdout d3x0c1 /path/to/command.sh --command-param argument1 argument2`,
+  $ cronitor exec --no-stdout d3x0c1 /path/to/command.sh --command-param argument1 argument2
+
+Example pausing other monitors during a maintenance script:
+  $ cronitor exec --pause-during tag:database --pause-during x7y8z9 d3x0c1 /path/to/migrate.sh
+      > Monitors tagged "database" and the monitor x7y8z9 are paused while the script runs and resumed when it exits
+      > If exec is killed before it can resume them, the monitors resume on their own after 24 hours
+
+Example writing metrics for node_exporter:
+  $ cronitor exec --textfile /var/lib/node_exporter/cronitor.prom d3x0c1 /path/to/command.sh
+      > Duration, exit code, start and end times, CPU time and peak memory are written for each run, labeled monitor="d3x0c1"
+      > Metrics are written locally, so they are recorded even when Cronitor cannot be reached
+      > Set CRONITOR_TEXTFILE or 'cronitor config set textfile <path>' to write metrics for every job
+
+Example keeping the output of every run:
+  $ cronitor exec --log-dir /var/log/cronitor d3x0c1 /path/to/command.sh
+      > The output of each run is saved to /var/log/cronitor/d3x0c1/, replacing ">> /var/log/job.log 2>&1"
+      > View it with 'cronitor logs d3x0c1', or 'cronitor logs d3x0c1 --follow' while the job is running
+      > Set CRONITOR_LOG_DIR or 'cronitor config set log_dir <path>' to keep the output of every job`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		// We need to use raw os.Args so we can pass the wrapped command through unparsed
-		var foundExec, foundCode bool
+		var foundExec, foundCode, skipValue bool
 		monitorCodeRegex := regexp.MustCompile(`^[A-Za-z0-9]{3,12}$`)
 
 		for _, arg := range os.Args {
@@ -47,6 +72,17 @@ Example with no command output send to Cronitor:
 				continue
 			}
 
+			// The value of a flag like --pause-during is not the monitor code
+			if skipValue {
+				skipValue = false
+				continue
+			}
+
+			if foundExec && flagTakesValue(cmd, arg) {
+				skipValue = true
+				continue
+			}
+
 			// After finding "exec" we are looking for a monitor code
 			if foundExec && !foundCode {
 				if ret := monitorCodeRegex.FindStringSubmatch(strings.TrimSpace(arg)); ret != nil {
@@ -83,7 +119,11 @@ Example with no command output send to Cronitor:
 		} else {
 			subcommand = shellquote.Join(commandParts...)
 		}
-		os.Exit(RunCommand(subcommand, true, true))
+
+		resume := pauseMonitorsDuring(pauseDuring, pauseDuringMaxHours)
+		exitCode := RunCommand(subcommand, true, true)
+		resume()
+		os.Exit(exitCode)
 	},
 }
 
@@ -93,9 +133,22 @@ func RunCommand(subcommand string, withEnvironment bool, withMonitoring bool) in
 	startTime := makeStamp()
 	formattedStartTime := formatStamp(startTime)
 
-	if withMonitoring {
+	// Keep what happened to each ping for the run history
+	pingDeliveries := map[string]string{}
+	var pingDeliveriesLock sync.Mutex
+	ping := func(endpoint string, message string, timestamp float64, duration *float64, exitCode *int) {
 		monitoringWaitGroup.Add(1)
-		go sendPing("run", monitorCode, subcommand, formattedStartTime, startTime, nil, nil, &monitoringWaitGroup)
+		go func() {
+			defer monitoringWaitGroup.Done()
+			delivery := deliverPing(endpoint, monitorCode, message, formattedStartTime, timestamp, duration, exitCode)
+			pingDeliveriesLock.Lock()
+			pingDeliveries[endpoint] = delivery
+			pingDeliveriesLock.Unlock()
+		}()
+	}
+
+	if withMonitoring {
+		ping("run", subcommand, startTime, nil, nil)
 	}
 
 	log(fmt.Sprintf("Running subcommand: %s", subcommand))
@@ -117,15 +170,24 @@ func RunCommand(subcommand string, withEnvironment bool, withMonitoring bool) in
 	}
 
 	// Proxy and copy the command's stdout if the filesystem is available
+	outputWriters := []io.Writer{os.Stdout}
 	tempFile, err := getTempFile()
 	if err == nil {
 		defer tempFile.Close()
-		execCmd.Stdout = io.MultiWriter(os.Stdout, tempFile)
+		outputWriters = append(outputWriters, tempFile)
 	} else {
 		log(err.Error())
-		execCmd.Stdout = os.Stdout
 	}
 
+	// Keep the full output of the run when a log directory is set
+	var runLog *os.File
+	if withMonitoring {
+		if runLog = createRunLog(startTime); runLog != nil {
+			outputWriters = append(outputWriters, runLog)
+		}
+	}
+	execCmd.Stdout = io.MultiWriter(outputWriters...)
+
 	// Combine stdout and stderr from the command into a single buffer which we'll stream as stdout
 	// Alternatively we could pass stderr from the subcommand but I've chosen to only use it for CronitorCLI errors at the moment
 	execCmd.Stderr = execCmd.Stdout
@@ -171,39 +233,140 @@ func RunCommand(subcommand string, withEnvironment bool, withMonitoring bool) in
 			exitCode := 0
 			if err == nil {
 				if withMonitoring {
-					monitoringWaitGroup.Add(1)
-					go sendPing("complete", monitorCode, string(outputForPing), formattedStartTime, endTime, &duration, &exitCode, &monitoringWaitGroup)
+					ping("complete", string(outputForPing), endTime, &duration, &exitCode)
 				}
 			} else {
 				message := strings.TrimSpace(fmt.Sprintf("[%s] %s", err.Error(), outputForPing))
 
-				// This works on both Posix and Windows (syscall.WaitStatus is cross platform).
-				// Cribbed from aws-vault.
-				if exiterr, ok := err.(*exec.ExitError); ok {
-
-					if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
-						exitCode = status.ExitStatus()
-					} else {
-						exitCode = 1
-					}
-				}
+				exitCode = exitCodeFromError(err)
 
 				if withMonitoring {
-					monitoringWaitGroup.Add(1)
-					go sendPing("fail", monitorCode, message, formattedStartTime, endTime, &duration, &exitCode, &monitoringWaitGroup)
+					ping("fail", message, endTime, &duration, &exitCode)
 				}
 			}
 
+			if withMonitoring {
+				writeExecMetrics(monitorCode, startTime, endTime, exitCode, execCmd.ProcessState)
+			}
+
+			runLogFilename := finishRunLog(runLog)
 			monitoringWaitGroup.Wait()
+
+			if withMonitoring {
+				recordRun(lib.RunRecord{
+					Series:   formattedStartTime,
+					Monitor:  monitorCode,
+					Command:  subcommand,
+					Host:     effectiveHostname(),
+					Start:    startTime,
+					End:      endTime,
+					Duration: duration,
+					ExitCode: exitCode,
+					Output:   string(readOutputTail(tempFile, outputForHistoryMaxLen)),
+					Log:      runLogFilename,
+					Pings:    pingDeliveries,
+				})
+			}
+
 			return exitCode
 		}
 	}
 
 }
 
+// exitCodeFromError returns the exit code of a command that failed. A command that could not be started has no exit
+// code of its own, so it gets 127 when the program was not found, as a shell would report, and 1 otherwise.
+func exitCodeFromError(err error) int {
+	// This works on both Posix and Windows (syscall.WaitStatus is cross platform).
+	// Cribbed from aws-vault.
+	if exiterr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exiterr.Sys().(syscall.WaitStatus); ok && status.ExitStatus() != 0 {
+			return status.ExitStatus()
+		}
+		return 1
+	}
+
+	if errors.Is(err, exec.ErrNotFound) || os.IsNotExist(err) {
+		return 127
+	}
+
+	return 1
+}
+
 func init() {
 	RootCmd.AddCommand(execCmd)
 	execCmd.Flags().BoolVar(&noStdoutPassthru, "no-stdout", noStdoutPassthru, "Do not send cron job output to Cronitor when your job completes")
+	execCmd.Flags().String("textfile", "", "Write metrics for this run to a node_exporter textfile collector file, e.g. /var/lib/node_exporter/cronitor.prom")
+	viper.BindPFlag(varTextfile, execCmd.Flags().Lookup("textfile"))
+	execCmd.Flags().String("log-dir", "", "Keep the output of each run in this directory, e.g. /var/log/cronitor")
+	viper.BindPFlag(varLogDir, execCmd.Flags().Lookup("log-dir"))
+	execCmd.Flags().StringSliceVar(&pauseDuring, "pause-during", pauseDuring, "Pause this monitor, or every monitor with tag:NAME, while the command runs. Can be used more than once.")
+}
+
+// flagTakesValue reports whether arg is a flag, like --hostname or -n, whose value is the next argument
+func flagTakesValue(cmd *cobra.Command, arg string) bool {
+	if !strings.HasPrefix(arg, "-") || arg == "--" || strings.Contains(arg, "=") {
+		return false
+	}
+
+	var flag *pflag.Flag
+	for _, flags := range []*pflag.FlagSet{cmd.Flags(), cmd.InheritedFlags()} {
+		if flag == nil && strings.HasPrefix(arg, "--") {
+			flag = flags.Lookup(strings.TrimPrefix(arg, "--"))
+		} else if flag == nil && len(arg) == 2 {
+			flag = flags.ShorthandLookup(arg[1:])
+		}
+	}
+
+	return flag != nil && flag.Value.Type() != "bool"
+}
+
+// EscapeExecArgs inserts a "--" after the monitor code of an exec command so that Cobra does not parse the flags of the
+// command being run. Flags given to exec before the monitor code, and their values, are left for Cobra.
+func EscapeExecArgs(args []string) []string {
+	execIndex := -1
+	for i, arg := range args {
+		if arg == "help" {
+			return args
+		}
+
+		if arg == "exec" {
+			execIndex = i
+			break
+		}
+	}
+
+	if execIndex < 0 {
+		return args
+	}
+
+	for _, arg := range args[execIndex+1:] {
+		if arg == "--" {
+			return args
+		}
+	}
+
+	for i := execIndex + 1; i < len(args); i++ {
+		if flagTakesValue(execCmd, args[i]) {
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(args[i], "-") {
+			continue
+		}
+
+		// Only escape when there is a command after the monitor code
+		if i+1 >= len(args) {
+			return args
+		}
+
+		escaped := append([]string{}, args[:i+1]...)
+		escaped = append(escaped, "--")
+		return append(escaped, args[i+1:]...)
+	}
+
+	return args
 }
 
 func makeCronLikeEnv() []string {
@@ -255,27 +418,39 @@ func getTempFile() (*os.File, error) {
 }
 
 func gatherOutput(tempFile *os.File) []byte {
-	var outputForPing []byte
 	var outputForPingMaxLen int64 = 2000
-	if noStdoutPassthru || tempFile == nil {
-		outputForPing = []byte{}
-	} else {
-		// Known reasons stat could fail here:
-		// 1. temp file was removed by an external process
-		// 2. filesystem is no longer available
-		if stat, err := os.Stat(tempFile.Name()); err == nil {
-			if size := stat.Size(); size < outputForPingMaxLen {
-				outputForPing = make([]byte, size)
-				tempFile.Seek(0, 0)
-			} else {
-				outputForPing = make([]byte, outputForPingMaxLen)
-				tempFile.Seek(outputForPingMaxLen*-1, 2)
-			}
-			tempFile.Read(outputForPing)
+	if noStdoutPassthru {
+		return []byte{}
+	}
+
+	return readOutputTail(tempFile, outputForPingMaxLen)
+}
+
+// The end of a run's output kept in the run history, which is recorded even with --no-stdout because it stays local
+const outputForHistoryMaxLen int64 = 4000
+
+// readOutputTail returns up to maxLen bytes from the end of the captured output
+func readOutputTail(tempFile *os.File, maxLen int64) []byte {
+	output := []byte{}
+	if tempFile == nil {
+		return output
+	}
+
+	// Known reasons stat could fail here:
+	// 1. temp file was removed by an external process
+	// 2. filesystem is no longer available
+	if stat, err := os.Stat(tempFile.Name()); err == nil {
+		if size := stat.Size(); size < maxLen {
+			output = make([]byte, size)
+			tempFile.Seek(0, 0)
+		} else {
+			output = make([]byte, maxLen)
+			tempFile.Seek(maxLen*-1, 2)
 		}
+		tempFile.Read(output)
 	}
 
-	return outputForPing
+	return output
 }
 
 func isStaleFile(file os.FileInfo) bool {
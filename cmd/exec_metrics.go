@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// writeExecMetrics records the last run of a job in the node_exporter textfile, if one is configured. Metrics are
+// written locally and do not need an API key or network access.
+func writeExecMetrics(code string, startTime float64, endTime float64, exitCode int, state *os.ProcessState) {
+	filename := viper.GetString(varTextfile)
+	if len(filename) == 0 {
+		return
+	}
+
+	if err := lib.UpdateTextfile(filename, "monitor", code, createExecMetrics(code, startTime, endTime, exitCode, state)); err != nil {
+		log(fmt.Sprintf("Cannot write metrics to %s: %s", filename, err.Error()))
+	}
+}
+
+func createExecMetrics(code string, startTime float64, endTime float64, exitCode int, state *os.ProcessState) []lib.Metric {
+	labels := map[string]string{"monitor": code}
+	gauge := func(name string, help string, value float64) lib.Metric {
+		return lib.Metric{Name: name, Help: help, Type: lib.METRIC_GAUGE, Samples: []lib.MetricSample{{Labels: labels, Value: value}}}
+	}
+
+	success := 0.0
+	if exitCode == 0 {
+		success = 1
+	}
+
+	metrics := []lib.Metric{
+		gauge("cronitor_job_last_run_start_timestamp_seconds", "Unix time the last run of the job started", startTime),
+		gauge("cronitor_job_last_run_end_timestamp_seconds", "Unix time the last run of the job ended", endTime),
+		gauge("cronitor_job_last_run_duration_seconds", "Duration of the last run of the job", endTime-startTime),
+		gauge("cronitor_job_last_run_exit_code", "Exit code of the last run of the job", float64(exitCode)),
+		gauge("cronitor_job_last_run_success", "Whether the last run of the job exited with status 0", success),
+	}
+
+	if state != nil {
+		metrics = append(metrics,
+			gauge("cronitor_job_last_run_user_cpu_seconds", "User CPU time used by the last run of the job", state.UserTime().Seconds()),
+			gauge("cronitor_job_last_run_system_cpu_seconds", "System CPU time used by the last run of the job", state.SystemTime().Seconds()))
+
+		if maxRss, ok := lib.ProcessMaxRssBytes(state); ok {
+			metrics = append(metrics, gauge("cronitor_job_last_run_max_rss_bytes", "Peak resident memory of the last run of the job", maxRss))
+		}
+	}
+
+	return metrics
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestEscapeExecArgs(t *testing.T) {
+	tests := map[string]string{
+		"cronitor exec d3x0c1 ls -la":                              "cronitor exec d3x0c1 -- ls -la",
+		"cronitor exec --no-stdout d3x0c1 ls -la":                  "cronitor exec --no-stdout d3x0c1 -- ls -la",
+		"cronitor exec --pause-during tag:db d3x0c1 ls -la":        "cronitor exec --pause-during tag:db d3x0c1 -- ls -la",
+		"cronitor exec --log-dir /var/log/cronitor d3x0c1 ls -la":  "cronitor exec --log-dir /var/log/cronitor d3x0c1 -- ls -la",
+		"cronitor exec -n web1 --textfile=/tmp/x.prom d3x0c1 ls":   "cronitor exec -n web1 --textfile=/tmp/x.prom d3x0c1 -- ls",
+		"cronitor -k abc exec --pause-during tag:db d3x0c1 backup": "cronitor -k abc exec --pause-during tag:db d3x0c1 -- backup",
+		"cronitor exec -n web1 --pause-during=x7y8z9 d3x0c1 ls":    "cronitor exec -n web1 --pause-during=x7y8z9 d3x0c1 -- ls",
+		"cronitor exec d3x0c1 -- ls -la":                           "cronitor exec d3x0c1 -- ls -la",
+		"cronitor exec d3x0c1":                                     "cronitor exec d3x0c1",
+		"cronitor help exec d3x0c1 ls":                             "cronitor help exec d3x0c1 ls",
+		"cronitor status --tag exec":                               "cronitor status --tag exec",
+	}
+
+	for input, expected := range tests {
+		if actual := strings.Join(EscapeExecArgs(strings.Fields(input)), " "); actual != expected {
+			t.Errorf("EscapeExecArgs(%q) = %q, expected %q", input, actual, expected)
+		}
+	}
+}
+
+func TestExitCodeFromError(t *testing.T) {
+	if code := exitCodeFromError(exec.Command("/nonexistent/cronitor-test").Start()); code != 127 {
+		t.Errorf("Expected 127 for a command that was not found, got %d", code)
+	}
+
+	if code := exitCodeFromError(errors.New("cannot start")); code != 1 {
+		t.Errorf("Expected 1 for a command that could not be started, got %d", code)
+	}
+
+	if code := exitCodeFromError(exec.Command("sh", "-c", "exit 3").Run()); code != 3 {
+		t.Errorf("Expected the exit code of the command, got %d", code)
+	}
+}
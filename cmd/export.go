@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var exportFormat string
+var exportOutput string
+var exportTags []string
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write existing monitors to a YAML or JSON file for use with apply",
+	Long: `
+Cronitor export writes your monitors in the monitors-as-code format read by 'cronitor apply', sorted by key.
+
+Example:
+  $ cronitor export > monitors.yaml
+      > Export every monitor as YAML
+
+  $ cronitor export --tag billing --format json --output billing.json
+      > Export monitors tagged "billing" as JSON
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if exportFormat != "yaml" && exportFormat != "json" {
+			return errors.New("invalid argument supplied to 'format'. Expecting 'yaml' or 'json'")
+		}
+
+		if len(viper.GetString(varApiKey)) < 10 {
+			return errors.New("you must provide a valid API key with this command or save a key using 'cronitor configure'")
+		}
+
+		return nil
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		monitors, err := getMonitorDefinitions()
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		monitors = filterMonitorsByTags(monitors, exportTags)
+
+		output, err := lib.WriteMonitorsFile(monitors, exportFormat)
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		if len(exportOutput) == 0 {
+			fmt.Print(string(output))
+			return
+		}
+
+		if err := ioutil.WriteFile(exportOutput, output, 0644); err != nil {
+			fatal(fmt.Sprintf("Problem writing %s: %s", exportOutput, err.Error()), 1)
+		}
+
+		printDoneText(fmt.Sprintf("Exported %d monitors to %s", len(monitors), exportOutput), false)
+	},
+}
+
+// filterMonitorsByTags keeps monitors that have every one of the supplied tags
+func filterMonitorsByTags(monitors []*lib.MonitorDefinition, tags []string) []*lib.MonitorDefinition {
+	if len(tags) == 0 {
+		return monitors
+	}
+
+	var filtered []*lib.MonitorDefinition
+	for _, monitor := range monitors {
+		hasTags := map[string]bool{}
+		for _, tag := range monitor.Tags {
+			hasTags[tag] = true
+		}
+
+		matches := true
+		for _, tag := range tags {
+			matches = matches && hasTags[tag]
+		}
+
+		if matches {
+			filtered = append(filtered, monitor)
+		}
+	}
+
+	return filtered
+}
+
+func init() {
+	RootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportFormat, "format", "yaml", "Output format. Accepted values: yaml, json")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", exportOutput, "Write to a file instead of stdout")
+	exportCmd.Flags().StringArrayVar(&exportTags, "tag", exportTags, "Only export monitors with this tag. Can be repeated.")
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"cronitor/lib"
+	"cronitor/lib/api"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var exporterListen string
+var exporterInterval time.Duration
+var exporterPings bool
+var exporterSelection monitorSelection
+
+// The most ping requests the exporter makes at once
+const exporterConcurrency = 4
+
+var exporterCmd = &cobra.Command{
+	Use:   "exporter [code...]",
+	Short: "Serve monitor status as Prometheus metrics",
+	Long: `
+Serve Prometheus metrics for your monitors at /metrics. Monitors are polled from the Cronitor API in the background,
+so scrapes are fast and do not make API requests. Each poll requests the monitors list, one page at a time, and with
+--pings one more request for each monitor, so raise --interval when exporting many monitors with --pings.
+
+Metrics, labeled with the monitor key, code, name and type:
+  cronitor_monitor_passing                        1 if the monitor is passing
+  cronitor_monitor_paused                         1 if the monitor is paused
+  cronitor_monitor_last_ping_timestamp_seconds    Unix time of the most recent ping, with --pings
+  cronitor_monitor_last_duration_seconds          Duration reported by the most recent completed run, with --pings
+  cronitor_up                                     1 if the last poll of the API succeeded
+
+Example:
+  $ cronitor exporter --listen :9712
+      > Export every monitor, polling the API every minute
+
+  $ cronitor exporter --tag production --interval 5m --pings
+      > Export monitors tagged "production" with the time and duration of their latest ping, requesting the pings of
+        each monitor every 5 minutes
+
+For metrics about jobs on this host that do not depend on the network, see 'cronitor exec --textfile'.
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(viper.GetString(varApiKey)) < 10 {
+			return errors.New("you must provide an API key with this command or save a key using 'cronitor configure'")
+		}
+
+		if exporterInterval < 10*time.Second {
+			return errors.New("the --interval must be at least 10s")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		exporter := &monitorExporter{}
+		go func() {
+			exporter.poll(args)
+			for waitForNextPoll(exporterInterval) {
+				exporter.poll(args)
+			}
+		}()
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", exporter)
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `<html><body><h1>Cronitor exporter</h1><a href="/metrics">Metrics</a></body></html>`)
+		})
+
+		server := &http.Server{Addr: exporterListen, Handler: mux}
+		go func() {
+			<-apiContext().Done()
+			server.Close()
+		}()
+
+		printSuccessText(fmt.Sprintf("Serving metrics at %s/metrics", exporterListen), false)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal(err.Error(), 1)
+		}
+	},
+}
+
+// monitorExporter keeps the result of the last poll of the API and serves it as metrics
+type monitorExporter struct {
+	lock          sync.RWMutex
+	monitors      []api.Monitor
+	lastPings     map[string]float64
+	lastDurations map[string]float64
+	lastPoll      float64
+	up            bool
+}
+
+func (e *monitorExporter) poll(args []string) {
+	ctx, cancel := context.WithTimeout(apiContext(), exporterInterval)
+	defer cancel()
+
+	monitors, err := selectMonitorsOrAll(args, exporterSelection)
+	if err != nil {
+		log("Poll failed: " + err.Error())
+		e.lock.Lock()
+		e.up = false
+		e.lock.Unlock()
+		return
+	}
+
+	lastPings := map[string]float64{}
+	lastDurations := map[string]float64{}
+	if exporterPings {
+		var results sync.Mutex
+		var wait sync.WaitGroup
+		client := getCronitorApi()
+		slots := make(chan bool, exporterConcurrency)
+		for _, monitor := range monitors {
+			wait.Add(1)
+			slots <- true
+			go func(key string) {
+				defer func() { <-slots; wait.Done() }()
+				pings, err := client.Pings(ctx, key, 0)
+				if err != nil {
+					log(fmt.Sprintf("Cannot read pings for %s: %s", key, err.Error()))
+					return
+				}
+
+				results.Lock()
+				defer results.Unlock()
+				if stamp, duration, ok := latestPing(pings); ok {
+					lastPings[key] = stamp
+					if duration > 0 {
+						lastDurations[key] = duration
+					}
+				}
+			}(monitor.Key)
+		}
+		wait.Wait()
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.monitors = monitors
+	e.lastPings = lastPings
+	e.lastDurations = lastDurations
+	e.lastPoll = makeStamp()
+	e.up = true
+}
+
+// latestPing returns the time of the newest ping and the duration of the newest run that reported one
+func latestPing(pings []api.Event) (float64, float64, bool) {
+	if len(pings) == 0 {
+		return 0, 0, false
+	}
+
+	for _, ping := range pings {
+		if ping.Duration > 0 {
+			return pings[0].Stamp, ping.Duration, true
+		}
+	}
+
+	return pings[0].Stamp, 0, true
+}
+
+func (e *monitorExporter) metrics() []lib.Metric {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	up := 0.0
+	if e.up {
+		up = 1
+	}
+
+	passing := lib.Metric{Name: "cronitor_monitor_passing", Help: "Whether the monitor is passing", Type: lib.METRIC_GAUGE}
+	paused := lib.Metric{Name: "cronitor_monitor_paused", Help: "Whether the monitor is paused", Type: lib.METRIC_GAUGE}
+	lastPing := lib.Metric{Name: "cronitor_monitor_last_ping_timestamp_seconds", Help: "Unix time of the most recent ping", Type: lib.METRIC_GAUGE}
+	lastDuration := lib.Metric{Name: "cronitor_monitor_last_duration_seconds", Help: "Duration reported by the most recent completed run", Type: lib.METRIC_GAUGE}
+
+	for _, monitor := range e.monitors {
+		labels := map[string]string{"key": monitor.Key, "code": monitor.Code, "name": monitor.Name, "type": monitor.Type}
+		passing.Samples = append(passing.Samples, lib.MetricSample{Labels: labels, Value: boolMetricValue(monitor.Passing)})
+		paused.Samples = append(paused.Samples, lib.MetricSample{Labels: labels, Value: boolMetricValue(monitor.Paused)})
+		if stamp, ok := e.lastPings[monitor.Key]; ok {
+			lastPing.Samples = append(lastPing.Samples, lib.MetricSample{Labels: labels, Value: stamp})
+		}
+		if duration, ok := e.lastDurations[monitor.Key]; ok {
+			lastDuration.Samples = append(lastDuration.Samples, lib.MetricSample{Labels: labels, Value: duration})
+		}
+	}
+
+	metrics := []lib.Metric{
+		{Name: "cronitor_up", Help: "Whether the last poll of the Cronitor API succeeded", Type: lib.METRIC_GAUGE, Samples: []lib.MetricSample{{Value: up}}},
+		passing,
+		paused,
+		lastPing,
+		lastDuration,
+	}
+
+	if e.lastPoll > 0 {
+		metrics = append(metrics, lib.Metric{Name: "cronitor_last_poll_timestamp_seconds", Help: "Unix time of the last successful poll of the Cronitor API", Type: lib.METRIC_GAUGE, Samples: []lib.MetricSample{{Value: e.lastPoll}}})
+	}
+
+	return metrics
+}
+
+func (e *monitorExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	lib.WriteMetrics(w, e.metrics())
+}
+
+func boolMetricValue(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+func init() {
+	RootCmd.AddCommand(exporterCmd)
+	addMonitorSelectionFlags(exporterCmd, &exporterSelection)
+	exporterCmd.Flags().StringVar(&exporterListen, "listen", ":9712", "Address to serve metrics on")
+	exporterCmd.Flags().DurationVar(&exporterInterval, "interval", time.Minute, "How often to poll the Cronitor API")
+	exporterCmd.Flags().BoolVar(&exporterPings, "pings", false, "Request each monitor's pings on every poll to export the last ping and duration metrics")
+}
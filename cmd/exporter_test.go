@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"cronitor/lib/api"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestExporterMetrics(t *testing.T) {
+	exporter := &monitorExporter{
+		monitors: []api.Monitor{
+			{Key: "backup", Code: "abc", Name: "Nightly backup", Type: "job", Passing: true},
+			{Key: "reports", Code: "def", Name: "Reports", Type: "job", Paused: true},
+		},
+		lastPings:     map[string]float64{"backup": 1600000000.5},
+		lastDurations: map[string]float64{"backup": 42},
+		up:            true,
+	}
+
+	var output strings.Builder
+	lib.WriteMetrics(&output, exporter.metrics())
+	for _, expected := range []string{
+		"cronitor_up 1\n",
+		`cronitor_monitor_passing{code="abc",key="backup",name="Nightly backup",type="job"} 1`,
+		`cronitor_monitor_paused{code="def",key="reports",name="Reports",type="job"} 1`,
+		`cronitor_monitor_last_ping_timestamp_seconds{code="abc",key="backup",name="Nightly backup",type="job"} 1.6000000005e+09`,
+		`cronitor_monitor_last_duration_seconds{code="abc",key="backup",name="Nightly backup",type="job"} 42`,
+	} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", expected, output.String())
+		}
+	}
+
+	if strings.Contains(output.String(), `last_ping_timestamp_seconds{code="def"`) {
+		t.Error("Expected no last ping for a monitor without pings")
+	}
+}
+
+func TestLatestPing(t *testing.T) {
+	pings := []api.Event{{Stamp: 300, Event: "run"}, {Stamp: 200, Event: "complete", Duration: 12.5}, {Stamp: 100, Event: "run"}}
+	if stamp, duration, ok := latestPing(pings); !ok || stamp != 300 || duration != 12.5 {
+		t.Errorf("Expected the newest ping and the newest duration, got %v %v %v", stamp, duration, ok)
+	}
+
+	if _, _, ok := latestPing(nil); ok {
+		t.Error("Expected no latest ping without pings")
+	}
+}
+
+func TestCreateExecMetrics(t *testing.T) {
+	command := exec.Command("sh", "-c", "exit 3")
+	command.Run()
+
+	metrics := createExecMetrics("abc", 100, 102.5, 3, command.ProcessState)
+	values := map[string]float64{}
+	for _, metric := range metrics {
+		if metric.Samples[0].Labels["monitor"] != "abc" {
+			t.Errorf("Expected %s to be labeled with the monitor code", metric.Name)
+		}
+		values[metric.Name] = metric.Samples[0].Value
+	}
+
+	if values["cronitor_job_last_run_duration_seconds"] != 2.5 || values["cronitor_job_last_run_exit_code"] != 3 || values["cronitor_job_last_run_success"] != 0 {
+		t.Errorf("Unexpected metrics %v", values)
+	}
+
+	if _, ok := values["cronitor_job_last_run_user_cpu_seconds"]; !ok {
+		t.Error("Expected CPU time metrics")
+	}
+}
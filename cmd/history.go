@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var historyFailed bool
+var historySince string
+var historyLimit int
+var historyOutput bool
+var historyJson bool
+
+var historyCmd = &cobra.Command{
+	Use:   "history [code]",
+	Short: "View the runs recorded on this host",
+	Long: `
+View the jobs run by 'cronitor exec' on this host. Every run is recorded locally, with its command, start and end,
+exit code, the end of its output and whether its pings reached Cronitor, so the history is available when Cronitor
+cannot be reached.
+
+Runs are kept in the state directory: /var/lib/cronitor for root, or ~/.local/state/cronitor for other users. Set
+CRONITOR_STATE_DIR to use another directory. The latest 5000 runs from the last 30 days are kept; change this with
+CRONITOR_HISTORY_MAX_RUNS and CRONITOR_HISTORY_MAX_AGE, or set CRONITOR_HISTORY_MAX_RUNS to 0 to stop recording runs.
+
+Example:
+  $ cronitor history
+      > Show the latest 20 runs of every job
+
+  $ cronitor history d3x0c1 --failed --since 7d
+      > Show failed runs of a single job from the last week
+
+  $ cronitor history d3x0c1 --limit 1 --output
+      > Show the last run of a job with the end of its output
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return errors.New("only one monitor code can be given")
+		}
+
+		if _, err := parseActivityTime(historySince, time.Now()); err != nil {
+			return errors.New(fmt.Sprintf("invalid --since %s: %s", historySince, err.Error()))
+		}
+
+		if historyLimit < 0 {
+			return errors.New("--limit cannot be negative")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		filter := lib.RunFilter{Failed: historyFailed, Limit: historyLimit}
+		filter.Since, _ = parseActivityTime(historySince, time.Now())
+		if len(args) > 0 {
+			filter.Monitor = args[0]
+		}
+
+		history := getHistory()
+		runs, err := history.Runs(filter)
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		if historyJson {
+			if runs == nil {
+				runs = []lib.RunRecord{}
+			}
+			b, _ := json.MarshalIndent(runs, "", "  ")
+			fmt.Println(string(b))
+			return
+		}
+
+		if len(runs) == 0 {
+			fmt.Println("No runs recorded in " + history.Filename)
+			return
+		}
+
+		if historyOutput {
+			printRunOutput(runs)
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Started", "Code", "Command", "Duration", "Exit Code", "Pings"})
+		table.SetAutoWrapText(false)
+		table.SetHeaderAlignment(3)
+		for _, run := range runs {
+			table.Append([]string{
+				formatRunTime(run.Start),
+				run.Monitor,
+				truncateString(run.Command, 50),
+				formatRunDuration(run.Duration),
+				strconv.Itoa(run.ExitCode),
+				formatPingDelivery(run.Pings),
+			})
+		}
+		table.Render()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().BoolVar(&historyFailed, "failed", false, "Show only runs that exited with a non-zero exit code")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Show runs started after this time, e.g. 24h, 7d, 2020-06-01 or 2020-06-01T22:00:00Z")
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Show at most this many of the latest runs, or 0 for no limit")
+	historyCmd.Flags().BoolVar(&historyOutput, "output", false, "Show the end of each run's output")
+	historyCmd.Flags().BoolVar(&historyJson, "json", false, "Print the runs as JSON")
+}
+
+// stateDirectory is where exec keeps what it records about runs. It is system-wide for root and per-user otherwise.
+func stateDirectory() string {
+	if directory := viper.GetString(varStateDir); len(directory) > 0 {
+		return directory
+	}
+
+	if runtime.GOOS == "windows" {
+		if localAppData := os.Getenv("LOCALAPPDATA"); len(localAppData) > 0 {
+			return filepath.Join(localAppData, "Cronitor")
+		}
+		return fmt.Sprintf("%s\\ProgramData\\Cronitor", os.Getenv("SYSTEMDRIVE"))
+	}
+
+	if os.Geteuid() == 0 {
+		return "/var/lib/cronitor"
+	}
+
+	if stateHome := os.Getenv("XDG_STATE_HOME"); len(stateHome) > 0 {
+		return filepath.Join(stateHome, "cronitor")
+	} else if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", "cronitor")
+	}
+
+	return filepath.Join(os.TempDir(), "cronitor")
+}
+
+func getHistory() *lib.History {
+	maxAge, _ := parseHumanDuration(viper.GetString(varHistoryMaxAge))
+	return &lib.History{
+		Filename: filepath.Join(stateDirectory(), lib.HISTORY_FILE),
+		MaxRuns:  viper.GetInt(varHistoryMaxRuns),
+		MaxAge:   maxAge,
+	}
+}
+
+// recordRun adds a run to the history unless recording is turned off. A failure is logged and does not affect the job.
+func recordRun(record lib.RunRecord) {
+	history := getHistory()
+	if history.MaxRuns <= 0 {
+		return
+	}
+
+	if err := history.Append(record); err != nil {
+		log("Cannot record run: " + err.Error())
+	}
+}
+
+func printRunOutput(runs []lib.RunRecord) {
+	for i, run := range runs {
+		if i > 0 {
+			printLn()
+		}
+
+		fmt.Printf("%s  %s  exit code %d  %s  pings %s\n", formatRunTime(run.Start), run.Monitor, run.ExitCode, formatRunDuration(run.Duration), formatPingDelivery(run.Pings))
+		fmt.Printf("$ %s\n", run.Command)
+		if len(run.Log) > 0 {
+			fmt.Printf("Full output: %s\n", run.Log)
+		}
+		if output := strings.TrimRight(run.Output, "\n"); len(output) > 0 {
+			fmt.Println(output)
+		}
+	}
+}
+
+func formatRunTime(stamp float64) string {
+	return time.Unix(int64(stamp), 0).Format("2006-01-02 15:04:05")
+}
+
+func formatRunDuration(seconds float64) string {
+	return (time.Duration(seconds*1000) * time.Millisecond).String()
+}
+
+// formatPingDelivery summarizes the pings sent for a run as "sent" when every ping was delivered, or lists each ping
+// and what happened to it
+func formatPingDelivery(pings map[string]string) string {
+	if len(pings) == 0 {
+		return "none"
+	}
+
+	var endpoints []string
+	allSent := true
+	for endpoint, delivery := range pings {
+		endpoints = append(endpoints, endpoint)
+		allSent = allSent && delivery == lib.PING_SENT
+	}
+
+	if allSent {
+		return lib.PING_SENT
+	}
+
+	// The run ping is sent before complete or fail
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i] == "run" || (endpoints[j] != "run" && endpoints[i] < endpoints[j])
+	})
+
+	var parts []string
+	for _, endpoint := range endpoints {
+		parts = append(parts, fmt.Sprintf("%s %s", endpoint, pings[endpoint]))
+	}
+	return strings.Join(parts, ", ")
+}
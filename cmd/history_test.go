@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestFormatPingDelivery(t *testing.T) {
+	tests := []struct {
+		pings    map[string]string
+		expected string
+	}{
+		{nil, "none"},
+		{map[string]string{"run": lib.PING_SENT, "complete": lib.PING_SENT}, "sent"},
+		{map[string]string{"fail": lib.PING_FAILED, "run": lib.PING_SENT}, "run sent, fail failed"},
+		{map[string]string{"complete": lib.PING_REJECTED}, "complete rejected"},
+	}
+
+	for _, test := range tests {
+		if actual := formatPingDelivery(test.pings); actual != test.expected {
+			t.Errorf("formatPingDelivery(%v) = %q, expected %q", test.pings, actual, test.expected)
+		}
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	viper.Set(varStateDir, "/tmp/cronitor-state")
+	viper.Set(varHistoryMaxAge, "7d")
+	defer viper.Set(varStateDir, "")
+	defer viper.Set(varHistoryMaxAge, "30d")
+
+	history := getHistory()
+	if history.Filename != filepath.Join("/tmp/cronitor-state", lib.HISTORY_FILE) {
+		t.Errorf("Expected the history in CRONITOR_STATE_DIR, got %s", history.Filename)
+	}
+	if history.MaxAge.Hours() != 7*24 || history.MaxRuns != 5000 {
+		t.Errorf("Expected 5000 runs for 7 days, got %d runs for %s", history.MaxRuns, history.MaxAge)
+	}
+
+	if runtime.GOOS != "windows" {
+		viper.Set(varStateDir, "")
+		if directory := stateDirectory(); len(directory) == 0 || !filepath.IsAbs(directory) {
+			t.Errorf("Expected an absolute default state directory, got %q", directory)
+		}
+	}
+}
+
+func TestRunThatCannotStartIsRecordedAsFailed(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+
+	history := &lib.History{Filename: filepath.Join(directory, lib.HISTORY_FILE)}
+	err := exec.Command("/nonexistent/cronitor-test").Start()
+	history.Append(lib.RunRecord{Series: "a", Monitor: "abc", Start: 1000, ExitCode: exitCodeFromError(err)})
+
+	runs, _ := history.Runs(lib.RunFilter{Failed: true})
+	if len(runs) != 1 || runs[0].Succeeded() {
+		t.Errorf("Expected a run that could not be started to be listed as failed, got %+v", runs)
+	}
+}
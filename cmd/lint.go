@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type LintProblem struct {
+	Filename string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+}
+
+const lintError = "error"
+const lintWarning = "warning"
+
+// The PATH cron provides when a crontab does not set one
+const cronDefaultPath = "/usr/bin:/bin"
+
+var lintFormat string
+var lintSystem bool
+
+var lintEnvironmentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\s*=`)
+var lintUnescapedPercentRegex = regexp.MustCompile(`(^|[^\\])%`)
+var lintDropInNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+var lintShellBuiltins = map[string]bool{".": true, ":": true, "[": true, "cd": true, "echo": true, "eval": true, "exec": true, "export": true, "nice": true, "printf": true, "set": true, "source": true, "test": true, "time": true, "true": true, "false": true, "ulimit": true, "umask": true}
+
+var lintCmd = &cobra.Command{
+	Use:   "lint <optional path>",
+	Short: "Check crontabs for problems cron silently tolerates",
+	Long: `
+Cronitor lint reads your crontabs and reports problems that cause cron to skip jobs or lines without any warning.
+
+Checks include invalid schedules, a missing trailing newline, unescaped % characters, commands that cannot be found or
+are not executable, unknown users in system crontabs, duplicate jobs, and /etc/cron.d files that cron will not read.
+
+Each problem is reported with its file, line number and severity. The exit code is 1 if any errors are found.
+
+Example:
+  $ cronitor lint
+      > Check your user crontab, the system crontab and the system drop-in directory
+
+  $ cronitor lint /path/to/crontab
+      > Check a crontab file (or directory of crontabs)
+
+  $ cronitor lint /path/to/cron.d --system
+      > Check a directory of system crontabs, where each line names the user to run as
+
+  $ cronitor lint --format json
+      > Output problems as JSON for use by other tools
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if lintFormat != "text" && lintFormat != "json" {
+			return errors.New("invalid argument supplied to 'format'. Expecting 'text' or 'json'")
+		}
+
+		return nil
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		var username string
+		if u, err := user.Current(); err == nil {
+			username = u.Username
+		}
+
+		problems := []LintProblem{}
+		seenJobs := map[string]string{}
+
+		if len(args) > 0 {
+			if isPathToDirectory(args[0]) {
+				problems = append(problems, lintDirectory(username, args[0], lintSystem, seenJobs)...)
+			} else {
+				problems = append(problems, lintCrontab(lib.CrontabFactory(username, args[0]), lintSystem || isSystemCrontabFile(args[0]), seenJobs)...)
+			}
+		} else {
+			if userCrontab := lib.CrontabFactory(username, ""); userCrontab.Exists() {
+				problems = append(problems, lintCrontab(userCrontab, false, seenJobs)...)
+			}
+
+			if systemCrontab := lib.CrontabFactory(username, lib.SYSTEM_CRONTAB); systemCrontab.Exists() {
+				problems = append(problems, lintCrontab(systemCrontab, true, seenJobs)...)
+			}
+
+			problems = append(problems, lintDirectory(username, lib.DROP_IN_DIRECTORY, true, seenJobs)...)
+		}
+
+		hasErrors := false
+		for _, problem := range problems {
+			hasErrors = hasErrors || problem.Severity == lintError
+		}
+
+		if lintFormat == "json" {
+			b, _ := json.MarshalIndent(problems, "", "  ")
+			fmt.Println(string(b))
+		} else {
+			for _, problem := range problems {
+				location := problem.Filename
+				if problem.Line > 0 {
+					location = fmt.Sprintf("%s:%d", location, problem.Line)
+				}
+				fmt.Println(fmt.Sprintf("%s: %s: %s (%s)", location, problem.Severity, problem.Message, problem.Check))
+			}
+		}
+
+		if hasErrors {
+			os.Exit(1)
+		}
+	},
+}
+
+func lintDirectory(username, directory string, isSystem bool, seenJobs map[string]string) []LintProblem {
+	var problems []LintProblem
+	for _, crontabFile := range lib.EnumerateCrontabFiles(directory) {
+		if isSystem {
+			problems = append(problems, lintDropInFile(crontabFile)...)
+		}
+
+		problems = append(problems, lintCrontab(lib.CrontabFactory(username, crontabFile), isSystem, seenJobs)...)
+	}
+
+	return problems
+}
+
+// lintDropInFile reports drop-in files that cron will skip because of their name, owner or permissions
+func lintDropInFile(filename string) []LintProblem {
+	var problems []LintProblem
+	problem := func(severity, check, message string) {
+		problems = append(problems, LintProblem{Filename: filename, Severity: severity, Check: check, Message: message})
+	}
+
+	if !lintDropInNameRegex.MatchString(filepath.Base(filename)) {
+		problem(lintWarning, "filename", "file name contains characters other than letters, digits, underscores and hyphens; cron on Debian and Ubuntu will skip this file")
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return problems
+	}
+
+	if info.Mode().Perm()&0022 != 0 {
+		problem(lintError, "permissions", fmt.Sprintf("file is writable by group or other (mode %04o); cron will skip this file", info.Mode().Perm()))
+	}
+
+	if uid, ok := fileOwnerUid(info); ok && uid != 0 {
+		problem(lintError, "permissions", fmt.Sprintf("file is owned by uid %d instead of root; cron will skip this file", uid))
+	}
+
+	return problems
+}
+
+func lintCrontab(crontab *lib.Crontab, isSystem bool, seenJobs map[string]string) []LintProblem {
+	var problems []LintProblem
+	problem := func(line *lib.Line, severity, check, message string) {
+		lintProblem := LintProblem{Filename: crontab.DisplayName(), Severity: severity, Check: check, Message: message}
+		if line != nil {
+			lintProblem.Line = line.LineNumber + 1
+		}
+		problems = append(problems, lintProblem)
+	}
+
+	if err, _ := crontab.Parse(true); err != nil {
+		problem(nil, lintError, "read", err.Error())
+		return problems
+	}
+
+	if crontab.DefaultsError != nil {
+		problem(nil, lintWarning, "annotation", crontab.DefaultsError.Error())
+	}
+
+	// Cron uses the PATH from the crontab when one is set
+	path := ""
+	for _, line := range crontab.Lines {
+		if lintEnvironmentRegex.MatchString(line.FullLine) && strings.HasPrefix(line.FullLine, "PATH") {
+			path = strings.Trim(strings.TrimSpace(line.FullLine[strings.Index(line.FullLine, "=")+1:]), "\"'")
+		}
+	}
+
+	for _, line := range crontab.Lines {
+		if line.FullLine == "" || strings.HasPrefix(line.FullLine, "#") || lintEnvironmentRegex.MatchString(line.FullLine) {
+			continue
+		}
+
+		if !line.IsJob() {
+			problem(line, lintError, "syntax", "line is not a job or environment setting; cron will reject the crontab or skip this line")
+			continue
+		}
+
+		if schedule, err := lib.ParseCronExpression(line.CronExpression); err != nil {
+			problem(line, lintError, "schedule", fmt.Sprintf("invalid schedule \"%s\": %s", line.CronExpression, err.Error()))
+		} else {
+			for _, warning := range schedule.Warnings {
+				problem(line, lintWarning, "schedule", warning)
+			}
+		}
+
+		if line.AnnotationError != nil {
+			problem(line, lintWarning, "annotation", line.AnnotationError.Error())
+		}
+
+		command := line.CommandToRun
+		runAs := line.RunAs
+		if isSystem && runAs == "" {
+			// The parser only recognizes the user field when the user exists
+			fields := strings.Fields(command)
+			if len(fields) < 2 {
+				problem(line, lintError, "user", "system crontab line is missing the user field")
+			} else if _, err := user.Lookup(fields[0]); err != nil {
+				problem(line, lintError, "user", fmt.Sprintf("user \"%s\" does not exist; cron will not run this job", fields[0]))
+				command = strings.Join(fields[1:], " ")
+			}
+		}
+
+		if lintUnescapedPercentRegex.MatchString(command) {
+			problem(line, lintWarning, "percent", "unescaped % is converted to a newline by cron and everything after it is sent to the command as stdin; escape it as \\%")
+		}
+
+		if severity, check, message := lintExecutable(command, path); message != "" {
+			problem(line, severity, check, message)
+		}
+
+		jobKey := fmt.Sprintf("%s %s %s", line.CronExpression, runAs, line.CommandToRun)
+		location := fmt.Sprintf("%s:%d", crontab.DisplayName(), line.LineNumber+1)
+		if previous, exists := seenJobs[jobKey]; exists {
+			problem(line, lintWarning, "duplicate", fmt.Sprintf("duplicate of the job at %s", previous))
+		} else {
+			seenJobs[jobKey] = location
+		}
+	}
+
+	// Cron only reads lines that end with a newline, so a missing newline silently drops the last job. A comment or
+	// environment setting on the last line does no harm.
+	if !crontab.IsUserCrontab && len(crontab.Lines) > 0 {
+		if lastLine := crontab.Lines[len(crontab.Lines)-1]; lastLine.IsJob() {
+			problem(lastLine, lintError, "newline", "file does not end with a newline; cron will ignore the last line")
+		}
+	}
+
+	return problems
+}
+
+// lintExecutable checks that the first word of a command can be run, returning an empty message if it can
+func lintExecutable(command, path string) (string, string, string) {
+	fields := strings.Fields(command)
+
+	// Skip leading environment assignments, e.g. FOO=bar /path/to/cmd
+	for len(fields) > 0 && lintEnvironmentRegex.MatchString(fields[0]) {
+		fields = fields[1:]
+	}
+
+	if len(fields) == 0 || lintShellBuiltins[fields[0]] || strings.ContainsAny(fields[0][:1], "($`{\"'~") {
+		return "", "", ""
+	}
+
+	executable := strings.TrimRight(fields[0], ";")
+
+	if filepath.IsAbs(executable) {
+		info, err := os.Stat(executable)
+		if err != nil {
+			return lintError, "executable", fmt.Sprintf("command %s does not exist", executable)
+		}
+
+		if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+			return lintError, "executable", fmt.Sprintf("command %s is not executable", executable)
+		}
+
+		return "", "", ""
+	}
+
+	if strings.Contains(executable, "/") {
+		return lintWarning, "path", fmt.Sprintf("command %s is a relative path and will be resolved from the user's home directory", executable)
+	}
+
+	searchPath := path
+	if searchPath == "" {
+		searchPath = cronDefaultPath
+	}
+
+	for _, directory := range filepath.SplitList(searchPath) {
+		if info, err := os.Stat(filepath.Join(directory, executable)); err == nil && !info.IsDir() && info.Mode().Perm()&0111 != 0 {
+			return "", "", ""
+		}
+	}
+
+	if path == "" {
+		return lintWarning, "path", fmt.Sprintf("command %s is not in cron's default PATH (%s) and the crontab does not set PATH", executable, cronDefaultPath)
+	}
+
+	return lintWarning, "path", fmt.Sprintf("command %s was not found in the crontab PATH", executable)
+}
+
+func isSystemCrontabFile(filename string) bool {
+	if absoluteFilename, err := filepath.Abs(filename); err == nil {
+		return absoluteFilename == lib.SYSTEM_CRONTAB || filepath.Dir(absoluteFilename) == lib.DROP_IN_DIRECTORY
+	}
+
+	return false
+}
+
+func init() {
+	RootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().StringVar(&lintFormat, "format", "text", "Output format. Accepted values: text, json")
+	lintCmd.Flags().BoolVar(&lintSystem, "system", lintSystem, "Treat the supplied crontabs as system crontabs that include a user field")
+}
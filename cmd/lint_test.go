@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"testing"
+)
+
+func TestLintCrontab(t *testing.T) {
+	problems := lintCrontab(lib.CrontabFactory("", "../fixtures/crontab-with-problems"), false, map[string]string{})
+
+	expected := []struct {
+		line     int
+		severity string
+		check    string
+	}{
+		{3, lintError, "schedule"},
+		{4, lintWarning, "percent"},
+		{6, lintWarning, "duplicate"},
+		{7, lintError, "executable"},
+		{8, lintError, "syntax"},
+		{9, lintWarning, "path"},
+		{10, lintError, "newline"},
+	}
+
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %+v", len(expected), len(problems), problems)
+	}
+
+	for i, e := range expected {
+		if problems[i].Line != e.line || problems[i].Severity != e.severity || problems[i].Check != e.check {
+			t.Errorf("Expected %s %s on line %d, got: %+v", e.severity, e.check, e.line, problems[i])
+		}
+	}
+}
+
+func TestLintExecutable(t *testing.T) {
+	tables := []struct {
+		command string
+		path    string
+		check   string
+	}{
+		{"/bin/sh -c 'true'", "", ""},
+		{"FOO=bar /bin/sh", "", ""},
+		{"cd /tmp && ./run.sh", "", ""},
+		{"sh script.sh", "", ""},
+		{"definitely-not-a-command-xyz", "", "path"},
+		{"definitely-not-a-command-xyz", "/usr/local/bin", "path"},
+		{"/etc/passwd", "", "executable"},
+	}
+
+	for _, table := range tables {
+		if _, check, _ := lintExecutable(table.command, table.path); check != table.check {
+			t.Errorf("Expected check '%s' for '%s', got '%s'", table.check, table.command, check)
+		}
+	}
+}
+
+func TestLintCrontabTrailingComment(t *testing.T) {
+	problems := lintCrontab(lib.CrontabFactory("", "../fixtures/crontab-with-trailing-comment"), false, map[string]string{})
+	if len(problems) != 0 {
+		t.Errorf("Expected a comment without a newline on the last line to be allowed, got %+v", problems)
+	}
+}
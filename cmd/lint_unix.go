@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+func fileOwnerUid(info os.FileInfo) (uint32, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Uid, true
+	}
+
+	return 0, false
+}
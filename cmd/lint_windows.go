@@ -0,0 +1,8 @@
+package cmd
+
+import "os"
+
+// File ownership is not meaningful for cron on Windows
+func fileOwnerUid(info os.FileInfo) (uint32, bool) {
+	return 0, false
+}
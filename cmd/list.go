@@ -17,10 +17,13 @@ Cronitor list scans for cron jobs and displays them in an easy to read table
 
 Example:
   $ cronitor list
-      > List all cron jobs in your user crontab and system directory
+      > List all cron jobs in your user crontab, system directory and /etc/anacrontab
 
   $ cronitor list /path/to/crontab
       > Instead of the user crontab, list the jobs in a provided a crontab file (or directory of crontabs)
+
+  $ cronitor list /etc/anacrontab
+      > List anacron jobs with their period, delay and job identifier
 	`,
 	Args: func(cmd *cobra.Command, args []string) error {
 
@@ -34,23 +37,27 @@ Example:
 		}
 
 		crontabs := []*lib.Crontab{}
+		anacrontabs := []*lib.Anacrontab{}
 		commands := []string{}
 
 		if len(args) > 0 {
 			// A supplied argument can be a specific file or a directory
 			if isPathToDirectory(args[0]) {
 				crontabs = lib.ReadCrontabsInDirectory(username, args[0], crontabs)
+			} else if lib.IsAnacrontabFile(args[0]) {
+				anacrontabs = lib.ReadAnacrontabFromFile(args[0], anacrontabs)
 			} else {
 				crontabs = lib.ReadCrontabFromFile(username, args[0], crontabs)
 			}
 		} else {
-			// Without a supplied argument look at the user crontab, system crontab, and the system drop-in directory
+			// Without a supplied argument look at the user crontab, system crontab, the system drop-in directory and anacrontab
 			crontabs = lib.ReadCrontabFromFile(username, "", crontabs)
 			crontabs = lib.ReadCrontabFromFile(username, lib.SYSTEM_CRONTAB, crontabs)
 			crontabs = lib.ReadCrontabsInDirectory(username, lib.DROP_IN_DIRECTORY, crontabs)
+			anacrontabs = lib.ReadAnacrontabFromFile(lib.ANACRONTAB, anacrontabs)
 		}
 
-		if len(crontabs) == 0 {
+		if len(crontabs) == 0 && len(anacrontabs) == 0 {
 			printWarningText("No crontab files found", false)
 			return
 		}
@@ -81,6 +88,32 @@ Example:
 			table.Render()
 			fmt.Println()
 		}
+
+		for _, anacrontab := range anacrontabs {
+			if len(anacrontab.Lines) == 0 {
+				continue
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Schedule", "Job ID", "Command"})
+			table.SetAutoWrapText(true)
+			table.SetHeaderAlignment(3)
+			table.SetColMinWidth(0, 17)
+			table.SetColMinWidth(2, 80)
+
+			for _, job := range anacrontab.Lines {
+				if !job.IsJob() {
+					continue
+				}
+
+				table.Append([]string{job.Schedule(), job.Identifier, job.CommandToRun})
+				commands = append(commands, job.CommandToRun)
+			}
+
+			printSuccessText(fmt.Sprintf("Checking %s", anacrontab.DisplayName()), false)
+			table.Render()
+			fmt.Println()
+		}
 	},
 }
 
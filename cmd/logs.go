@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var logsRun int
+var logsLast bool
+var logsFollow bool
+var logsList bool
+var logsDir string
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <code>",
+	Short: "View the output of jobs run with a log directory",
+	Long: `
+View the full output of jobs run by 'cronitor exec' with a log directory. Each run's output is kept in its own file,
+in a directory for each monitor, instead of appending it to a log file with ">> /var/log/job.log 2>&1".
+
+Set the log directory with 'exec --log-dir DIR', CRONITOR_LOG_DIR or 'cronitor config set log_dir DIR'. Logs are kept
+for the latest 30 runs of each job; change this with CRONITOR_LOG_MAX_RUNS, limit the space used by each job with
+CRONITOR_LOG_MAX_SIZE (e.g. 100MB) or their age with CRONITOR_LOG_MAX_AGE (e.g. 14d), and compress finished logs with
+CRONITOR_LOG_COMPRESS=true.
+
+Example:
+  $ cronitor logs d3x0c1
+      > Show the output of the latest run
+
+  $ cronitor logs d3x0c1 --list
+      > List the runs that have logs, numbered from the latest
+
+  $ cronitor logs d3x0c1 --run 3
+      > Show the output of the third latest run
+
+  $ cronitor logs d3x0c1 --follow
+      > Show the output of the latest run and, if it is still running, keep showing new output until it finishes
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("a monitor code is required")
+		}
+
+		if logsRun < 0 {
+			return errors.New("--run must be 1 or more")
+		}
+
+		if logsRun > 0 && logsLast {
+			return errors.New("--run cannot be used with --last")
+		}
+
+		if logsList && (logsFollow || logsRun > 0 || logsLast) {
+			return errors.New("--list cannot be used with --run, --last or --follow")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(logsDir) > 0 {
+			viper.Set(varLogDir, logsDir)
+		}
+
+		archive := getLogArchive()
+		if archive == nil {
+			fatal("no log directory is set; use 'cronitor exec --log-dir DIR' or set CRONITOR_LOG_DIR", 1)
+		}
+
+		logs, err := archive.Runs(args[0])
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		if len(logs) == 0 {
+			fatal(fmt.Sprintf("No logs for %s in %s", args[0], archive.Directory), 1)
+		}
+
+		if logsList {
+			printRunLogs(logs)
+			return
+		}
+
+		run := 1
+		if logsRun > 0 {
+			run = logsRun
+		}
+
+		if run > len(logs) {
+			fatal(fmt.Sprintf("There are only %d logs for %s", len(logs), args[0]), 1)
+		}
+
+		runLog := logs[len(logs)-run]
+		if logsFollow && runLog.Running() {
+			err = followRunLog(os.Stdout, runLog.Filename, runLog.Running, 250*time.Millisecond)
+		} else {
+			err = printRunLog(os.Stdout, runLog)
+		}
+
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().IntVar(&logsRun, "run", 0, "Show the Nth latest run, where 1 is the latest")
+	logsCmd.Flags().BoolVar(&logsLast, "last", false, "Show the latest run (default)")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep showing output until the run finishes")
+	logsCmd.Flags().BoolVar(&logsList, "list", false, "List the runs that have logs")
+	logsCmd.Flags().StringVar(&logsDir, "log-dir", "", "The log directory given to exec, if it is not set in the config file or CRONITOR_LOG_DIR")
+}
+
+// getLogArchive returns the archive for run logs, or nil when no log directory is set
+func getLogArchive() *lib.LogArchive {
+	directory := viper.GetString(varLogDir)
+	if len(directory) == 0 {
+		return nil
+	}
+
+	maxBytes, _ := parseByteSize(viper.GetString(varLogMaxSize))
+	maxAge, _ := parseHumanDuration(viper.GetString(varLogMaxAge))
+	return &lib.LogArchive{
+		Directory: directory,
+		MaxRuns:   viper.GetInt(varLogMaxRuns),
+		MaxBytes:  maxBytes,
+		MaxAge:    maxAge,
+		Compress:  viper.GetBool(varLogCompress),
+	}
+}
+
+// createRunLog opens a log for this run of monitorCode when a log directory is set. A failure is logged and the job
+// runs without a log.
+func createRunLog(start float64) *os.File {
+	archive := getLogArchive()
+	if archive == nil {
+		return nil
+	}
+
+	sec := int64(start)
+	f, err := archive.Create(monitorCode, time.Unix(sec, int64((start-float64(sec))*1e9)), os.Getpid())
+	if err != nil {
+		log("Cannot create run log: " + err.Error())
+		return nil
+	}
+
+	return f
+}
+
+// finishRunLog closes a run's log and rotates the monitor's logs, returning the log's final filename
+func finishRunLog(f *os.File) string {
+	if f == nil {
+		return ""
+	}
+
+	f.Close()
+	filename, err := getLogArchive().Finish(monitorCode, f.Name())
+	if err != nil {
+		log("Cannot rotate run logs: " + err.Error())
+	}
+
+	return filename
+}
+
+func printRunLog(w io.Writer, runLog lib.RunLog) error {
+	reader, err := runLog.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// followRunLog copies a log to w, and keeps copying what is written to it until running reports that its run is done
+func followRunLog(w io.Writer, filename string, running func() bool, interval time.Duration) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for {
+		// Check before copying so that output written just before the run finished is not missed
+		done := !running()
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+
+		if done {
+			return nil
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+func printRunLogs(logs []lib.RunLog) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Run", "Started", "Size", "Status", "File"})
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(3)
+
+	for i := len(logs) - 1; i >= 0; i-- {
+		status := "Finished"
+		if logs[i].Running() {
+			status = "Running"
+		} else if !logs[i].Finished {
+			status = "Ended early"
+		} else if logs[i].Compressed {
+			status = "Compressed"
+		}
+
+		table.Append([]string{
+			strconv.Itoa(len(logs) - i),
+			logs[i].Start.Format("2006-01-02 15:04:05"),
+			formatByteSize(logs[i].Size),
+			status,
+			logs[i].Filename,
+		})
+	}
+
+	table.Render()
+}
+
+var byteSizeRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMGT]?)(?:I?B)?$`)
+
+// parseByteSize converts a size like 500K, 100MB or 1.5GB to bytes, counting 1024 bytes to the kilobyte. An empty value
+// is zero.
+func parseByteSize(value string) (int64, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	matches := byteSizeRegex.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if matches == nil {
+		return 0, errors.New("expecting a size like 500K, 100MB or 1GB")
+	}
+
+	units := map[string]float64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+	size, _ := strconv.ParseFloat(matches[1], 64)
+	return int64(size * units[matches[2]]), nil
+}
+
+func formatByteSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
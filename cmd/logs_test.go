@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{"": 0, "512": 512, "500K": 500 << 10, "100MB": 100 << 20, "1.5gb": 3 << 29, "2MiB": 2 << 20}
+	for value, expected := range tests {
+		if actual, err := parseByteSize(value); err != nil || actual != expected {
+			t.Errorf("parseByteSize(%q) = %d %v, expected %d", value, actual, err, expected)
+		}
+	}
+
+	for _, value := range []string{"MB", "10 apples", "-5M"} {
+		if _, err := parseByteSize(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+func TestFollowRunLog(t *testing.T) {
+	f, _ := ioutil.TempFile("", "cronitor-log")
+	defer os.Remove(f.Name())
+	f.WriteString("line 1\n")
+
+	// The run writes a line each time it is checked and finishes after the third check
+	checks := 0
+	running := func() bool {
+		checks++
+		f.WriteString("line " + string(rune('1'+checks)) + "\n")
+		return checks < 3
+	}
+
+	var output bytes.Buffer
+	if err := followRunLog(&output, f.Name(), running, time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.Close()
+
+	if expected := "line 1\nline 2\nline 3\nline 4\n"; output.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, output.String())
+	}
+}
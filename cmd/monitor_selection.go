@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"cronitor/lib/api"
+	"errors"
+	"fmt"
+	"os/user"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// monitorSelection chooses monitors by code or key, and by tag, name pattern or the jobs on this host
+type monitorSelection struct {
+	Tags     []string
+	Names    []string
+	ThisHost bool
+}
+
+func (s monitorSelection) hasFilters() bool {
+	return len(s.Tags) > 0 || len(s.Names) > 0 || s.ThisHost
+}
+
+func addMonitorSelectionFlags(cmd *cobra.Command, selection *monitorSelection) {
+	cmd.Flags().StringSliceVar(&selection.Tags, "tag", nil, "Select monitors with this tag. Can be used more than once; monitors must have every tag.")
+	cmd.Flags().StringSliceVar(&selection.Names, "name", nil, "Select monitors whose name matches this pattern, e.g. 'web1 *'. Can be used more than once.")
+	cmd.Flags().BoolVar(&selection.ThisHost, "this-host", false, "Select the monitors for cron jobs on this host")
+}
+
+// selectMonitors returns the monitors given by code or key in args, followed by the monitors that match every filter
+// in the selection
+func selectMonitors(args []string, selection monitorSelection) ([]api.Monitor, error) {
+	if len(args) == 0 && !selection.hasFilters() {
+		return nil, errors.New("a monitor code, --tag, --name or --this-host is required")
+	}
+
+	client := getCronitorApi()
+	var selected []api.Monitor
+	seen := map[string]bool{}
+	for _, key := range args {
+		monitor, err := client.GetMonitor(apiContext(), key)
+		if api.IsNotFound(err) {
+			return nil, errors.New(fmt.Sprintf("monitor %s does not exist", key))
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !seen[monitor.Key] {
+			selected = append(selected, *monitor)
+			seen[monitor.Key] = true
+		}
+	}
+
+	if !selection.hasFilters() {
+		return selected, nil
+	}
+
+	monitors, err := client.AllMonitors(apiContext())
+	if err != nil {
+		return nil, err
+	}
+
+	var hostIdentifiers map[string]bool
+	if selection.ThisHost {
+		hostIdentifiers = hostMonitorIdentifiers()
+	}
+
+	for _, monitor := range filterMonitors(monitors, selection, hostIdentifiers) {
+		if !seen[monitor.Key] {
+			selected = append(selected, monitor)
+			seen[monitor.Key] = true
+		}
+	}
+
+	return selected, nil
+}
+
+// selectMonitorsOrAll is selectMonitors, but returns every monitor when no codes or filters are given
+func selectMonitorsOrAll(args []string, selection monitorSelection) ([]api.Monitor, error) {
+	if len(args) == 0 && !selection.hasFilters() {
+		return getCronitorApi().AllMonitors(apiContext())
+	}
+
+	return selectMonitors(args, selection)
+}
+
+// filterMonitors keeps monitors that match every filter in the selection. hostIdentifiers holds the keys and codes of
+// the jobs on this host, and is used only when the selection includes ThisHost.
+func filterMonitors(monitors []api.Monitor, selection monitorSelection, hostIdentifiers map[string]bool) []api.Monitor {
+	var filtered []api.Monitor
+	for _, monitor := range monitors {
+		if matchesMonitorSelection(monitor, selection, hostIdentifiers) {
+			filtered = append(filtered, monitor)
+		}
+	}
+
+	return filtered
+}
+
+func matchesMonitorSelection(monitor api.Monitor, selection monitorSelection, hostIdentifiers map[string]bool) bool {
+	for _, tag := range selection.Tags {
+		if !hasTag(monitor.Tags, tag) {
+			return false
+		}
+	}
+
+	if len(selection.Names) > 0 {
+		matched := false
+		for _, pattern := range selection.Names {
+			if ok, _ := filepath.Match(pattern, monitor.Name); ok {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if selection.ThisHost && !hostIdentifiers[monitor.Key] && !hostIdentifiers[monitor.Code] {
+		return false
+	}
+
+	return true
+}
+
+// hostMonitorIdentifiers returns the keys discover gives the cron jobs on this host, and the codes of jobs that are
+// already monitored with 'cronitor exec'. Both key versions are included so monitors created before a key migration
+// are found.
+func hostMonitorIdentifiers() map[string]bool {
+	var username string
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+
+	crontabs := []*lib.Crontab{}
+	crontabs = lib.ReadCrontabFromFile(username, "", crontabs)
+	crontabs = lib.ReadCrontabFromFile(username, lib.SYSTEM_CRONTAB, crontabs)
+	crontabs = lib.ReadCrontabsInDirectory(username, lib.DROP_IN_DIRECTORY, crontabs)
+
+	identifiers := map[string]bool{}
+	for _, crontab := range crontabs {
+		for _, line := range crontab.Lines {
+			if !line.IsMonitorable() {
+				continue
+			}
+
+			identifiers[crontab.Key(line, lib.KEY_VERSION_1)] = true
+			identifiers[crontab.Key(line, lib.KEY_VERSION_2)] = true
+			if len(line.Code) > 0 {
+				identifiers[line.Code] = true
+			}
+		}
+	}
+
+	for _, anacrontab := range lib.ReadAnacrontabFromFile(lib.ANACRONTAB, []*lib.Anacrontab{}) {
+		for _, job := range anacrontab.Lines {
+			if !job.IsMonitorable() {
+				continue
+			}
+
+			identifiers[job.Key(lib.KEY_VERSION_1)] = true
+			identifiers[job.Key(lib.KEY_VERSION_2)] = true
+			if len(job.Code) > 0 {
+				identifiers[job.Code] = true
+			}
+		}
+	}
+
+	return identifiers
+}
+
+// printSelectedMonitors lists monitors before a bulk change
+func printSelectedMonitors(monitors []api.Monitor) {
+	for _, monitor := range monitors {
+		name := monitor.Name
+		if len(name) == 0 {
+			name = monitor.DefaultName
+		}
+		fmt.Println(fmt.Sprintf("  %s  %s", monitor.Code, name))
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"cronitor/lib/api"
+	"testing"
+)
+
+func TestFilterMonitors(t *testing.T) {
+	monitors := []api.Monitor{
+		{Key: "k1", Code: "c1", Name: "web1 backup", Tags: []string{"cron-job", "database"}},
+		{Key: "k2", Code: "c2", Name: "web1 cleanup", Tags: []string{"cron-job"}},
+		{Key: "k3", Code: "c3", Name: "web2 backup", Tags: []string{"database"}},
+	}
+
+	tests := []struct {
+		selection monitorSelection
+		host      map[string]bool
+		expected  []string
+	}{
+		{monitorSelection{Tags: []string{"database"}}, nil, []string{"k1", "k3"}},
+		{monitorSelection{Tags: []string{"database", "cron-job"}}, nil, []string{"k1"}},
+		{monitorSelection{Names: []string{"web1 *"}}, nil, []string{"k1", "k2"}},
+		{monitorSelection{Names: []string{"* backup", "web1 cleanup"}}, nil, []string{"k1", "k2", "k3"}},
+		{monitorSelection{ThisHost: true}, map[string]bool{"k2": true, "c3": true}, []string{"k2", "k3"}},
+		{monitorSelection{ThisHost: true, Tags: []string{"database"}}, map[string]bool{"k2": true, "c3": true}, []string{"k3"}},
+	}
+
+	for _, test := range tests {
+		var keys []string
+		for _, monitor := range filterMonitors(monitors, test.selection, test.host) {
+			keys = append(keys, monitor.Key)
+		}
+
+		if len(keys) != len(test.expected) {
+			t.Errorf("Selection %+v: expected %v, got %v", test.selection, test.expected, keys)
+			continue
+		}
+
+		for i := range keys {
+			if keys[i] != test.expected[i] {
+				t.Errorf("Selection %+v: expected %v, got %v", test.selection, test.expected, keys)
+				break
+			}
+		}
+	}
+}
+
+func TestParsePauseHours(t *testing.T) {
+	tests := map[string]int{"": 0, "2h": 2, "30m": 1, "90m": 2}
+	for duration, expected := range tests {
+		if hours, err := parsePauseHours(duration); err != nil || hours != expected {
+			t.Errorf("parsePauseHours(%q) = %d %v, expected %d", duration, hours, err, expected)
+		}
+	}
+
+	for _, duration := range []string{"2 hours", "-1h"} {
+		if _, err := parsePauseHours(duration); err == nil {
+			t.Errorf("Expected error for %q", duration)
+		}
+	}
+}
+
+func TestFlagTakesValue(t *testing.T) {
+	tests := map[string]bool{
+		"--pause-during":   true,
+		"--pause-during=x": false,
+		"--no-stdout":      false,
+		"d3x0c1":           false,
+		"--":               false,
+	}
+
+	for arg, expected := range tests {
+		if actual := flagTakesValue(execCmd, arg); actual != expected {
+			t.Errorf("flagTakesValue(%q) = %v, expected %v", arg, actual, expected)
+		}
+	}
+}
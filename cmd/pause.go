@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"cronitor/lib/api"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var pauseSelection monitorSelection
+var pauseFor string
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause [code...]",
+	Short: "Pause alerts for monitors",
+	Long: `
+Pause alerts for one or more monitors, for example during a maintenance window. Pings are still recorded while a
+monitor is paused. Select monitors by code or key, or use --tag, --name and --this-host to select many at once.
+
+Example:
+  $ cronitor pause d3x0c1
+      > Pause alerts for a single monitor until it is resumed
+
+  $ cronitor pause --tag database --for 2h
+      > Pause every monitor tagged "database" for 2 hours. Pauses are rounded up to the next hour.
+
+  $ cronitor pause --this-host --for 30m
+      > Pause the monitors for every cron job on this host, for example before a reboot
+
+  $ cronitor pause --name "web1 *"
+      > Pause monitors whose name starts with "web1 "
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(viper.GetString(varApiKey)) < 10 {
+			return errors.New("you must provide an API key with this command or save a key using 'cronitor configure'")
+		}
+
+		_, err := parsePauseHours(pauseFor)
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		hours, _ := parsePauseHours(pauseFor)
+		monitors, err := selectMonitors(args, pauseSelection)
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		if len(monitors) == 0 {
+			printWarningText("No monitors matched", false)
+			return
+		}
+
+		paused := updateMonitors(monitors, func(client *api.Client, monitor api.Monitor) error {
+			return client.PauseMonitor(apiContext(), monitor.Key, hours)
+		})
+
+		if hours > 0 {
+			printDoneText(fmt.Sprintf("Paused %d monitors for %d hours", paused, hours), false)
+		} else {
+			printDoneText(fmt.Sprintf("Paused %d monitors", paused), false)
+		}
+
+		if paused < len(monitors) {
+			os.Exit(1)
+		}
+	},
+}
+
+// parsePauseHours converts a --for duration to the whole hours used by the API, rounding up. An empty duration
+// pauses until the monitor is resumed.
+func parsePauseHours(duration string) (int, error) {
+	if len(duration) == 0 {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(duration)
+	if err != nil || d <= 0 {
+		return 0, errors.New(fmt.Sprintf("invalid --for duration %s. Expecting a duration like 30m or 2h", duration))
+	}
+
+	return int(math.Ceil(d.Hours())), nil
+}
+
+// updateMonitors applies a change to each monitor, reporting each failure, and returns the number that succeeded
+func updateMonitors(monitors []api.Monitor, update func(*api.Client, api.Monitor) error) int {
+	client := getCronitorApi()
+	succeeded := 0
+	for _, monitor := range monitors {
+		if err := update(client, monitor); err != nil {
+			printErrorText(fmt.Sprintf("%s: %s", monitor.Code, err.Error()), true)
+			continue
+		}
+
+		succeeded++
+		log(fmt.Sprintf("Updated %s", monitor.Key))
+	}
+
+	return succeeded
+}
+
+// pauseMonitorsDuring pauses the monitors matching each selector, given as a monitor code or key or as tag:NAME, and
+// returns a function that resumes them. Monitors that are already paused are left alone, so they stay paused after
+// the run. The monitors are paused for at most maxHours in case this process is killed before it can resume them. A
+// problem pausing is reported but does not stop the caller.
+func pauseMonitorsDuring(selectors []string, maxHours int) func() {
+	if len(selectors) == 0 {
+		return func() {}
+	}
+
+	var keys []string
+	var selection monitorSelection
+	for _, selector := range selectors {
+		if strings.HasPrefix(selector, "tag:") {
+			selection.Tags = append(selection.Tags, strings.TrimPrefix(selector, "tag:"))
+		} else {
+			keys = append(keys, selector)
+		}
+	}
+
+	monitors, err := selectMonitors(keys, selection)
+	if err != nil {
+		printErrorText("Cannot pause monitors: "+err.Error(), false)
+		return func() {}
+	}
+
+	monitors = unpausedMonitors(monitors)
+	updateMonitors(monitors, func(client *api.Client, monitor api.Monitor) error {
+		return client.PauseMonitor(apiContext(), monitor.Key, maxHours)
+	})
+
+	return func() {
+		updateMonitors(monitors, func(client *api.Client, monitor api.Monitor) error {
+			return client.ResumeMonitor(apiContext(), monitor.Key)
+		})
+	}
+}
+
+func unpausedMonitors(monitors []api.Monitor) []api.Monitor {
+	var unpaused []api.Monitor
+	for _, monitor := range monitors {
+		if !monitor.Paused {
+			unpaused = append(unpaused, monitor)
+		}
+	}
+
+	return unpaused
+}
+
+func init() {
+	RootCmd.AddCommand(pauseCmd)
+	addMonitorSelectionFlags(pauseCmd, &pauseSelection)
+	pauseCmd.Flags().StringVar(&pauseFor, "for", "", "Pause for this long, e.g. 2h, instead of until the monitor is resumed")
+}
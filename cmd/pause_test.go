@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"cronitor/lib/api"
+	"testing"
+)
+
+func TestUnpausedMonitors(t *testing.T) {
+	monitors := []api.Monitor{{Key: "running"}, {Key: "paused", Paused: true}}
+
+	unpaused := unpausedMonitors(monitors)
+	if len(unpaused) != 1 || unpaused[0].Key != "running" {
+		t.Errorf("Expected only the running monitor, got %+v", unpaused)
+	}
+}
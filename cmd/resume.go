@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"cronitor/lib/api"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var resumeSelection monitorSelection
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [code...]",
+	Short: "Resume alerts for paused monitors",
+	Long: `
+Resume alerts for monitors paused with 'cronitor pause'. Select monitors by code or key, or use --tag, --name and
+--this-host to select many at once.
+
+Example:
+  $ cronitor resume d3x0c1
+
+  $ cronitor resume --tag database
+      > Resume every monitor tagged "database" at the end of a maintenance window
+
+  $ cronitor resume --this-host
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(viper.GetString(varApiKey)) < 10 {
+			return errors.New("you must provide an API key with this command or save a key using 'cronitor configure'")
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		monitors, err := selectMonitors(args, resumeSelection)
+		if err != nil {
+			fatal(err.Error(), 1)
+		}
+
+		if len(monitors) == 0 {
+			printWarningText("No monitors matched", false)
+			return
+		}
+
+		resumed := updateMonitors(monitors, func(client *api.Client, monitor api.Monitor) error {
+			return client.ResumeMonitor(apiContext(), monitor.Key)
+		})
+
+		printDoneText(fmt.Sprintf("Resumed %d monitors", resumed), false)
+		if resumed < len(monitors) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(resumeCmd)
+	addMonitorSelectionFlags(resumeCmd, &resumeSelection)
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"context"
 	"cronitor/lib"
+	"cronitor/lib/api"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -10,11 +12,12 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"regexp"
-	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fatih/color"
@@ -36,6 +39,19 @@ var hostname string
 var pingApiKey string
 var verbose bool
 var noStdoutPassthru bool
+var profile string
+
+// Set when the selected profile cannot be loaded. Only 'configure' and 'config set' can run without it, to create the profile.
+var profileErr error
+
+// The HTTP clients shared by every monitors API request and every ping, created when first used
+var apiHttpClient *http.Client
+var pingHttpClient *http.Client
+var httpClientLock sync.Mutex
+
+// The context shared by every monitors API request, created when first used
+var rootContext context.Context
+var rootContextOnce sync.Once
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
@@ -44,6 +60,14 @@ var RootCmd = &cobra.Command{
 	Long: shortDescription(Version) + `
 
 Command line tools for Cronitor.io. See https://cronitor.io/docs/using-cronitor-cli for details.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if profileErr != nil && cmd != configureCmd && cmd != configSetCmd {
+			cmd.SilenceUsage = true
+			return profileErr
+		}
+
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -55,11 +79,38 @@ func Execute() {
 }
 
 var varApiKey = "CRONITOR_API_KEY"
+var varApiKeyFile = "CRONITOR_API_KEY_FILE"
 var varHostname = "CRONITOR_HOSTNAME"
 var varLog = "CRONITOR_LOG"
 var varPingApiKey = "CRONITOR_PING_API_KEY"
 var varExcludeText = "CRONITOR_EXCLUDE_TEXT"
 var varConfig = "CRONITOR_CONFIG"
+var varProfile = "CRONITOR_PROFILE"
+var varKeyVersion = "CRONITOR_KEY_VERSION"
+var varNameTemplate = "CRONITOR_NAME_TEMPLATE"
+var varNameRewrite = "CRONITOR_NAME_REWRITE"
+var varTags = "CRONITOR_TAGS"
+var varTagRules = "CRONITOR_TAG_RULES"
+var varEnvironment = "CRONITOR_ENVIRONMENT"
+var varGrace = "CRONITOR_GRACE"
+var varRules = "CRONITOR_RULES"
+var varNamesFile = "CRONITOR_NAMES_FILE"
+var varTimeout = "CRONITOR_TIMEOUT"
+var varPingTimeout = "CRONITOR_PING_TIMEOUT"
+var varRetries = "CRONITOR_RETRIES"
+var varProxy = "CRONITOR_PROXY"
+var varCaBundle = "CRONITOR_CA_BUNDLE"
+var varClientCert = "CRONITOR_CLIENT_CERT"
+var varClientKey = "CRONITOR_CLIENT_KEY"
+var varTextfile = "CRONITOR_TEXTFILE"
+var varStateDir = "CRONITOR_STATE_DIR"
+var varHistoryMaxRuns = "CRONITOR_HISTORY_MAX_RUNS"
+var varHistoryMaxAge = "CRONITOR_HISTORY_MAX_AGE"
+var varLogDir = "CRONITOR_LOG_DIR"
+var varLogMaxRuns = "CRONITOR_LOG_MAX_RUNS"
+var varLogMaxSize = "CRONITOR_LOG_MAX_SIZE"
+var varLogMaxAge = "CRONITOR_LOG_MAX_AGE"
+var varLogCompress = "CRONITOR_LOG_COMPRESS"
 
 func init() {
 	userAgent = fmt.Sprintf("CronitorCLI/%s", Version)
@@ -71,6 +122,7 @@ func init() {
 	RootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", cfgFile, "Config file")
 	RootCmd.PersistentFlags().StringVarP(&apiKey, "api-key", "k", apiKey, "Cronitor API Key")
 	RootCmd.PersistentFlags().StringVarP(&pingApiKey, "ping-api-key", "p", pingApiKey, "Ping API Key")
+	RootCmd.PersistentFlags().StringVar(&profile, "profile", profile, "Use the named profile from the config file")
 	RootCmd.PersistentFlags().StringVarP(&hostname, "hostname", "n", hostname, "A unique identifier for this host (default: system hostname)")
 	RootCmd.PersistentFlags().StringVarP(&debugLog, "log", "l", debugLog, "Write debug logs to supplied file")
 	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", verbose, "Verbose output")
@@ -83,6 +135,14 @@ func init() {
 	viper.BindPFlag(varLog, RootCmd.PersistentFlags().Lookup("log"))
 	viper.BindPFlag(varPingApiKey, RootCmd.PersistentFlags().Lookup("ping-api-key"))
 	viper.BindPFlag(varConfig, RootCmd.PersistentFlags().Lookup("config"))
+	viper.BindPFlag(varProfile, RootCmd.PersistentFlags().Lookup("profile"))
+
+	viper.SetDefault(varTimeout, "120s")
+	viper.SetDefault(varPingTimeout, "10s")
+	viper.SetDefault(varRetries, 3)
+	viper.SetDefault(varHistoryMaxRuns, 5000)
+	viper.SetDefault(varHistoryMaxAge, "30d")
+	viper.SetDefault(varLogMaxRuns, 30)
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -90,26 +150,25 @@ func initConfig() {
 
 	viper.AutomaticEnv() // read in environment variables that match
 
-	// If a custom config file is specified by flag or env var, use it. Otherwise use default file.
-	if len(viper.GetString(varConfig)) > 0 {
-		viper.SetConfigFile(viper.GetString(varConfig))
-	} else {
-		viper.AddConfigPath(defaultConfigFileDirectory())
-		viper.SetConfigName("cronitor")
-	}
+	// If a custom config file is specified by flag or env var, use it. Otherwise use the default files.
+	readConfigFiles()
+	viper.MergeConfigMap(loadedConfigMap())
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		log("Reading config from " + viper.ConfigFileUsed())
+	profileErr = applyProfile()
+	if profileErr == nil {
+		profileErr = readApiKeyFile()
 	}
 }
 
 func sendPing(endpoint string, uniqueIdentifier string, message string, series string, timestamp float64, duration *float64, exitCode *int, group *sync.WaitGroup) {
 	defer group.Done()
+	deliverPing(endpoint, uniqueIdentifier, message, series, timestamp, duration, exitCode)
+}
 
-	Client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+// deliverPing sends a ping, retrying until it is accepted or rejected, and returns lib.PING_SENT, lib.PING_REJECTED
+// when the ping API refused it, or lib.PING_FAILED when every attempt failed
+func deliverPing(endpoint string, uniqueIdentifier string, message string, series string, timestamp float64, duration *float64, exitCode *int) string {
+	Client := getPingHttpClient()
 
 	hostname := effectiveHostname()
 	pingApiAuthKey := viper.GetString(varPingApiKey)
@@ -149,8 +208,12 @@ func sendPing(endpoint string, uniqueIdentifier string, message string, series s
 		series = fmt.Sprintf("&series=%s", series)
 	}
 
-	pingSent := false
+	delivery := lib.PING_FAILED
 	uri := ""
+
+	// A ping holds up the job it reports on, so waiting for the rate limit to reset stops at the ping timeout
+	// instead of sleeping as long as the server asks on every attempt
+	waitDeadline := time.Now().Add(viper.GetDuration(varPingTimeout))
 	for i := 1; i <= 6; i++ {
 		if dev {
 			pingApiHost = "http://dev.cronitor.io"
@@ -168,7 +231,12 @@ func sendPing(endpoint string, uniqueIdentifier string, message string, series s
 		uri = fmt.Sprintf("%s/%s/%s?try=%d%s%s%s%s%s%s%s", pingApiHost, uniqueIdentifier, endpoint, i, formattedStamp, message, pingApiAuthKey, hostname, formattedDuration, series, formattedStatusCode)
 		log("Sending ping " + uri)
 
-		request, _ := http.NewRequest("GET", uri, nil)
+		request, err := http.NewRequest("GET", uri, nil)
+		if err != nil {
+			log(err.Error())
+			break
+		}
+
 		request.Header.Add("User-Agent", userAgent)
 		response, err := Client.Do(request)
 
@@ -182,20 +250,34 @@ func sendPing(endpoint string, uniqueIdentifier string, message string, series s
 
 		// Any 2xx is considered a successful response
 		if response.StatusCode >= 200 && response.StatusCode < 300 {
-			pingSent = true
+			delivery = lib.PING_SENT
 			break
 		}
 
-		// Backoff on any 4xx request, e.g. 429 Too Many Requests
+		// On 429 Too Many Requests, wait as long as the server asks before trying again
+		if response.StatusCode == http.StatusTooManyRequests {
+			wait := api.RetryAfter(response, time.Second*time.Duration(i))
+			if time.Now().Add(wait).After(waitDeadline) {
+				log(fmt.Sprintf("Ping rate limited; not retrying because the server asked to wait %s", wait))
+				break
+			}
+			log(fmt.Sprintf("Ping rate limited; retrying in %s", wait))
+			time.Sleep(wait)
+			continue
+		}
+
+		// Give up on any other 4xx request, the ping will not succeed if it is sent again
 		if response.StatusCode >= 400 && response.StatusCode < 500 {
-			pingSent = true
+			delivery = lib.PING_REJECTED
 			break
 		}
 	}
 
-	if !pingSent {
+	if delivery == lib.PING_FAILED {
 		raven.CaptureErrorAndWait(errors.New("Ping failure; retries exhausted: "+uri), nil)
 	}
+
+	return delivery
 }
 
 func effectiveHostname() string {
@@ -246,14 +328,6 @@ func effectiveTimezoneLocationName() lib.TimezoneLocationName {
 	return lib.TimezoneLocationName{""}
 }
 
-func defaultConfigFileDirectory() string {
-	if runtime.GOOS == "windows" {
-		return fmt.Sprintf("%s\\ProgramData\\Cronitor", os.Getenv("SYSTEMDRIVE"))
-	}
-
-	return "/etc/cronitor"
-}
-
 func truncateString(s string, length int) string {
 	if len(s) <= length {
 		return s
@@ -365,12 +439,158 @@ func shortDescription(version string) string {
 	return fmt.Sprintf("CronitorCLI version %s", version)
 }
 
-func getCronitorApi() *lib.CronitorApi {
-	return &lib.CronitorApi{
-		IsDev:          dev,
-		IsAutoDiscover: isAutoDiscover,
-		ApiKey:         varApiKey,
-		UserAgent:      userAgent,
-		Logger:         log,
+func getCronitorApi() *api.Client {
+	baseUrl := api.BASE_URL
+	if dev {
+		baseUrl = api.DEV_BASE_URL
+	}
+
+	return &api.Client{
+		BaseUrl:    baseUrl,
+		ApiKey:     viper.GetString(varApiKey),
+		UserAgent:  userAgent,
+		HttpClient: getApiHttpClient(),
+		Logger:     log,
+	}
+}
+
+// getApiHttpClient returns the client for monitors API requests, which retries requests that fail in a way that is
+// likely to be temporary
+func getApiHttpClient() *http.Client {
+	client, err := loadApiHttpClient()
+	if err != nil {
+		fatal(err.Error(), 1)
+	}
+
+	return client
+}
+
+// loadApiHttpClient creates the client for monitors API requests once, returning a problem with the proxy, CA bundle
+// or client certificate settings
+func loadApiHttpClient() (*http.Client, error) {
+	httpClientLock.Lock()
+	defer httpClientLock.Unlock()
+
+	if apiHttpClient == nil {
+		client, err := newHttpClient(viper.GetDuration(varTimeout), viper.GetInt(varRetries))
+		if err != nil {
+			return nil, err
+		}
+		apiHttpClient = client
 	}
+
+	return apiHttpClient, nil
+}
+
+// getPingHttpClient returns the client for pings. sendPing retries pings itself, falling back to a second host.
+// A ping must never stop the job it reports on, so a configuration problem is logged and a default client is used.
+func getPingHttpClient() *http.Client {
+	httpClientLock.Lock()
+	defer httpClientLock.Unlock()
+
+	if pingHttpClient == nil {
+		client, err := newHttpClient(viper.GetDuration(varPingTimeout), 0)
+		if err != nil {
+			log(err.Error())
+			client = &http.Client{Timeout: viper.GetDuration(varPingTimeout)}
+		}
+		pingHttpClient = client
+	}
+
+	return pingHttpClient
+}
+
+func newHttpClient(timeout time.Duration, retries int) (*http.Client, error) {
+	return api.NewHttpClient(api.HttpOptions{
+		Timeout:    timeout,
+		Retries:    retries,
+		Proxy:      viper.GetString(varProxy),
+		CaBundle:   viper.GetString(varCaBundle),
+		ClientCert: viper.GetString(varClientCert),
+		ClientKey:  viper.GetString(varClientKey),
+		Logger:     log,
+	})
+}
+
+// apiContext is the context for monitors API requests made by commands. It is cancelled when the CLI is
+// interrupted or terminated, which stops requests in flight and the polling loops of commands that watch.
+func apiContext() context.Context {
+	rootContextOnce.Do(func() {
+		var cancel context.CancelFunc
+		rootContext, cancel = context.WithCancel(context.Background())
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			// A second signal ends the CLI straight away
+			signal.Stop(signals)
+			cancel()
+		}()
+	})
+
+	return rootContext
+}
+
+// waitForNextPoll waits for interval, returning false if the CLI is interrupted first
+func waitForNextPoll(interval time.Duration) bool {
+	select {
+	case <-apiContext().Done():
+		return false
+	case <-time.After(interval):
+		return true
+	}
+}
+
+// putMonitors sends discovered monitors to Cronitor and saves the code of each new monitor
+func putMonitors(monitors map[string]*lib.Monitor) (map[string]*lib.Monitor, error) {
+	monitorsArray := make([]lib.Monitor, 0, len(monitors))
+	for _, v := range monitors {
+		monitorsArray = append(monitorsArray, *v)
+	}
+
+	responseMonitors, err := getCronitorApi().PutMonitors(apiContext(), monitorsArray, isAutoDiscover)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range responseMonitors {
+		// We only need to update the Monitor struct with a code if this is a new monitor.
+		// For updates the monitor code is sent as well as the key and that takes precedence.
+		if _, ok := monitors[value.Key]; ok {
+			monitors[value.Key].Code = value.Code
+		}
+	}
+
+	return monitors, nil
+}
+
+// getMonitorSummaries fetches every monitor with the fields discover uses to match monitors to jobs
+func getMonitorSummaries() ([]lib.MonitorSummary, error) {
+	monitors, err := getCronitorApi().AllMonitors(apiContext())
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]lib.MonitorSummary, 0, len(monitors))
+	for _, monitor := range monitors {
+		summaries = append(summaries, monitor.Summary())
+	}
+
+	return summaries, nil
+}
+
+// getMonitorDefinitions fetches every monitor with the fields used by monitors-as-code files
+func getMonitorDefinitions() ([]*lib.MonitorDefinition, error) {
+	monitors, err := getCronitorApi().AllMonitors(apiContext())
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := make([]*lib.MonitorDefinition, 0, len(monitors))
+	for _, monitor := range monitors {
+		definitions = append(definitions, monitor.Definition())
+	}
+
+	return definitions, nil
 }
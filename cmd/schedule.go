@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+type ScheduledJob struct {
+	Key               string `json:"key"`
+	Filename          string `json:"file"`
+	LineNumber        int    `json:"line"`
+	Schedule          string `json:"schedule"`
+	Command           string `json:"command"`
+	SuggestedSchedule string `json:"suggested_schedule,omitempty"`
+	schedule          *lib.CronSchedule
+	location          *time.Location
+}
+
+// ScheduleMinute lists the keys of the jobs that start in a minute, which refer to the report's jobs
+type ScheduleMinute struct {
+	Time    time.Time       `json:"time"`
+	Count   int             `json:"count"`
+	JobKeys []string        `json:"jobs,omitempty"`
+	Jobs    []*ScheduledJob `json:"-"`
+}
+
+type ScheduleReport struct {
+	Start       time.Time         `json:"start"`
+	End         time.Time         `json:"end"`
+	Threshold   int               `json:"threshold"`
+	JobCount    int               `json:"job_count"`
+	Jobs        []*ScheduledJob   `json:"jobs"`
+	Minutes     []*ScheduleMinute `json:"minutes"`
+	Hotspots    []*ScheduleMinute `json:"hotspots"`
+	Suggestions []*ScheduledJob   `json:"suggestions"`
+}
+
+var scheduleWindow time.Duration
+var scheduleStart string
+var scheduleThreshold int
+var scheduleFormat string
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Analyze when your cron jobs run",
+}
+
+var scheduleReportCmd = &cobra.Command{
+	Use:   "report <optional path>",
+	Short: "Report how many jobs start in each minute and suggest ways to spread them out",
+	Long: `
+Cronitor schedule report reads every crontab that 'cronitor list' can find and counts how many jobs start in each
+minute of a time window. Minutes where many jobs start together are highlighted as hotspots, and jobs that run at a
+fixed minute are given a suggested schedule in a less busy minute.
+
+Example:
+  $ cronitor schedule report
+      > Show a heatmap of job starts for the next 24 hours, one row per hour and one column per minute
+
+  $ cronitor schedule report /path/to/crontab --window 168h --threshold 5
+      > Analyze a crontab file (or directory of crontabs) for the next week, flagging minutes with 5 or more job starts
+
+  $ cronitor schedule report --format json
+      > Output the jobs, per-minute counts with the keys of the jobs starting, hotspots and suggestions as JSON
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if scheduleFormat != "text" && scheduleFormat != "json" {
+			return errors.New("invalid argument supplied to 'format'. Expecting 'text' or 'json'")
+		}
+
+		if scheduleWindow < time.Minute {
+			return errors.New("the window must be at least one minute")
+		}
+
+		if scheduleThreshold < 2 {
+			return errors.New("the threshold must be at least 2")
+		}
+
+		return nil
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		var username string
+		if u, err := user.Current(); err == nil {
+			username = u.Username
+		}
+
+		start := time.Now().Truncate(time.Hour)
+		if len(scheduleStart) > 0 {
+			var err error
+			if start, err = time.Parse(time.RFC3339, scheduleStart); err != nil {
+				fatal(fmt.Sprintf("Invalid start time %s, expecting RFC3339 e.g. 2020-01-02T15:04:05Z", scheduleStart), 1)
+			}
+		}
+
+		crontabs := []*lib.Crontab{}
+		if len(args) > 0 {
+			if isPathToDirectory(args[0]) {
+				crontabs = lib.ReadCrontabsInDirectory(username, args[0], crontabs)
+			} else {
+				crontabs = lib.ReadCrontabFromFile(username, args[0], crontabs)
+			}
+		} else {
+			crontabs = lib.ReadCrontabFromFile(username, "", crontabs)
+			crontabs = lib.ReadCrontabFromFile(username, lib.SYSTEM_CRONTAB, crontabs)
+			crontabs = lib.ReadCrontabsInDirectory(username, lib.DROP_IN_DIRECTORY, crontabs)
+		}
+
+		report := createScheduleReport(collectScheduledJobs(crontabs), start, start.Add(scheduleWindow), scheduleThreshold)
+
+		if scheduleFormat == "json" {
+			b, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Println(string(b))
+			return
+		}
+
+		printScheduleReport(report)
+	},
+}
+
+func collectScheduledJobs(crontabs []*lib.Crontab) []*ScheduledJob {
+	var jobs []*ScheduledJob
+	for _, crontab := range crontabs {
+		location := time.Local
+		if crontab.TimezoneLocationName != nil {
+			if crontabLocation, err := time.LoadLocation(crontab.TimezoneLocationName.Name); err == nil {
+				location = crontabLocation
+			}
+		}
+
+		for _, line := range crontab.Lines {
+			if len(line.CronExpression) == 0 || len(line.CommandToRun) == 0 {
+				continue
+			}
+
+			schedule, err := lib.ParseCronExpression(line.CronExpression)
+			if err != nil {
+				log(fmt.Sprintf("Skipping %s L%d: %s", crontab.DisplayName(), line.LineNumber, err.Error()))
+				continue
+			}
+
+			jobs = append(jobs, &ScheduledJob{
+				Key:        fmt.Sprintf("%s:%d", crontab.DisplayName(), line.LineNumber+1),
+				Filename:   crontab.DisplayName(),
+				LineNumber: line.LineNumber + 1,
+				Schedule:   line.CronExpression,
+				Command:    line.CommandToRun,
+				schedule:   schedule,
+				location:   location,
+			})
+		}
+	}
+
+	return jobs
+}
+
+func createScheduleReport(jobs []*ScheduledJob, start, end time.Time, threshold int) ScheduleReport {
+	report := ScheduleReport{Start: start, End: end, Threshold: threshold, JobCount: len(jobs), Jobs: jobs, Minutes: []*ScheduleMinute{}, Hotspots: []*ScheduleMinute{}, Suggestions: []*ScheduledJob{}}
+	if report.Jobs == nil {
+		report.Jobs = []*ScheduledJob{}
+	}
+	minutes := map[int64]*ScheduleMinute{}
+
+	// How many jobs start at each minute past the hour, used to find quiet minutes for suggestions
+	minuteOfHourLoad := make([]int, 60)
+
+	for _, job := range jobs {
+		startsAtMinute := map[int]bool{}
+		for _, run := range job.schedule.RunsBetween(start.In(job.location), end.In(job.location)) {
+			run = run.In(start.Location())
+			if _, ok := minutes[run.Unix()]; !ok {
+				minutes[run.Unix()] = &ScheduleMinute{Time: run}
+			}
+			minutes[run.Unix()].Count++
+			minutes[run.Unix()].Jobs = append(minutes[run.Unix()].Jobs, job)
+			minutes[run.Unix()].JobKeys = append(minutes[run.Unix()].JobKeys, job.Key)
+			startsAtMinute[run.Minute()] = true
+		}
+
+		for minute := range startsAtMinute {
+			minuteOfHourLoad[minute]++
+		}
+	}
+
+	for _, minute := range minutes {
+		report.Minutes = append(report.Minutes, minute)
+	}
+	sort.Slice(report.Minutes, func(i, j int) bool { return report.Minutes[i].Time.Before(report.Minutes[j].Time) })
+
+	suggested := map[*ScheduledJob]bool{}
+	for _, minute := range report.Minutes {
+		if minute.Count < threshold {
+			continue
+		}
+		report.Hotspots = append(report.Hotspots, minute)
+
+		// Leave the first job where it is and move the rest to the least busy minutes
+		for _, job := range minute.Jobs[1:] {
+			if suggested[job] {
+				continue
+			}
+
+			if suggestion, ok := suggestSchedule(job.Schedule, minuteOfHourLoad); ok {
+				job.SuggestedSchedule = suggestion
+				report.Suggestions = append(report.Suggestions, job)
+			}
+			suggested[job] = true
+		}
+	}
+
+	return report
+}
+
+// suggestSchedule moves a job that starts at a single fixed minute to the least busy minute past the hour
+func suggestSchedule(expression string, minuteOfHourLoad []int) (string, bool) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return "", false
+	}
+
+	current, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return "", false
+	}
+
+	quietest := current
+	for minute, load := range minuteOfHourLoad {
+		if load < minuteOfHourLoad[quietest] {
+			quietest = minute
+		}
+	}
+
+	if quietest == current {
+		return "", false
+	}
+
+	minuteOfHourLoad[current]--
+	minuteOfHourLoad[quietest]++
+	fields[0] = strconv.Itoa(quietest)
+	return strings.Join(fields, " "), true
+}
+
+func printScheduleReport(report ScheduleReport) {
+	counts := map[int64]int{}
+	maxCount := 0
+	for _, minute := range report.Minutes {
+		counts[minute.Time.Unix()] = minute.Count
+		if minute.Count > maxCount {
+			maxCount = minute.Count
+		}
+	}
+
+	printSuccessText(fmt.Sprintf("Job starts from %s to %s (%d jobs)", report.Start.Format(time.RFC3339), report.End.Format(time.RFC3339), report.JobCount), false)
+	fmt.Println()
+	fmt.Println("                  0         1         2         3         4         5")
+	fmt.Println("                  012345678901234567890123456789012345678901234567890123456789")
+
+	hot := color.New(color.FgHiRed)
+	for hour := report.Start.Truncate(time.Hour); hour.Before(report.End); hour = hour.Add(time.Hour) {
+		fmt.Print(hour.Format("Mon Jan 02 15:04 "))
+		for minute := 0; minute < 60; minute++ {
+			t := hour.Add(time.Duration(minute) * time.Minute)
+			count := counts[t.Unix()]
+			if t.Before(report.Start) || !t.Before(report.End) {
+				fmt.Print(" ")
+			} else if count >= report.Threshold {
+				hot.Print(heatmapCell(count))
+			} else {
+				fmt.Print(heatmapCell(count))
+			}
+		}
+		fmt.Println()
+	}
+
+	fmt.Println()
+	fmt.Println("Legend: . no jobs   1-9 jobs starting   + 10 or more")
+	fmt.Println()
+
+	if len(report.Hotspots) == 0 {
+		printDoneText(fmt.Sprintf("No minute has %d or more jobs starting together", report.Threshold), false)
+		return
+	}
+
+	printWarningText(fmt.Sprintf("%d minutes with %d or more jobs starting together, busiest has %d", len(report.Hotspots), report.Threshold, maxCount), false)
+	for _, hotspot := range report.Hotspots {
+		printWarningText(fmt.Sprintf("%s  %d jobs", hotspot.Time.Format("Mon Jan 02 15:04"), hotspot.Count), true)
+	}
+
+	if len(report.Suggestions) > 0 {
+		fmt.Println()
+		printSuccessText("Suggested schedules:", false)
+		for _, job := range report.Suggestions {
+			fmt.Println(fmt.Sprintf("    %s:%d  %s  ->  %s  %s", job.Filename, job.LineNumber, job.Schedule, job.SuggestedSchedule, job.Command))
+		}
+	}
+}
+
+func heatmapCell(count int) string {
+	if count == 0 {
+		return "."
+	} else if count > 9 {
+		return "+"
+	}
+
+	return strconv.Itoa(count)
+}
+
+func init() {
+	RootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleReportCmd)
+	scheduleReportCmd.Flags().DurationVar(&scheduleWindow, "window", 24*time.Hour, "Length of the time window to analyze, e.g. 24h or 168h")
+	scheduleReportCmd.Flags().StringVar(&scheduleStart, "start", scheduleStart, "Start of the time window in RFC3339 format (default: the start of the current hour)")
+	scheduleReportCmd.Flags().IntVar(&scheduleThreshold, "threshold", 3, "Number of jobs starting in the same minute that makes it a hotspot")
+	scheduleReportCmd.Flags().StringVar(&scheduleFormat, "format", "text", "Output format. Accepted values: text, json")
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"cronitor/lib"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCreateScheduleReport(t *testing.T) {
+	var jobs []*ScheduledJob
+	for _, expression := range []string{"0 * * * *", "0 * * * *", "0 * * * *", "*/30 * * * *", "15 3 * * *"} {
+		schedule, _ := lib.ParseCronExpression(expression)
+		jobs = append(jobs, &ScheduledJob{Schedule: expression, schedule: schedule, location: time.UTC})
+	}
+
+	start, _ := time.Parse(time.RFC3339, "2020-03-02T00:00:00Z")
+	report := createScheduleReport(jobs, start, start.Add(2*time.Hour), 3)
+
+	if len(report.Hotspots) != 2 || report.Hotspots[0].Count != 4 {
+		t.Fatalf("Expected two hotspots with 4 jobs at the top of each hour, got: %+v", report.Hotspots)
+	}
+
+	// The first job stays put, the other two fixed-minute jobs move and */30 cannot be moved
+	if len(report.Suggestions) != 2 {
+		t.Fatalf("Expected 2 suggestions, got %d", len(report.Suggestions))
+	}
+
+	for _, job := range report.Suggestions {
+		if job.SuggestedSchedule == "" || job.SuggestedSchedule[:2] == "0 " {
+			t.Errorf("Expected job to be moved off minute 0, got: %s", job.SuggestedSchedule)
+		}
+	}
+
+	if report.Suggestions[0].SuggestedSchedule == report.Suggestions[1].SuggestedSchedule {
+		t.Error("Expected colliding jobs to be spread to different minutes")
+	}
+}
+
+func TestScheduleReportJson(t *testing.T) {
+	schedule, _ := lib.ParseCronExpression("*/30 * * * *")
+	jobs := []*ScheduledJob{{Key: "/etc/crontab:4", Filename: "/etc/crontab", LineNumber: 4, Schedule: "*/30 * * * *", schedule: schedule, location: time.UTC}}
+
+	start, _ := time.Parse(time.RFC3339, "2020-03-02T00:00:00Z")
+	b, _ := json.Marshal(createScheduleReport(jobs, start, start.Add(time.Hour), 3))
+
+	var report struct {
+		Threshold int
+		Jobs      []map[string]interface{}
+		Minutes   []struct{ Jobs []string }
+	}
+	json.Unmarshal(b, &report)
+
+	if report.Threshold != 3 || len(report.Jobs) != 1 || report.Jobs[0]["key"] != "/etc/crontab:4" {
+		t.Fatalf("Expected the threshold and each job once, got %s", b)
+	}
+
+	if len(report.Minutes) != 2 || len(report.Minutes[0].Jobs) != 1 || report.Minutes[0].Jobs[0] != "/etc/crontab:4" {
+		t.Errorf("Expected each minute to list job keys, got %s", b)
+	}
+}
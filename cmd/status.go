@@ -1,29 +1,26 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"cronitor/lib/api"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"os"
 )
 
-type StatusMonitor struct {
-	Name    string `json:"name"`
-	Code    string `json:"code"`
-	Passing bool   `json:"passing"`
-	Status  string `json:"status"`
-}
-
-type StatusMonitors struct {
-	Monitors []StatusMonitor `json:"monitors"`
-}
+var statusSelection monitorSelection
+var statusCheck bool
+var statusThresholds checkThresholds
+var statusWatch bool
+var statusInterval time.Duration
 
 var statusCmd = &cobra.Command{
-	Use:   "status",
+	Use:   "status [code...]",
 	Short: "View monitor status",
 	Long: `
 View monitor status
@@ -34,67 +31,177 @@ Examples:
 
   View status of a single monitor:
   $ cronitor status d3x0c1
+
+  View status of monitors tagged "database" whose name starts with "web1 ":
+  $ cronitor status --tag database --name "web1 *"
+
+  Check monitor status from Nagios or Icinga:
+  $ cronitor status --check --tag production --warning 1 --critical 3
+      > Prints a single line like "CRONITOR WARNING - 12 monitors, 1 failing, 0 paused: Nightly backup | failing=1;0;2;0;12 ..."
+      > Exits 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN, e.g. the API cannot be reached or no monitors matched)
+      > Paused monitors are not counted as failing; use --paused-warning to warn about them
+
+  Keep the status on screen, refreshing every minute and highlighting monitors that changed state:
+  $ cronitor status --watch --interval 1m
+      > For a full-screen view with recent activity and keys to pause or resume monitors, use 'cronitor dashboard'
 `,
 
 	Args: func(cmd *cobra.Command, args []string) error {
+		// A check must exit with a Nagios state, so problems that would otherwise exit 1 are reported as UNKNOWN
+		if statusCheck {
+			if profileErr != nil {
+				checkUnknown(profileErr.Error())
+			}
+
+			if _, err := loadApiHttpClient(); err != nil {
+				checkUnknown(err.Error())
+			}
+		}
+
 		if len(viper.GetString(varApiKey)) < 10 {
+			if statusCheck {
+				checkUnknown("no API key; provide one with --api-key or save a key using 'cronitor configure'")
+			}
 			return errors.New("you must provide an API key with this command or save a key using 'cronitor configure'")
 		}
 
+		if statusWatch && statusCheck {
+			return errors.New("--watch cannot be used with --check")
+		}
+
+		if statusWatch && statusInterval < minimumWatchInterval {
+			return errors.New(fmt.Sprintf("--interval must be at least %s", minimumWatchInterval))
+		}
+
 		return nil
 	},
 
 	Run: func(cmd *cobra.Command, args []string) {
-		url := getCronitorApi().Url()
-		if len(args) > 0 {
-			url = url + "/" + args[0]
+		if statusWatch {
+			watchStatus(args)
+			return
+		}
+
+		monitors, err := selectMonitorsOrAll(args, statusSelection)
+
+		if statusCheck {
+			if err != nil {
+				checkUnknown(err.Error())
+			}
+
+			state, output := evaluateStatusCheck(monitors, statusThresholds)
+			fmt.Println(output)
+			os.Exit(state)
 		}
 
-		// @todo refactor this to use GetMonitors
-		response, err := getCronitorApi().GetRawResponse(url)
 		if err != nil {
-			fatal(fmt.Sprintf("Request to %s failed: %s", url, err), 1)
+			fatal(err.Error(), 1)
 		}
 
-		buf := new(bytes.Buffer)
-		json.Indent(buf, response, "", "  ")
-		log("\nResponse:")
-		log(buf.String() + "\n")
-
-		// Unmarshall the API response into the StatusMonitor struct.
-		// If this is a detail response (with a monitor code argument) we need to unmarshal it directly into a StatusMonitor
-		responseMonitors := StatusMonitors{}
-		if len(args) == 0 {
-			if err = json.Unmarshal(response, &responseMonitors); err != nil {
-				fatal(fmt.Sprintf("Error %s from %s: %s", err.Error(), url, response), 1)
-			}
-		} else {
-			singleMonitor := StatusMonitor{}
-			if err = json.Unmarshal(response, &singleMonitor); err != nil {
-				fatal(fmt.Sprintf("Error %s from %s: %s", err.Error(), url, response), 1)
-			}
+		renderStatusTable(os.Stdout, monitors, nil)
+	},
+}
 
-			responseMonitors.Monitors = []StatusMonitor{singleMonitor}
+const minimumWatchInterval = 5 * time.Second
+
+// watchStatus polls the API until interrupted, redrawing the status table after each poll
+// and highlighting monitors whose state differs from the previous poll.
+func watchStatus(args []string) {
+	var previous map[string]string
+	var monitors []api.Monitor
+
+	for {
+		current, err := selectMonitorsOrAll(args, statusSelection)
+		if apiContext().Err() != nil {
+			return
+		}
+		if err == nil {
+			monitors = current
 		}
 
-		fmt.Println(url)
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Health", "Name", "Code", "Status"})
-		table.SetAutoWrapText(false)
-		table.SetHeaderAlignment(3)
+		states := monitorStates(monitors)
+		changed := changedMonitors(previous, states)
 
-		for _, v := range responseMonitors.Monitors {
-			state := "Ok"
-			if !v.Passing {
-				state = "Failing"
-			}
-			table.Append([]string{state, v.Name, v.Code, v.Status})
+		fmt.Print("\033[H\033[2J")
+		fmt.Printf("Every %s: cronitor status    %s    (Ctrl-C to exit)\n\n", statusInterval, time.Now().Format("2006-01-02 15:04:05"))
+		if err != nil {
+			printErrorText("Could not refresh status: "+err.Error(), false)
+			printLn()
 		}
 
-		table.Render()
-	},
+		renderStatusTable(os.Stdout, monitors, changed)
+		if len(changed) > 0 {
+			printWarningText(fmt.Sprintf("%d monitor(s) changed state since the last update", len(changed)), false)
+		}
+
+		if err == nil {
+			previous = states
+		}
+		if !waitForNextPoll(statusInterval) {
+			return
+		}
+	}
+}
+
+// renderStatusTable writes the status table. Monitors whose code is in changed are shown in bold
+// yellow with their previous state.
+func renderStatusTable(w io.Writer, monitors []api.Monitor, changed map[string]string) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Health", "Name", "Code", "Status"})
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(3)
+
+	highlight := tablewriter.Colors{tablewriter.Bold, tablewriter.FgYellowColor}
+	for _, v := range monitors {
+		state := monitorState(v)
+		if was, ok := changed[v.Code]; ok {
+			state = fmt.Sprintf("%s (was %s)", state, was)
+			table.Rich([]string{state, v.Name, v.Code, v.Status}, []tablewriter.Colors{highlight, highlight, highlight, highlight})
+			continue
+		}
+		table.Append([]string{state, v.Name, v.Code, v.Status})
+	}
+
+	table.Render()
+}
+
+func monitorState(monitor api.Monitor) string {
+	if monitor.Paused {
+		return "Paused"
+	} else if !monitor.Passing {
+		return "Failing"
+	}
+	return "Ok"
+}
+
+// monitorStates maps monitor codes to their health as shown in the status table
+func monitorStates(monitors []api.Monitor) map[string]string {
+	states := map[string]string{}
+	for _, monitor := range monitors {
+		states[monitor.Code] = monitorState(monitor)
+	}
+	return states
+}
+
+// changedMonitors returns the previous state of every monitor whose state differs between polls.
+// Nothing is reported for the first poll or for monitors that were not in the previous poll.
+func changedMonitors(previous, current map[string]string) map[string]string {
+	changed := map[string]string{}
+	for code, state := range current {
+		if was, ok := previous[code]; ok && was != state {
+			changed[code] = was
+		}
+	}
+	return changed
 }
 
 func init() {
 	RootCmd.AddCommand(statusCmd)
+	addMonitorSelectionFlags(statusCmd, &statusSelection)
+	statusCmd.Flags().BoolVar(&statusCheck, "check", false, "Print a Nagios plugin result and exit 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN)")
+	statusCmd.Flags().IntVar(&statusThresholds.Warning, "warning", 0, "With --check, WARNING when at least this many monitors are failing")
+	statusCmd.Flags().IntVar(&statusThresholds.Critical, "critical", 1, "With --check, CRITICAL when at least this many monitors are failing")
+	statusCmd.Flags().BoolVar(&statusWatch, "watch", false, "Refresh the status until interrupted, highlighting monitors that changed state")
+	statusCmd.Flags().DurationVar(&statusInterval, "interval", 30*time.Second, "With --watch, how often to refresh")
+	statusCmd.Flags().IntVar(&statusThresholds.PausedWarning, "paused-warning", 0, "With --check, WARNING when at least this many monitors are paused")
 }
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"cronitor/lib/api"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Nagios plugin exit codes
+const CHECK_OK = 0
+const CHECK_WARNING = 1
+const CHECK_CRITICAL = 2
+const CHECK_UNKNOWN = 3
+
+var checkStateNames = map[int]string{
+	CHECK_OK:       "OK",
+	CHECK_WARNING:  "WARNING",
+	CHECK_CRITICAL: "CRITICAL",
+	CHECK_UNKNOWN:  "UNKNOWN",
+}
+
+// The most failing monitors named in the check summary before it is shortened
+const checkMaxNames = 5
+
+// checkThresholds are the number of failing or paused monitors that change the check state. A threshold of 0 is
+// never reached.
+type checkThresholds struct {
+	Warning       int
+	Critical      int
+	PausedWarning int
+}
+
+// evaluateStatusCheck returns the Nagios exit code and single-line output, with perfdata, for the selected monitors.
+// A paused monitor is counted as paused even if it is failing.
+func evaluateStatusCheck(monitors []api.Monitor, thresholds checkThresholds) (int, string) {
+	if len(monitors) == 0 {
+		return CHECK_UNKNOWN, "CRONITOR UNKNOWN - No monitors matched"
+	}
+
+	var failing []string
+	paused := 0
+	for _, monitor := range monitors {
+		if monitor.Paused {
+			paused++
+		} else if !monitor.Passing {
+			name := monitor.Name
+			if len(name) == 0 {
+				name = monitor.Code
+			}
+			// Everything after a | in plugin output is read as perfdata
+			failing = append(failing, strings.Replace(name, "|", "/", -1))
+		}
+	}
+
+	state := CHECK_OK
+	if reachesThreshold(len(failing), thresholds.Critical) {
+		state = CHECK_CRITICAL
+	} else if reachesThreshold(len(failing), thresholds.Warning) || reachesThreshold(paused, thresholds.PausedWarning) {
+		state = CHECK_WARNING
+	}
+
+	summary := fmt.Sprintf("%d monitors, %d failing, %d paused", len(monitors), len(failing), paused)
+	if len(failing) > 0 {
+		names := failing
+		if len(names) > checkMaxNames {
+			names = append(names[:checkMaxNames:checkMaxNames], fmt.Sprintf("and %d more", len(failing)-checkMaxNames))
+		}
+		summary = fmt.Sprintf("%s: %s", summary, strings.Join(names, ", "))
+	}
+
+	perfdata := strings.Join([]string{
+		fmt.Sprintf("monitors=%d;;;0", len(monitors)),
+		fmt.Sprintf("failing=%d;%s;%s;0;%d", len(failing), formatThreshold(thresholds.Warning), formatThreshold(thresholds.Critical), len(monitors)),
+		fmt.Sprintf("paused=%d;%s;;0;%d", paused, formatThreshold(thresholds.PausedWarning), len(monitors)),
+	}, " ")
+
+	return state, fmt.Sprintf("CRONITOR %s - %s | %s", checkStateNames[state], summary, perfdata)
+}
+
+func reachesThreshold(count int, threshold int) bool {
+	return threshold > 0 && count >= threshold
+}
+
+// formatThreshold writes a threshold as a Nagios range, which alerts when the value is outside 0 to threshold-1
+func formatThreshold(threshold int) string {
+	if threshold <= 0 {
+		return ""
+	}
+
+	return fmt.Sprint(threshold - 1)
+}
+
+// checkUnknown prints an UNKNOWN check result for a problem that prevented the check and exits
+func checkUnknown(message string) {
+	fmt.Println(fmt.Sprintf("CRONITOR UNKNOWN - %s", strings.Replace(message, "\n", " ", -1)))
+	os.Exit(CHECK_UNKNOWN)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"cronitor/lib/api"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateStatusCheck(t *testing.T) {
+	monitors := []api.Monitor{
+		{Code: "c1", Name: "Nightly backup", Passing: false},
+		{Code: "c2", Name: "Cleanup", Passing: true},
+		{Code: "c3", Name: "Reports", Passing: false, Paused: true},
+		{Code: "c4", Passing: false},
+	}
+
+	tests := []struct {
+		thresholds checkThresholds
+		state      int
+	}{
+		{checkThresholds{Critical: 1}, CHECK_CRITICAL},
+		{checkThresholds{Warning: 1, Critical: 3}, CHECK_WARNING},
+		{checkThresholds{Critical: 3}, CHECK_OK},
+		{checkThresholds{Critical: 3, PausedWarning: 1}, CHECK_WARNING},
+	}
+
+	for _, test := range tests {
+		if state, output := evaluateStatusCheck(monitors, test.thresholds); state != test.state {
+			t.Errorf("Thresholds %+v: expected state %d, got %d: %s", test.thresholds, test.state, state, output)
+		}
+	}
+
+	_, output := evaluateStatusCheck(monitors, checkThresholds{Warning: 1, Critical: 3})
+	expected := "CRONITOR WARNING - 4 monitors, 2 failing, 1 paused: Nightly backup, c4 | monitors=4;;;0 failing=2;0;2;0;4 paused=1;;;0;4"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	if state, output := evaluateStatusCheck(nil, checkThresholds{Critical: 1}); state != CHECK_UNKNOWN || !strings.HasPrefix(output, "CRONITOR UNKNOWN") {
+		t.Errorf("Expected UNKNOWN when no monitors matched, got %d %s", state, output)
+	}
+}
+
+func TestEvaluateStatusCheckShortensNames(t *testing.T) {
+	var monitors []api.Monitor
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		monitors = append(monitors, api.Monitor{Name: name})
+	}
+
+	_, output := evaluateStatusCheck(monitors, checkThresholds{Critical: 1})
+	if !strings.Contains(output, "paused: a, b, c, d, e, and 2 more |") {
+		t.Errorf("Expected the failing names to be shortened, got %s", output)
+	}
+}
+
+func TestEvaluateStatusCheckEscapesNames(t *testing.T) {
+	_, output := evaluateStatusCheck([]api.Monitor{{Name: "backup | primary"}}, checkThresholds{Critical: 1})
+	if strings.Count(output, "|") != 1 || !strings.Contains(output, "backup / primary") {
+		t.Errorf("Expected | in names to be replaced so perfdata can be read, got %s", output)
+	}
+}
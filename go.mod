@@ -4,12 +4,16 @@ go 1.14
 
 require (
 	github.com/certifi/gocertifi v0.0.0-20200211180108-c7c1fbc02894 // indirect
+	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e
 	github.com/equinox-io/equinox v1.2.0
 	github.com/fatih/color v1.9.0
 	github.com/getsentry/raven-go v0.2.0
 	github.com/kballard/go-shellquote v0.0.0-20180428030007-95032a82bc51
 	github.com/manifoldco/promptui v0.7.0
 	github.com/olekukonko/tablewriter v0.0.4
+	github.com/spf13/cast v1.3.0
 	github.com/spf13/cobra v0.0.6
+	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.6.2
+	gopkg.in/yaml.v2 v2.2.4
 )
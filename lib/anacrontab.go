@@ -0,0 +1,289 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const ANACRONTAB = "/etc/anacrontab"
+
+var anacronEnvironmentRegex = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=(.*)$`)
+
+// Named periods accepted by anacron in place of a number of days
+var anacronPeriodKeywords = map[string]int{
+	"@daily":   1,
+	"@weekly":  7,
+	"@monthly": 31,
+}
+
+type Anacrontab struct {
+	Filename             string
+	Lines                []*AnacronJob
+	Environment          map[string]string
+	TimezoneLocationName *TimezoneLocationName
+}
+
+type AnacronJob struct {
+	Name         string
+	FullLine     string
+	LineNumber   int
+	Period       string
+	PeriodDays   int
+	DelayMinutes int
+	Identifier   string
+	CommandToRun string
+	Code         string
+	Mon          Monitor
+}
+
+func (a *Anacrontab) Parse() error {
+	lines, err := a.load()
+	if err != nil {
+		return err
+	}
+
+	if len(a.Lines) > 0 {
+		panic("Cannot read into non-empty anacrontab struct")
+	}
+
+	a.parseLines(lines)
+	return nil
+}
+
+func (a *Anacrontab) parseLines(lines []string) {
+	a.Environment = map[string]string{}
+
+	for lineNumber, fullLine := range lines {
+		fullLine = strings.TrimSpace(fullLine)
+		job := AnacronJob{
+			FullLine:   fullLine,
+			LineNumber: lineNumber,
+		}
+
+		// Comments and blank lines are kept as-is so the file can be re-created later
+		if fullLine == "" || strings.HasPrefix(fullLine, "#") {
+			a.Lines = append(a.Lines, &job)
+			continue
+		}
+
+		// Environment assignments, e.g. START_HOURS_RANGE=3-22. Anacron allows whitespace around the "=".
+		if ret := anacronEnvironmentRegex.FindStringSubmatch(fullLine); ret != nil {
+			name := ret[1]
+			value := strings.Trim(strings.TrimSpace(ret[2]), "\"'")
+			a.Environment[name] = value
+			if name == "TZ" || name == "CRON_TZ" {
+				a.TimezoneLocationName = &TimezoneLocationName{value}
+			}
+
+			a.Lines = append(a.Lines, &job)
+			continue
+		}
+
+		// Job lines look like: period delay job-identifier command
+		splitLine := strings.Fields(fullLine)
+		if len(splitLine) < 4 {
+			a.Lines = append(a.Lines, &job)
+			continue
+		}
+
+		periodDays, ok := parseAnacronPeriod(splitLine[0])
+		delay, err := strconv.Atoi(splitLine[1])
+		if !ok || err != nil || delay < 0 {
+			a.Lines = append(a.Lines, &job)
+			continue
+		}
+
+		command := splitLine[3:]
+
+		// If this job is already being wrapped by the Cronitor client, read current code.
+		if len(command) > 2 && strings.HasSuffix(command[0], "cronitor") && command[1] == "exec" {
+			job.Code = command[2]
+			command = command[3:]
+		} else if len(command) > 3 && strings.HasSuffix(command[0], "cronitor") && command[1] == "--no-stdout" && command[2] == "exec" {
+			job.Code = command[3]
+			command = command[4:]
+		}
+
+		job.Period = splitLine[0]
+		job.PeriodDays = periodDays
+		job.DelayMinutes = delay
+		job.Identifier = splitLine[2]
+		job.CommandToRun = strings.Join(command, " ")
+		a.Lines = append(a.Lines, &job)
+	}
+}
+
+// RandomDelayMinutes returns the RANDOM_DELAY anacron adds on top of each job delay
+func (a Anacrontab) RandomDelayMinutes() int {
+	if value, ok := a.Environment["RANDOM_DELAY"]; ok {
+		if minutes, err := strconv.Atoi(value); err == nil && minutes > 0 {
+			return minutes
+		}
+	}
+
+	return 0
+}
+
+func (a Anacrontab) Write() string {
+	var cl []string
+	for _, job := range a.Lines {
+		cl = append(cl, job.Write())
+	}
+
+	return strings.Join(cl, "\n")
+}
+
+func (a Anacrontab) Save(anacrontabLines string) error {
+	if anacrontabLines == "" {
+		return errors.New("cannot save anacrontab, file is empty")
+	}
+
+	if ioutil.WriteFile(a.Filename, []byte(anacrontabLines), 0644) != nil {
+		return errors.New(fmt.Sprintf("cannot write anacrontab at %s; check permissions and try again", a.Filename))
+	}
+
+	return nil
+}
+
+func (a Anacrontab) DisplayName() string {
+	return a.Filename
+}
+
+func (a Anacrontab) CanonicalName() string {
+	if absolutePath, err := filepath.Abs(a.Filename); err == nil {
+		return absolutePath
+	}
+
+	return a.DisplayName()
+}
+
+func (a Anacrontab) IsWritable() bool {
+	file, err := os.OpenFile(a.Filename, os.O_WRONLY, 0666)
+	defer file.Close()
+	if err != nil {
+		return false
+	}
+	return true
+}
+
+func (a Anacrontab) Exists() bool {
+	if _, err := os.Stat(a.Filename); os.IsNotExist(err) {
+		return false
+	}
+
+	return true
+}
+
+func (a Anacrontab) load() ([]string, error) {
+	if _, err := os.Stat(a.Filename); os.IsNotExist(err) {
+		return nil, errors.New(fmt.Sprintf("the file %s does not exist", a.Filename))
+	}
+
+	b, err := ioutil.ReadFile(a.Filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("the anacrontab file at %s could not be read; check permissions and try again", a.Filename))
+	}
+
+	if len(b) == 0 {
+		return nil, errors.New("the anacrontab file is empty")
+	}
+
+	return strings.Split(string(b), "\n"), nil
+}
+
+func (j AnacronJob) IsJob() bool {
+	return len(j.Identifier) > 0 && len(j.CommandToRun) > 0
+}
+
+func (j AnacronJob) IsMonitorable() bool {
+	return j.IsJob() && !j.HasLegacyIntegration()
+}
+
+func (j AnacronJob) HasLegacyIntegration() bool {
+	return strings.Contains(j.CommandToRun, "cronitor.io") || strings.Contains(j.CommandToRun, "cronitor.link")
+}
+
+func (j AnacronJob) CommandIsComplex() bool {
+	return strings.Contains(j.CommandToRun, ";") || strings.Contains(j.CommandToRun, "|") || strings.Contains(j.CommandToRun, "&&") || strings.Contains(j.CommandToRun, "||")
+}
+
+// Schedule returns a human readable description of when anacron runs the job
+func (j AnacronJob) Schedule() string {
+	label := "days"
+	if j.PeriodDays == 1 {
+		label = "day"
+	}
+
+	schedule := fmt.Sprintf("every %d %s", j.PeriodDays, label)
+	if strings.HasPrefix(j.Period, "@") {
+		schedule = j.Period
+	}
+
+	if j.DelayMinutes > 0 {
+		schedule = fmt.Sprintf("%s, delay %dm", schedule, j.DelayMinutes)
+	}
+
+	return schedule
+}
+
+func (j AnacronJob) Write() string {
+	if !j.IsMonitorable() || len(j.Code) > 0 || len(j.Mon.Code) == 0 {
+		// If a cronitor integration already existed on the line we have nothing else here to change
+		return j.FullLine
+	}
+
+	var lineParts []string
+	lineParts = append(lineParts, j.Period, strconv.Itoa(j.DelayMinutes), j.Identifier, "cronitor")
+	if j.Mon.NoStdoutPassthru {
+		lineParts = append(lineParts, "--no-stdout")
+	}
+	lineParts = append(lineParts, "exec", j.Mon.Code)
+
+	if j.CommandIsComplex() {
+		lineParts = append(lineParts, "\""+strings.Replace(j.CommandToRun, "\"", "\\\"", -1)+"\"")
+	} else {
+		lineParts = append(lineParts, j.CommandToRun)
+	}
+
+	return strings.Join(lineParts, " ")
+}
+
+func parseAnacronPeriod(period string) (int, bool) {
+	if days, ok := anacronPeriodKeywords[period]; ok {
+		return days, true
+	}
+
+	if days, err := strconv.Atoi(period); err == nil && days > 0 {
+		return days, true
+	}
+
+	return 0, false
+}
+
+// IsAnacrontabFile reports whether a path should be read as an anacrontab rather than a crontab
+func IsAnacrontabFile(filename string) bool {
+	return strings.HasPrefix(filepath.Base(filename), "anacrontab")
+}
+
+func AnacrontabFactory(filename string) *Anacrontab {
+	return &Anacrontab{
+		Filename: filename,
+	}
+}
+
+func ReadAnacrontabFromFile(filename string, anacrontabs []*Anacrontab) []*Anacrontab {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return anacrontabs
+	}
+
+	anacrontab := AnacrontabFactory(filename)
+	anacrontab.Parse()
+	anacrontabs = append(anacrontabs, anacrontab)
+	return anacrontabs
+}
@@ -0,0 +1,73 @@
+package lib
+
+import "testing"
+
+func TestAnacrontabParse(t *testing.T) {
+	anacrontab := AnacrontabFactory("../fixtures/anacrontab")
+	if err := anacrontab.Parse(); err != nil {
+		t.Fatalf("Unexpected error parsing anacrontab: %s", err)
+	}
+
+	if anacrontab.RandomDelayMinutes() != 45 {
+		t.Errorf("Expected RANDOM_DELAY of 45, got %d", anacrontab.RandomDelayMinutes())
+	}
+
+	var jobs []*AnacronJob
+	for _, job := range anacrontab.Lines {
+		if job.IsJob() {
+			jobs = append(jobs, job)
+		}
+	}
+
+	tables := []struct {
+		identifier   string
+		periodDays   int
+		delayMinutes int
+		command      string
+		schedule     string
+	}{
+		{"cron.daily", 1, 5, "run-parts --report /etc/cron.daily", "every 1 day, delay 5m"},
+		{"cron.weekly", 7, 10, "run-parts --report /etc/cron.weekly", "every 7 days, delay 10m"},
+		{"cron.monthly", 31, 15, "run-parts --report /etc/cron.monthly", "@monthly, delay 15m"},
+		{"backup.db", 3, 0, "/usr/local/bin/backup.sh && echo \"done\"", "every 3 days"},
+	}
+
+	if len(jobs) != len(tables) {
+		t.Fatalf("Expected %d jobs, got %d", len(tables), len(jobs))
+	}
+
+	for i, table := range tables {
+		job := jobs[i]
+		if job.Identifier != table.identifier || job.PeriodDays != table.periodDays || job.DelayMinutes != table.delayMinutes || job.CommandToRun != table.command {
+			t.Errorf("Job %d parsed incorrectly, got: %+v", i, job)
+		}
+
+		if job.Schedule() != table.schedule {
+			t.Errorf("Job %d schedule incorrect, got: %s, expected: %s", i, job.Schedule(), table.schedule)
+		}
+	}
+}
+
+func TestAnacronJobWrite(t *testing.T) {
+	tables := []struct {
+		caseName string
+		fullLine string
+		code     string
+		expected string
+	}{
+		{"unmonitored job is unchanged", "1\t5\tcron.daily\trun-parts /etc/cron.daily", "", "1\t5\tcron.daily\trun-parts /etc/cron.daily"},
+		{"monitored job is wrapped", "1\t5\tcron.daily\trun-parts /etc/cron.daily", "d3x0c1", "1 5 cron.daily cronitor exec d3x0c1 run-parts /etc/cron.daily"},
+		{"complex command is quoted", "@weekly 0 backup a.sh && b.sh", "d3x0c1", "@weekly 0 backup cronitor exec d3x0c1 \"a.sh && b.sh\""},
+		{"existing integration is unchanged", "1 5 cron.daily cronitor exec abc123 run-parts /etc/cron.daily", "d3x0c1", "1 5 cron.daily cronitor exec abc123 run-parts /etc/cron.daily"},
+	}
+
+	for _, table := range tables {
+		anacrontab := Anacrontab{}
+		anacrontab.parseLines([]string{table.fullLine})
+		job := anacrontab.Lines[0]
+		job.Mon.Code = table.code
+		if written := job.Write(); written != table.expected {
+			t.Errorf("Test case '%s' failed, got: %s, expected: %s", table.caseName, written, table.expected)
+		}
+	}
+}
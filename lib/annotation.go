@@ -0,0 +1,142 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+)
+
+var annotationRegex = regexp.MustCompile(`^#\s*cronitor:\s*(.*)$`)
+var crontabDefaultsRegex = regexp.MustCompile(`^#\s*cronitor-defaults:\s*(.*)$`)
+
+// Annotation holds monitor settings from a "# cronitor: ..." comment on the line above a job, e.g.
+//
+//	# cronitor: key=nightly-billing name="Nightly billing" tags=billing,prod grace=10m rule="run_time_exceeds 30 minutes" notify=oncall
+type Annotation struct {
+	Key          string
+	Name         string
+	Tags         []string
+	GraceSeconds uint
+	HasGrace     bool
+	Rules        []Rule
+	Notify       []string
+	Skip         bool
+}
+
+// IsAnnotation reports whether a crontab line is a cronitor annotation comment
+func IsAnnotation(line string) bool {
+	return annotationRegex.MatchString(strings.TrimSpace(line))
+}
+
+// IsCrontabDefaults reports whether a crontab line is a "# cronitor-defaults: ..." comment, whose settings apply to
+// every job in the crontab
+func IsCrontabDefaults(line string) bool {
+	return crontabDefaultsRegex.MatchString(strings.TrimSpace(line))
+}
+
+// ParseAnnotation reads settings from an annotation comment. Settings that could be read are returned along with
+// an error describing any that could not.
+func ParseAnnotation(line string) (*Annotation, error) {
+	ret := annotationRegex.FindStringSubmatch(strings.TrimSpace(line))
+	if ret == nil {
+		return nil, errors.New("not a cronitor annotation")
+	}
+
+	return parseAnnotationSettings(ret[1])
+}
+
+// ParseCrontabDefaults reads settings from a crontab defaults comment. Only grace, rule, tags and notify are allowed.
+func ParseCrontabDefaults(line string) (*Annotation, error) {
+	ret := crontabDefaultsRegex.FindStringSubmatch(strings.TrimSpace(line))
+	if ret == nil {
+		return nil, errors.New("not a cronitor-defaults comment")
+	}
+
+	defaults, err := parseAnnotationSettings(ret[1])
+	if defaults != nil && (len(defaults.Key) > 0 || len(defaults.Name) > 0 || defaults.Skip) {
+		defaults.Key, defaults.Name, defaults.Skip = "", "", false
+		err = errors.New("invalid cronitor-defaults: key, name and skip can only be set for a single job")
+	}
+
+	return defaults, err
+}
+
+func parseAnnotationSettings(settings string) (*Annotation, error) {
+	words, err := shellquote.Split(settings)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("cannot read annotation: %s", err.Error()))
+	}
+
+	annotation := Annotation{}
+	var problems []string
+	for _, word := range words {
+		key, value := word, ""
+		if equalsPosition := strings.Index(word, "="); equalsPosition > 0 {
+			key, value = word[:equalsPosition], word[equalsPosition+1:]
+		}
+
+		switch key {
+		case "key":
+			annotation.Key = strings.TrimSpace(value)
+		case "name":
+			annotation.Name = strings.TrimSpace(value)
+		case "tags", "tag":
+			annotation.Tags = append(annotation.Tags, splitAnnotationList(value)...)
+		case "notify":
+			annotation.Notify = append(annotation.Notify, splitAnnotationList(value)...)
+		case "grace":
+			if grace, err := ParseGraceSeconds(value); err == nil {
+				annotation.GraceSeconds = grace
+				annotation.HasGrace = true
+			} else {
+				problems = append(problems, err.Error())
+			}
+		case "rule":
+			if rule, err := ParseRule(value); err == nil {
+				annotation.Rules = MergeRules(annotation.Rules, []Rule{rule})
+			} else {
+				problems = append(problems, err.Error())
+			}
+		case "skip":
+			annotation.Skip = value == "" || value == "true"
+		default:
+			problems = append(problems, fmt.Sprintf("unknown setting \"%s\"", key))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &annotation, errors.New(fmt.Sprintf("invalid annotation: %s", strings.Join(problems, ", ")))
+	}
+
+	return &annotation, nil
+}
+
+func splitAnnotationList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// ParseGraceSeconds accepts a duration like "10m" or a plain number of seconds
+func ParseGraceSeconds(value string) (uint, error) {
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return uint(seconds), nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, errors.New(fmt.Sprintf("invalid duration \"%s\"", value))
+	}
+
+	return uint(duration / time.Second), nil
+}
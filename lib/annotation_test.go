@@ -0,0 +1,58 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnnotation(t *testing.T) {
+	annotation, err := ParseAnnotation(`# cronitor: name="Nightly billing" tags=billing,prod grace=10m notify=oncall skip`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := Annotation{Name: "Nightly billing", Tags: []string{"billing", "prod"}, GraceSeconds: 600, HasGrace: true, Notify: []string{"oncall"}, Skip: true}
+	if !reflect.DeepEqual(*annotation, expected) {
+		t.Errorf("Annotation parsed incorrectly, got: %+v", annotation)
+	}
+
+	if annotation, _ := ParseAnnotation("#cronitor: grace=90"); annotation.GraceSeconds != 90 {
+		t.Errorf("Expected grace in seconds, got: %d", annotation.GraceSeconds)
+	}
+
+	if _, err := ParseAnnotation("# cronitor: grace=soon"); err == nil {
+		t.Error("Expected error for invalid grace period")
+	}
+
+	if IsAnnotation("# just a comment about cronitor") {
+		t.Error("Ordinary comments should not be annotations")
+	}
+}
+
+func TestCrontabParseAnnotations(t *testing.T) {
+	crontab := CrontabFactory("", "../fixtures/crontab-with-annotations")
+	crontab.Parse(true)
+
+	annotations := map[string]*Line{}
+	for _, line := range crontab.Lines {
+		if line.CronExpression != "" {
+			annotations[line.CommandToRun] = line
+		}
+	}
+
+	if line := annotations["/var/app/bin/billing.sh"]; line.Annotation == nil || line.Annotation.Name != "Nightly billing" {
+		t.Error("Expected annotation on billing job")
+	}
+
+	if line := annotations["/var/app/bin/heartbeat.sh"]; line.Annotation == nil || !line.Annotation.Skip {
+		t.Error("Expected skip annotation on heartbeat job")
+	}
+
+	if line := annotations["/var/app/bin/unannotated.sh"]; line.Annotation != nil {
+		t.Error("Annotations should only apply to the line immediately below")
+	}
+
+	if line := annotations["/var/app/bin/invalid.sh"]; line.AnnotationError == nil {
+		t.Error("Expected annotation error on invalid job")
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const ACTIVITY_ALL = "activity"
+const ACTIVITY_PINGS = "pings"
+const ACTIVITY_ALERTS = "alerts"
+
+// Event is a ping or alert in a monitor's activity, newest first
+type Event struct {
+	Stamp       float64 `json:"stamp"`
+	Type        string  `json:"type,omitempty"`
+	Event       string  `json:"event,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Message     string  `json:"msg,omitempty"`
+	Host        string  `json:"host,omitempty"`
+	IpAddress   string  `json:"ip_address,omitempty"`
+	Series      string  `json:"series,omitempty"`
+	Duration    float64 `json:"duration,omitempty"`
+	StatusCode  *int    `json:"status_code,omitempty"`
+}
+
+// ActivityOptions selects which events to return. Kind is ACTIVITY_ALL, ACTIVITY_PINGS or ACTIVITY_ALERTS, and Before
+// is a unix timestamp; only events before it are returned.
+type ActivityOptions struct {
+	Kind   string
+	Before float64
+}
+
+// EventIterator walks a monitor's activity from newest to oldest, requesting older pages as they are needed
+type EventIterator struct {
+	client  *Client
+	ctx     context.Context
+	key     string
+	options ActivityOptions
+	done    bool
+	events  []Event
+	index   int
+	current Event
+	err     error
+}
+
+// Next advances to the next older event, returning false when there are no more events or a request failed
+func (it *EventIterator) Next() bool {
+	for it.index >= len(it.events) {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		it.fetchPage()
+	}
+
+	it.current = it.events[it.index]
+	it.index++
+	return true
+}
+
+// Event returns the current event
+func (it *EventIterator) Event() Event {
+	return it.current
+}
+
+// Err returns the error that stopped iteration, if any
+func (it *EventIterator) Err() error {
+	return it.err
+}
+
+func (it *EventIterator) fetchPage() {
+	var events []Event
+	if events, it.err = it.client.Activity(it.ctx, it.key, it.options); it.err != nil {
+		return
+	}
+
+	it.events = events
+	it.index = 0
+	if len(events) == 0 {
+		it.done = true
+		return
+	}
+
+	// Each page ends with the oldest event, which is where the next page starts
+	oldest := events[len(events)-1].Stamp
+	if it.options.Before > 0 && oldest >= it.options.Before {
+		it.done = true
+	}
+	it.options.Before = oldest
+}
+
+// Activity returns one page of a monitor's pings and alerts, or only one of them if options.Kind is set
+func (c *Client) Activity(ctx context.Context, key string, options ActivityOptions) ([]Event, error) {
+	kind := options.Kind
+	if len(kind) == 0 {
+		kind = ACTIVITY_ALL
+	}
+
+	var query url.Values
+	if options.Before > 0 {
+		query = url.Values{"before": {strconv.FormatFloat(options.Before, 'f', 3, 64)}}
+	}
+
+	events := []Event{}
+	if err := c.do(ctx, "GET", fmt.Sprintf("/monitors/%s/%s", url.PathEscape(key), kind), query, nil, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Pings returns one page of a monitor's pings
+func (c *Client) Pings(ctx context.Context, key string, before float64) ([]Event, error) {
+	return c.Activity(ctx, key, ActivityOptions{Kind: ACTIVITY_PINGS, Before: before})
+}
+
+// Alerts returns one page of a monitor's alerts
+func (c *Client) Alerts(ctx context.Context, key string, before float64) ([]Event, error) {
+	return c.Activity(ctx, key, ActivityOptions{Kind: ACTIVITY_ALERTS, Before: before})
+}
+
+// ListActivity returns an iterator over a monitor's activity that follows each page to the next older one
+func (c *Client) ListActivity(ctx context.Context, key string, options ActivityOptions) *EventIterator {
+	return &EventIterator{client: c, ctx: ctx, key: key, options: options}
+}
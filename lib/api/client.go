@@ -0,0 +1,168 @@
+// Package api is a client for the Cronitor monitors API
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const BASE_URL = "https://cronitor.io/v3"
+const DEV_BASE_URL = "http://dev.cronitor.io/v3"
+
+// Client sends authenticated requests to the monitors API. The zero value of HttpClient is http.DefaultClient.
+type Client struct {
+	BaseUrl    string
+	ApiKey     string
+	UserAgent  string
+	HttpClient *http.Client
+	Logger     func(string)
+}
+
+// Error is returned when the API responds with a status other than 2xx
+type Error struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *Error) Error() string {
+	if message := e.Message(); len(message) > 0 {
+		return fmt.Sprintf("%s %s: unexpected %d API response: %s", e.Method, e.Url, e.StatusCode, message)
+	}
+
+	return fmt.Sprintf("%s %s: unexpected %d API response", e.Method, e.Url, e.StatusCode)
+}
+
+// Message is the error described in the response body, or the body itself if it is not a JSON error
+func (e *Error) Message() string {
+	type ErrorBody struct {
+		Error   string          `json:"error"`
+		Detail  string          `json:"detail"`
+		Message string          `json:"message"`
+		Errors  json.RawMessage `json:"errors"`
+	}
+
+	errorBody := ErrorBody{}
+	if err := json.Unmarshal(e.Body, &errorBody); err == nil {
+		for _, message := range []string{errorBody.Error, errorBody.Detail, errorBody.Message} {
+			if len(message) > 0 {
+				return message
+			}
+		}
+
+		if len(errorBody.Errors) > 0 {
+			return string(errorBody.Errors)
+		}
+	}
+
+	message := strings.TrimSpace(string(e.Body))
+	if len(message) > 200 {
+		message = message[:200] + "..."
+	}
+
+	return message
+}
+
+// IsNotFound reports whether err is an API response that the monitor or resource does not exist
+func IsNotFound(err error) bool {
+	var apiError *Error
+	return errors.As(err, &apiError) && apiError.StatusCode == http.StatusNotFound
+}
+
+// IsUnauthorized reports whether err is an API response that the API key was missing or rejected
+func IsUnauthorized(err error) bool {
+	var apiError *Error
+	return errors.As(err, &apiError) && (apiError.StatusCode == http.StatusUnauthorized || apiError.StatusCode == http.StatusForbidden)
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	baseUrl := c.BaseUrl
+	if len(baseUrl) == 0 {
+		baseUrl = BASE_URL
+	}
+
+	u := strings.TrimRight(baseUrl, "/") + path
+	if len(query) > 0 {
+		u = u + "?" + query.Encode()
+	}
+
+	return u
+}
+
+func (c *Client) log(message string) {
+	if c.Logger != nil {
+		c.Logger(message)
+	}
+}
+
+// do sends a request with an optional JSON body and, if result is not nil, decodes the JSON response into it
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{}, result interface{}) error {
+	requestUrl := c.url(path, query)
+
+	var requestBody io.Reader
+	var jsonBytes []byte
+	if body != nil {
+		var err error
+		if jsonBytes, err = json.Marshal(body); err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		json.Indent(buf, jsonBytes, "", "  ")
+		c.log("\nRequest:")
+		c.log(buf.String() + "\n")
+		requestBody = bytes.NewReader(jsonBytes)
+	}
+
+	request, err := http.NewRequest(method, requestUrl, requestBody)
+	if err != nil {
+		return err
+	}
+
+	request = request.WithContext(ctx)
+	request.SetBasicAuth(c.ApiKey, "")
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("User-Agent", c.UserAgent)
+
+	httpClient := c.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Request to %s failed: %s", requestUrl, err))
+	}
+
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Request to %s failed: %s", requestUrl, err))
+	}
+
+	buf := new(bytes.Buffer)
+	json.Indent(buf, contents, "", "  ")
+	c.log("\nResponse:")
+	c.log(buf.String() + "\n")
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &Error{Method: method, Url: requestUrl, StatusCode: response.StatusCode, Body: contents}
+	}
+
+	if result != nil && len(bytes.TrimSpace(contents)) > 0 {
+		if err := json.Unmarshal(contents, result); err != nil {
+			return errors.New(fmt.Sprintf("Error from %s: %s", requestUrl, err.Error()))
+		}
+	}
+
+	return nil
+}
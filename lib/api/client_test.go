@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestClient(handler http.HandlerFunc) (*Client, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	return &Client{BaseUrl: server.URL, ApiKey: "apikey", UserAgent: "CronitorCLI/test", HttpClient: server.Client()}, server
+}
+
+func TestListMonitorsFollowsPages(t *testing.T) {
+	client, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, ok := r.BasicAuth(); !ok || user != "apikey" {
+			t.Errorf("Expected the API key as basic auth, got %q", user)
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `{"total_monitor_count": 3, "page_size": 2, "monitors": [{"key": "page1-a"}, {"key": "page1-b"}]}`)
+		case "2":
+			fmt.Fprint(w, `{"total_monitor_count": 3, "page_size": 2, "monitors": [{"key": "page2-a"}]}`)
+		default:
+			t.Errorf("Unexpected request for page %s", r.URL.Query().Get("page"))
+		}
+	})
+	defer server.Close()
+
+	monitors, err := client.AllMonitors(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(monitors) != 3 || monitors[0].Key != "page1-a" || monitors[2].Key != "page2-a" {
+		t.Errorf("Expected 3 monitors from 2 pages, got %v", monitors)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	client, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": "Monitor not found"}`)
+	})
+	defer server.Close()
+
+	_, err := client.GetMonitor(context.Background(), "abc123")
+	if !IsNotFound(err) {
+		t.Fatalf("Expected a not found error, got %v", err)
+	}
+
+	if apiError := err.(*Error); apiError.Message() != "Monitor not found" || apiError.Method != "GET" {
+		t.Errorf("Unexpected error %#v", apiError)
+	}
+}
+
+func TestPauseAndResumeMonitor(t *testing.T) {
+	var paths []string
+	client, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	})
+	defer server.Close()
+
+	client.PauseMonitor(context.Background(), "abc123", 0)
+	client.PauseMonitor(context.Background(), "abc123", 4)
+	client.ResumeMonitor(context.Background(), "abc123")
+
+	expected := []string{"/monitors/abc123/pause", "/monitors/abc123/pause/4", "/monitors/abc123/pause/0"}
+	if fmt.Sprint(paths) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+}
+
+func TestListActivityFollowsBefore(t *testing.T) {
+	var befores []string
+	client, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/monitors/abc123/pings" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+
+		before := r.URL.Query().Get("before")
+		befores = append(befores, before)
+		switch before {
+		case "":
+			fmt.Fprint(w, `[{"stamp": 300, "event": "complete"}, {"stamp": 200, "event": "run"}]`)
+		case "200.000":
+			fmt.Fprint(w, `[{"stamp": 100, "event": "complete"}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	})
+	defer server.Close()
+
+	var stamps []float64
+	it := client.ListActivity(context.Background(), "abc123", ActivityOptions{Kind: ACTIVITY_PINGS})
+	for it.Next() {
+		stamps = append(stamps, it.Event().Stamp)
+	}
+
+	if it.Err() != nil || fmt.Sprint(stamps) != "[300 200 100]" || len(befores) != 3 {
+		t.Errorf("Expected 3 events from 3 requests, got %v from %v (%v)", stamps, befores, it.Err())
+	}
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"cronitor/lib"
+	"fmt"
+	"net/url"
+)
+
+// Monitor is a monitor as returned by the monitors API
+type Monitor struct {
+	Key           string              `json:"key"`
+	Code          string              `json:"code,omitempty"`
+	Name          string              `json:"name,omitempty"`
+	DefaultName   string              `json:"defaultName,omitempty"`
+	Type          string              `json:"type,omitempty"`
+	Rules         []lib.Rule          `json:"rules,omitempty"`
+	Tags          []string            `json:"tags,omitempty"`
+	Timezone      string              `json:"timezone,omitempty"`
+	Notifications map[string][]string `json:"notifications,omitempty"`
+	Note          string              `json:"note,omitempty"`
+	Passing       bool                `json:"passing"`
+	Paused        bool                `json:"paused,omitempty"`
+	Disabled      bool                `json:"disabled,omitempty"`
+	Status        string              `json:"status,omitempty"`
+	Created       string              `json:"created,omitempty"`
+}
+
+// Summary returns the fields discover uses to match monitors to jobs
+func (m Monitor) Summary() lib.MonitorSummary {
+	return lib.MonitorSummary{
+		Name:        m.Name,
+		DefaultName: m.DefaultName,
+		Key:         m.Key,
+		Code:        m.Code,
+		Tags:        m.Tags,
+		Note:        m.Note,
+		Paused:      m.Paused,
+	}
+}
+
+// Definition returns the fields written to a monitors-as-code file
+func (m Monitor) Definition() *lib.MonitorDefinition {
+	return &lib.MonitorDefinition{
+		Key:           m.Key,
+		Name:          m.Name,
+		Type:          m.Type,
+		Rules:         m.Rules,
+		Tags:          m.Tags,
+		Timezone:      m.Timezone,
+		Notifications: m.Notifications,
+		Note:          m.Note,
+	}
+}
+
+// MonitorIterator walks the pages of the monitor list, requesting each page as it is needed
+type MonitorIterator struct {
+	client   *Client
+	ctx      context.Context
+	page     int
+	done     bool
+	monitors []Monitor
+	index    int
+	current  Monitor
+	err      error
+}
+
+// Next advances to the next monitor, returning false when there are no more monitors or a request failed
+func (it *MonitorIterator) Next() bool {
+	for it.index >= len(it.monitors) {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		it.fetchPage()
+	}
+
+	it.current = it.monitors[it.index]
+	it.index++
+	return true
+}
+
+// Monitor returns the current monitor
+func (it *MonitorIterator) Monitor() Monitor {
+	return it.current
+}
+
+// Err returns the error that stopped iteration, if any
+func (it *MonitorIterator) Err() error {
+	return it.err
+}
+
+func (it *MonitorIterator) fetchPage() {
+	type ExpectedResponse struct {
+		TotalMonitorCount int       `json:"total_monitor_count"`
+		PageSize          int       `json:"page_size"`
+		Monitors          []Monitor `json:"monitors"`
+	}
+
+	it.page++
+	response := ExpectedResponse{}
+	if it.err = it.client.do(it.ctx, "GET", "/monitors", url.Values{"page": {fmt.Sprint(it.page)}}, nil, &response); it.err != nil {
+		return
+	}
+
+	it.monitors = response.Monitors
+	it.index = 0
+	if len(response.Monitors) == 0 || it.page*response.PageSize >= response.TotalMonitorCount {
+		it.done = true
+	}
+}
+
+// ListMonitors returns an iterator over every monitor in the account
+func (c *Client) ListMonitors(ctx context.Context) *MonitorIterator {
+	return &MonitorIterator{client: c, ctx: ctx}
+}
+
+// AllMonitors fetches every page of the monitor list
+func (c *Client) AllMonitors(ctx context.Context) ([]Monitor, error) {
+	monitors := []Monitor{}
+	it := c.ListMonitors(ctx)
+	for it.Next() {
+		monitors = append(monitors, it.Monitor())
+	}
+
+	return monitors, it.Err()
+}
+
+// GetMonitor fetches a monitor by key or code
+func (c *Client) GetMonitor(ctx context.Context, key string) (*Monitor, error) {
+	monitor := &Monitor{}
+	if err := c.do(ctx, "GET", "/monitors/"+url.PathEscape(key), nil, nil, monitor); err != nil {
+		return nil, err
+	}
+
+	return monitor, nil
+}
+
+// CreateMonitor creates a single monitor
+func (c *Client) CreateMonitor(ctx context.Context, monitor *lib.MonitorDefinition) (*Monitor, error) {
+	created := &Monitor{}
+	if err := c.do(ctx, "POST", "/monitors", nil, monitor, created); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// UpdateMonitor updates the monitor with the given key or code. Fields omitted from the definition are left alone.
+func (c *Client) UpdateMonitor(ctx context.Context, key string, monitor *lib.MonitorDefinition) (*Monitor, error) {
+	updated := &Monitor{}
+	if err := c.do(ctx, "PUT", "/monitors/"+url.PathEscape(key), nil, monitor, updated); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// PutMonitors creates or updates the monitors generated by discover, matching existing monitors by key
+func (c *Client) PutMonitors(ctx context.Context, monitors []lib.Monitor, isAutoDiscover bool) ([]Monitor, error) {
+	var query url.Values
+	if isAutoDiscover {
+		query = url.Values{"auto-discover": {"1"}}
+	}
+
+	responseMonitors := []Monitor{}
+	if err := c.do(ctx, "PUT", "/monitors", query, monitors, &responseMonitors); err != nil {
+		return nil, err
+	}
+
+	return responseMonitors, nil
+}
+
+// PutMonitorDefinitions creates or updates monitors by key. Unlike PutMonitors, the name and note are set as given.
+func (c *Client) PutMonitorDefinitions(ctx context.Context, definitions []*lib.MonitorDefinition) ([]Monitor, error) {
+	responseMonitors := []Monitor{}
+	if err := c.do(ctx, "PUT", "/monitors", nil, definitions, &responseMonitors); err != nil {
+		return nil, err
+	}
+
+	return responseMonitors, nil
+}
+
+// DeleteMonitor deletes a monitor and its history
+func (c *Client) DeleteMonitor(ctx context.Context, key string) error {
+	return c.do(ctx, "DELETE", "/monitors/"+url.PathEscape(key), nil, nil, nil)
+}
+
+// PauseMonitor stops alerts for a monitor. With hours of 0 the monitor is paused until it is resumed.
+func (c *Client) PauseMonitor(ctx context.Context, key string, hours int) error {
+	path := fmt.Sprintf("/monitors/%s/pause", url.PathEscape(key))
+	if hours > 0 {
+		path = fmt.Sprintf("%s/%d", path, hours)
+	}
+
+	return c.do(ctx, "GET", path, nil, nil, nil)
+}
+
+// ResumeMonitor restarts alerts for a paused monitor
+func (c *Client) ResumeMonitor(ctx context.Context, key string) error {
+	return c.do(ctx, "GET", fmt.Sprintf("/monitors/%s/pause/0", url.PathEscape(key)), nil, nil, nil)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The longest a request waits before it is retried, even if the server asks for longer with Retry-After
+const MAX_RETRY_WAIT = 60 * time.Second
+
+// HttpOptions configures the HTTP client shared by API requests and pings
+type HttpOptions struct {
+	// Timeout for a request, including retries and reading the response body. Zero means no timeout.
+	Timeout time.Duration
+
+	// Retries is the number of times a request is repeated after a network error, a 5xx response or a 429 response
+	Retries int
+
+	// RetryWait is the wait before the first retry. It doubles with each attempt. The default is 1 second.
+	RetryWait time.Duration
+
+	// Proxy is used instead of the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables
+	Proxy string
+
+	// CaBundle is a PEM file of certificate authorities trusted in addition to the system roots
+	CaBundle string
+
+	// ClientCert and ClientKey are PEM files presented to servers that require a client certificate
+	ClientCert string
+	ClientKey  string
+
+	Logger func(string)
+}
+
+// NewHttpClient returns a client that sends requests through a proxy, CA bundle and client certificate as configured,
+// and retries requests that fail in a way that is likely to be temporary
+func NewHttpClient(options HttpOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+
+	if len(options.Proxy) > 0 {
+		proxyUrl, err := url.Parse(options.Proxy)
+		if err != nil || len(proxyUrl.Host) == 0 {
+			return nil, errors.New(fmt.Sprintf("the proxy %s is not a valid URL", options.Proxy))
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	if len(options.CaBundle) > 0 || len(options.ClientCert) > 0 || len(options.ClientKey) > 0 {
+		tlsConfig, err := newTlsConfig(options)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if options.Retries > 0 {
+		retryWait := options.RetryWait
+		if retryWait == 0 {
+			retryWait = time.Second
+		}
+		roundTripper = &retryTransport{base: transport, retries: options.Retries, retryWait: retryWait, logger: options.Logger}
+	}
+
+	return &http.Client{Transport: roundTripper, Timeout: options.Timeout}, nil
+}
+
+func newTlsConfig(options HttpOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if len(options.CaBundle) > 0 {
+		pem, err := ioutil.ReadFile(options.CaBundle)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("the CA bundle %s could not be read: %s", options.CaBundle, err.Error()))
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New(fmt.Sprintf("the CA bundle %s does not contain any PEM certificates", options.CaBundle))
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(options.ClientCert) > 0 || len(options.ClientKey) > 0 {
+		if len(options.ClientCert) == 0 || len(options.ClientKey) == 0 {
+			return nil, errors.New("a client certificate and a client key must be used together")
+		}
+
+		certificate, err := tls.LoadX509KeyPair(options.ClientCert, options.ClientKey)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("the client certificate %s could not be loaded: %s", options.ClientCert, err.Error()))
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// retryTransport repeats requests after network errors and 5xx responses with exponential backoff, and after 429
+// responses as soon as the Retry-After header allows
+type retryTransport struct {
+	base      http.RoundTripper
+	retries   int
+	retryWait time.Duration
+	logger    func(string)
+}
+
+func (t *retryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && request.Body != nil {
+			if request.GetBody == nil {
+				return nil, errors.New(fmt.Sprintf("request to %s cannot be retried", request.URL))
+			}
+
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request.Body = body
+		}
+
+		response, err := t.base.RoundTrip(request)
+		if attempt >= t.retries || !isRetryable(request, response, err) {
+			return response, err
+		}
+
+		wait := backoff(t.retryWait, attempt)
+		if err != nil {
+			t.log(fmt.Sprintf("Request to %s failed: %s; retrying in %s", request.URL, err.Error(), wait))
+		} else {
+			if response.StatusCode == http.StatusTooManyRequests {
+				wait = RetryAfter(response, wait)
+			}
+			t.log(fmt.Sprintf("Unexpected %d response from %s; retrying in %s", response.StatusCode, request.URL, wait))
+			ioutil.ReadAll(response.Body)
+			response.Body.Close()
+		}
+
+		select {
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (t *retryTransport) log(message string) {
+	if t.logger != nil {
+		t.logger(message)
+	}
+}
+
+// isRetryable reports whether a request could succeed if it is sent again. A POST may have created something before
+// it failed, so it is only repeated when the server rate limited it.
+func isRetryable(request *http.Request, response *http.Response, err error) bool {
+	if request.Context().Err() != nil {
+		return false
+	}
+
+	if response != nil && response.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	if request.Method == "POST" {
+		return false
+	}
+
+	return err != nil || response.StatusCode >= 500
+}
+
+// backoff doubles the wait with each attempt and adds up to 50% jitter so that many hosts do not retry together
+func backoff(retryWait time.Duration, attempt int) time.Duration {
+	wait := retryWait << uint(attempt)
+	wait += time.Duration(rand.Int63n(int64(wait)/2 + 1))
+	if wait > MAX_RETRY_WAIT {
+		return MAX_RETRY_WAIT
+	}
+
+	return wait
+}
+
+// RetryAfter is the wait requested by a response's Retry-After header, in seconds or as a date, or fallback if it
+// does not have one. The wait is never longer than MAX_RETRY_WAIT.
+func RetryAfter(response *http.Response, fallback time.Duration) time.Duration {
+	wait := fallback
+	header := response.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		wait = time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+	}
+
+	if wait > MAX_RETRY_WAIT {
+		return MAX_RETRY_WAIT
+	}
+
+	return wait
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testHttpClient(t *testing.T, options HttpOptions) *http.Client {
+	options.RetryWait = time.Millisecond
+	client, err := NewHttpClient(options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	return client
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `[{"key": "abc123", "code": "d3x0c1"}]`)
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL, HttpClient: testHttpClient(t, HttpOptions{Retries: 3})}
+	monitors, err := client.PutMonitorDefinitions(context.Background(), nil)
+	if err != nil || len(monitors) != 1 {
+		t.Fatalf("Expected the request to succeed after retries, got %v %v", monitors, err)
+	}
+
+	if len(bodies) != 3 || bodies[2] != "null" {
+		t.Errorf("Expected the body to be sent with each of 3 attempts, got %q", bodies)
+	}
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL, HttpClient: testHttpClient(t, HttpOptions{Retries: 2})}
+	_, err := client.GetMonitor(context.Background(), "abc123")
+	if apiError, ok := err.(*Error); !ok || apiError.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected the last response as an error, got %v", err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestPostIsOnlyRetriedWhenRateLimited(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL, HttpClient: testHttpClient(t, HttpOptions{Retries: 3})}
+	client.CreateMonitor(context.Background(), nil)
+	if attempts != 2 {
+		t.Errorf("Expected a retry after 429 and none after 500, got %d attempts", attempts)
+	}
+}
+
+func TestRetryOnNetworkError(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, `{"key": "abc123"}`)
+	}))
+	defer server.Close()
+
+	client := &Client{BaseUrl: server.URL, HttpClient: testHttpClient(t, HttpOptions{Retries: 1})}
+	if monitor, err := client.GetMonitor(context.Background(), "abc123"); err != nil || monitor.Key != "abc123" {
+		t.Errorf("Expected the request to succeed after a dropped connection, got %v %v", monitor, err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":                              5 * time.Second,
+		"12":                            12 * time.Second,
+		"3600":                          MAX_RETRY_WAIT,
+		"not a number":                  5 * time.Second,
+		"Mon, 01 Jan 2001 00:00:00 GMT": 0,
+	}
+
+	for header, expected := range tests {
+		response := &http.Response{Header: http.Header{"Retry-After": {header}}}
+		if wait := RetryAfter(response, 5*time.Second); wait != expected {
+			t.Errorf("RetryAfter(%q) = %s, expected %s", header, wait, expected)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		fmt.Fprint(w, `{"key": "abc123"}`)
+	}))
+	defer proxy.Close()
+
+	client := &Client{BaseUrl: "http://cronitor.invalid/v3", HttpClient: testHttpClient(t, HttpOptions{Proxy: proxy.URL})}
+	if _, err := client.GetMonitor(context.Background(), "abc123"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if proxied != "http://cronitor.invalid/v3/monitors/abc123" {
+		t.Errorf("Expected the request to be sent through the proxy, got %q", proxied)
+	}
+
+	if _, err := NewHttpClient(HttpOptions{Proxy: "not a url"}); err == nil {
+		t.Error("Expected error for an invalid proxy")
+	}
+}
+
+func TestCaBundleAndClientCertificate(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+
+	clientCert, clientKey := writeTestCertificate(t, directory)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"key": "abc123"}`)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	caBundle := filepath.Join(directory, "ca.pem")
+	ioutil.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+
+	withoutBundle := &Client{BaseUrl: server.URL, HttpClient: testHttpClient(t, HttpOptions{})}
+	if _, err := withoutBundle.GetMonitor(context.Background(), "abc123"); err == nil {
+		t.Error("Expected error for a server certificate that is not trusted")
+	}
+
+	withoutCert := &Client{BaseUrl: server.URL, HttpClient: testHttpClient(t, HttpOptions{CaBundle: caBundle})}
+	if _, err := withoutCert.GetMonitor(context.Background(), "abc123"); err == nil {
+		t.Error("Expected error without a client certificate")
+	}
+
+	client := &Client{BaseUrl: server.URL, HttpClient: testHttpClient(t, HttpOptions{CaBundle: caBundle, ClientCert: clientCert, ClientKey: clientKey})}
+	if _, err := client.GetMonitor(context.Background(), "abc123"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	if _, err := NewHttpClient(HttpOptions{ClientCert: clientCert}); err == nil {
+		t.Error("Expected error for a client certificate without a key")
+	}
+
+	if _, err := NewHttpClient(HttpOptions{CaBundle: clientKey}); err == nil {
+		t.Error("Expected error for a CA bundle without certificates")
+	}
+}
+
+func writeTestCertificate(t *testing.T, directory string) (string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "cronitor-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+
+	certFile := filepath.Join(directory, "client.pem")
+	keyFile := filepath.Join(directory, "client-key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certFile, keyFile
+}
@@ -30,6 +30,8 @@ type Crontab struct {
 	Lines                   []*Line
 	TimezoneLocationName    *TimezoneLocationName
 	UsesSixFieldExpressions bool
+	Defaults                *Annotation
+	DefaultsError           error
 }
 
 func (c *Crontab) Parse(noAutoDiscover bool) (error, int) {
@@ -43,6 +45,8 @@ func (c *Crontab) Parse(noAutoDiscover bool) (error, int) {
 	}
 
 	var autoDiscoverLine *Line
+	var annotation *Annotation
+	var annotationError error
 
 	for lineNumber, fullLine := range lines {
 		var cronExpression string
@@ -107,6 +111,21 @@ func (c *Crontab) Parse(noAutoDiscover bool) (error, int) {
 
 		line.CommandToRun = strings.Join(command, " ")
 
+		// An annotation comment applies to the job on the line immediately below it
+		if len(line.CronExpression) > 0 {
+			line.Annotation = annotation
+			line.AnnotationError = annotationError
+		}
+
+		if IsAnnotation(fullLine) {
+			annotation, annotationError = ParseAnnotation(fullLine)
+		} else if IsCrontabDefaults(fullLine) {
+			c.Defaults, c.DefaultsError = ParseCrontabDefaults(fullLine)
+			annotation, annotationError = nil, nil
+		} else {
+			annotation, annotationError = nil, nil
+		}
+
 		if line.IsAutoDiscoverCommand() {
 			autoDiscoverLine = &line
 			if noAutoDiscover {
@@ -256,14 +275,20 @@ func (c Crontab) load() ([]string, int, error) {
 }
 
 type Line struct {
-	Name           string
-	FullLine       string
-	LineNumber     int
-	CronExpression string
-	CommandToRun   string
-	Code           string
-	RunAs          string
-	Mon            Monitor
+	Name            string
+	FullLine        string
+	LineNumber      int
+	CronExpression  string
+	CommandToRun    string
+	Code            string
+	RunAs           string
+	Annotation      *Annotation
+	AnnotationError error
+	Mon             Monitor
+}
+
+func (l Line) IsJob() bool {
+	return len(l.CronExpression) > 0 && len(l.CommandToRun) > 0
 }
 
 func (l Line) IsMonitorable() bool {
@@ -350,6 +375,7 @@ func createAutoDiscoverLine(crontab *Crontab) *Line {
 	commandToRun = strings.Replace(commandToRun, "-v", "", -1)
 	commandToRun = strings.Replace(commandToRun, "--interactive", "", -1)
 	commandToRun = strings.Replace(commandToRun, "-i", "", -1)
+	commandToRun = RemovePruneFlags(commandToRun)
 	if len(crontab.Filename) > 0 {
 		commandToRun = strings.Replace(commandToRun, crontab.Filename, crontab.CanonicalName(), -1)
 	}
@@ -365,6 +391,24 @@ func createAutoDiscoverLine(crontab *Crontab) *Line {
 	return &line
 }
 
+// RemovePruneFlags removes --prune and --prune-action from a discover command, so a command that runs unattended
+// never pauses or deletes monitors without a confirmation.
+func RemovePruneFlags(command string) string {
+	var fields []string
+	skipValue := false
+	for _, field := range strings.Fields(command) {
+		if skipValue {
+			skipValue = false
+		} else if field == "--prune-action" {
+			skipValue = true
+		} else if field != "--prune" && !strings.HasPrefix(field, "--prune=") && !strings.HasPrefix(field, "--prune-action=") {
+			fields = append(fields, field)
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
 func isSixFieldCronExpression(splitLine []string) bool {
 	matchDigitOrWildcard, _ := regexp.MatchString("^[-,?*/0-9]+$", splitLine[5])
 	matchDayOfWeekStringRange, _ := regexp.MatchString("^(Mon|Tue|Wed|Thr|Fri|Sat|Sun)(-(Mon|Tue|Wed|Thr|Fri|Sat|Sun))?$", splitLine[5])
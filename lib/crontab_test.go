@@ -0,0 +1,18 @@
+package lib
+
+import "testing"
+
+func TestRemovePruneFlags(t *testing.T) {
+	tests := map[string]string{
+		"cronitor discover --auto --prune --prune-action delete /etc/crontab": "cronitor discover --auto /etc/crontab",
+		"cronitor discover --prune-action=delete --prune":                     "cronitor discover",
+		"cronitor discover --prune=true -e /var/app":                          "cronitor discover -e /var/app",
+		"cronitor discover /var/prune-jobs/crontab":                           "cronitor discover /var/prune-jobs/crontab",
+	}
+
+	for command, expected := range tests {
+		if actual := RemovePruneFlags(command); actual != expected {
+			t.Errorf("RemovePruneFlags(%q) = %q, expected %q", command, actual, expected)
+		}
+	}
+}
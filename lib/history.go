@@ -0,0 +1,229 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const HISTORY_FILE = "history.jsonl"
+
+// Ping delivery states recorded for each ping sent during a run
+const PING_SENT = "sent"
+const PING_REJECTED = "rejected"
+const PING_FAILED = "failed"
+
+// RunRecord is what is known locally about one run of a job by exec. Start and End are unix timestamps, Series is
+// the ID sent with the run's pings to match them up, and Log is the file with the run's full output, if it was kept.
+// A command that could not be started is recorded with a non-zero ExitCode, so it is listed as failed.
+type RunRecord struct {
+	Series   string            `json:"series"`
+	Monitor  string            `json:"monitor"`
+	Command  string            `json:"command"`
+	Host     string            `json:"host,omitempty"`
+	Start    float64           `json:"start"`
+	End      float64           `json:"end"`
+	Duration float64           `json:"duration"`
+	ExitCode int               `json:"exit_code"`
+	Output   string            `json:"output,omitempty"`
+	Log      string            `json:"log,omitempty"`
+	Pings    map[string]string `json:"pings,omitempty"`
+}
+
+func (r RunRecord) Succeeded() bool {
+	return r.ExitCode == 0
+}
+
+// RunFilter selects runs from the history. Since is a unix timestamp, and Limit keeps only the latest runs.
+type RunFilter struct {
+	Monitor string
+	Failed  bool
+	Since   float64
+	Limit   int
+}
+
+func (f RunFilter) matches(record RunRecord) bool {
+	if len(f.Monitor) > 0 && record.Monitor != f.Monitor {
+		return false
+	}
+
+	if f.Failed && record.Succeeded() {
+		return false
+	}
+
+	return f.Since == 0 || record.Start >= f.Since
+}
+
+// History is an append-only file of runs, one JSON object per line. Once it holds more than a tenth over MaxRuns, or
+// its oldest run is older than MaxAge, it is rewritten without the oldest runs. A zero MaxRuns or MaxAge is no limit.
+type History struct {
+	Filename string
+	MaxRuns  int
+	MaxAge   time.Duration
+}
+
+// Append adds a run to the history, creating the file and its directory if needed. The file is only readable by its
+// owner because job output can contain secrets.
+func (h *History) Append(record RunRecord) error {
+	if err := os.MkdirAll(filepath.Dir(h.Filename), 0755); err != nil {
+		return errors.New(fmt.Sprintf("cannot create %s: %s", filepath.Dir(h.Filename), err.Error()))
+	}
+
+	unlock, err := lockFile(h.Filename)
+	if err != nil {
+		return errors.New(fmt.Sprintf("cannot lock %s: %s", h.Filename, err.Error()))
+	}
+	defer unlock()
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.Filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.New(fmt.Sprintf("cannot write %s: %s", h.Filename, err.Error()))
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return errors.New(fmt.Sprintf("cannot write %s: %s", h.Filename, err.Error()))
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return h.compact(time.Now())
+}
+
+// Runs returns the runs that match filter, oldest first. A history file that does not exist yet has no runs.
+func (h *History) Runs(filter RunFilter) ([]RunRecord, error) {
+	f, err := os.Open(h.Filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("cannot read %s: %s", h.Filename, err.Error()))
+	}
+	defer f.Close()
+
+	records, err := readRunRecords(f)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("cannot read %s: %s", h.Filename, err.Error()))
+	}
+
+	var matched []RunRecord
+	for _, record := range records {
+		if filter.matches(record) {
+			matched = append(matched, record)
+		}
+	}
+
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[len(matched)-filter.Limit:]
+	}
+
+	return matched, nil
+}
+
+// compact rewrites the history without runs over the limits. It must be called with the history locked.
+func (h *History) compact(now time.Time) error {
+	f, err := os.Open(h.Filename)
+	if err != nil {
+		return err
+	}
+
+	count, oldest, err := countRunRecords(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	tooMany := h.MaxRuns > 0 && count > h.MaxRuns+h.MaxRuns/10
+	tooOld := h.MaxAge > 0 && oldest > 0 && oldest < float64(now.Add(-h.MaxAge).Unix())
+	if !tooMany && !tooOld {
+		return nil
+	}
+
+	f, err = os.Open(h.Filename)
+	if err != nil {
+		return err
+	}
+	records, err := readRunRecords(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	if h.MaxAge > 0 {
+		cutoff := float64(now.Add(-h.MaxAge).Unix())
+		for len(records) > 0 && records[0].Start < cutoff {
+			records = records[1:]
+		}
+	}
+
+	if h.MaxRuns > 0 && len(records) > h.MaxRuns {
+		records = records[len(records)-h.MaxRuns:]
+	}
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		line, _ := json.Marshal(record)
+		buf.Write(append(line, '\n'))
+	}
+
+	return WriteFileAtomically(h.Filename, buf.Bytes(), 0600)
+}
+
+// readRunRecords reads every run, skipping lines that cannot be parsed, like a line left incomplete by a full disk
+func readRunRecords(r io.Reader) ([]RunRecord, error) {
+	var records []RunRecord
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record RunRecord
+			if json.Unmarshal(line, &record) == nil {
+				records = append(records, record)
+			}
+		}
+
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// countRunRecords counts the lines in a history file and returns the start of the first run, without parsing the rest
+func countRunRecords(r io.Reader) (int, float64, error) {
+	reader := bufio.NewReader(r)
+	first, err := reader.ReadBytes('\n')
+	if err == io.EOF {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+
+	var oldest RunRecord
+	json.Unmarshal(first, &oldest)
+
+	count := 1
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := reader.Read(buf)
+		count += bytes.Count(buf[:n], []byte{'\n'})
+		if err == io.EOF {
+			return count, oldest.Start, nil
+		} else if err != nil {
+			return 0, 0, err
+		}
+	}
+}
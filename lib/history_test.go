@@ -0,0 +1,98 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryAppendAndFilter(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+
+	history := &History{Filename: filepath.Join(directory, "state", HISTORY_FILE)}
+	if runs, err := history.Runs(RunFilter{}); err != nil || len(runs) != 0 {
+		t.Fatalf("Expected no runs before the file exists, got %v %v", runs, err)
+	}
+
+	for i, code := range []string{"abc", "def", "abc", "abc"} {
+		record := RunRecord{Monitor: code, Command: "backup.sh", Start: float64(1000 + i), ExitCode: i % 2, Pings: map[string]string{"run": PING_SENT}}
+		if err := history.Append(record); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	if info, _ := os.Stat(history.Filename); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the history to be private, got %s", info.Mode())
+	}
+
+	// A line left incomplete by a crash is skipped
+	f, _ := os.OpenFile(history.Filename, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"monitor": "abc", "sta`)
+	f.Close()
+
+	tests := []struct {
+		filter   RunFilter
+		expected []float64
+	}{
+		{RunFilter{}, []float64{1000, 1001, 1002, 1003}},
+		{RunFilter{Monitor: "abc"}, []float64{1000, 1002, 1003}},
+		{RunFilter{Monitor: "abc", Failed: true}, []float64{1003}},
+		{RunFilter{Since: 1002}, []float64{1002, 1003}},
+		{RunFilter{Monitor: "abc", Limit: 2}, []float64{1002, 1003}},
+	}
+
+	for _, test := range tests {
+		runs, err := history.Runs(test.filter)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		var starts []float64
+		for _, run := range runs {
+			starts = append(starts, run.Start)
+		}
+		if fmt.Sprint(starts) != fmt.Sprint(test.expected) {
+			t.Errorf("Filter %+v: expected %v, got %v", test.filter, test.expected, starts)
+		}
+	}
+}
+
+func TestHistoryCompact(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+
+	history := &History{Filename: filepath.Join(directory, HISTORY_FILE), MaxRuns: 10}
+	now := float64(time.Now().Unix())
+	for i := 0; i < 11; i++ {
+		history.Append(RunRecord{Monitor: "abc", Start: now + float64(i)})
+	}
+
+	if runs, _ := history.Runs(RunFilter{}); len(runs) != 11 {
+		t.Errorf("Expected compaction to wait until the history is a tenth over its limit, got %d runs", len(runs))
+	}
+
+	history.Append(RunRecord{Monitor: "abc", Start: now + 11})
+	runs, _ := history.Runs(RunFilter{})
+	if len(runs) != 10 || runs[0].Start != now+2 || runs[9].Start != now+11 {
+		t.Errorf("Expected the latest 10 runs, got %d runs from %f", len(runs), runs[0].Start)
+	}
+
+	history.MaxAge = time.Hour
+	history.Append(RunRecord{Monitor: "old", Start: now - 7200})
+	runs, _ = history.Runs(RunFilter{})
+	if len(runs) != 11 {
+		t.Errorf("Expected only the oldest run to decide whether runs are too old, got %d runs", len(runs))
+	}
+
+	history.Filename = filepath.Join(directory, "aged.jsonl")
+	history.Append(RunRecord{Monitor: "old", Start: now - 7200})
+	history.Append(RunRecord{Monitor: "new", Start: now})
+	runs, _ = history.Runs(RunFilter{})
+	if len(runs) != 1 || runs[0].Monitor != "new" {
+		t.Errorf("Expected runs older than MaxAge to be removed, got %+v", runs)
+	}
+}
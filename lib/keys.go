@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Key versions for cron job monitors. Version 1 keys change whenever the hostname or schedule changes, so a schedule
+// edit creates a new monitor. Version 2 keys use the machine ID and leave out the schedule, so the existing monitor's
+// rule is updated instead.
+const KEY_VERSION_1 = 1
+const KEY_VERSION_2 = 2
+
+// Files that hold a stable machine ID, in the order they are checked
+var MACHINE_ID_FILES = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// HostIdentity returns the machine ID, which survives hostname changes, or the hostname if there isn't one
+func HostIdentity() string {
+	for _, filename := range MACHINE_ID_FILES {
+		if b, err := ioutil.ReadFile(filename); err == nil {
+			if machineId := strings.TrimSpace(string(b)); len(machineId) > 0 {
+				return machineId
+			}
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+func IsValidKeyVersion(version int) bool {
+	return version == KEY_VERSION_1 || version == KEY_VERSION_2
+}
+
+// Key returns the monitor key for a line. A key set with an annotation is always used as-is.
+func (c Crontab) Key(line *Line, version int) string {
+	if line.Annotation != nil && len(line.Annotation.Key) > 0 {
+		return line.Annotation.Key
+	}
+
+	if version != KEY_VERSION_2 {
+		return line.Key(c.CanonicalName())
+	}
+
+	if line.IsAutoDiscoverCommand() {
+		return hashKey("v2", HostIdentity(), "auto discover "+c.CanonicalName())
+	}
+
+	// Without the schedule, the same command on two lines would share a key, so later copies are numbered. The crontab
+	// is part of the key so the same command in two crontabs, e.g. two users' crontabs, gets two monitors.
+	occurrence := 0
+	for _, other := range c.Lines {
+		if other == line {
+			break
+		}
+
+		if other.IsMonitorable() && other.CommandToRun == line.CommandToRun && other.RunAs == line.RunAs {
+			occurrence++
+		}
+	}
+
+	return hashKey("v2", HostIdentity(), c.keyIdentity(), line.CommandToRun, line.RunAs, fmt.Sprintf("%d", occurrence))
+}
+
+// keyIdentity names the crontab in version 2 keys: the owning user for a user crontab, otherwise its path
+func (c Crontab) keyIdentity() string {
+	if c.IsUserCrontab && len(c.User) > 0 {
+		return "user " + c.User
+	}
+
+	return c.CanonicalName()
+}
+
+// Key returns the monitor key for an anacron job. Version 2 keys leave out the period, so editing it updates the
+// existing monitor.
+func (j AnacronJob) Key(version int) string {
+	if version != KEY_VERSION_2 {
+		// Always use os.Hostname when creating a key so the key does not change when a user modifies their hostname using param/var
+		hostname, _ := os.Hostname()
+		return hashKey(hostname, j.CommandToRun, fmt.Sprintf("anacron %s", j.Period), j.Identifier)
+	}
+
+	return hashKey("v2", HostIdentity(), "anacron "+j.Identifier, j.CommandToRun)
+}
+
+// Key returns the monitor key for a systemd timer
+func (t SystemdTimer) Key(version int) string {
+	if version != KEY_VERSION_2 {
+		// Always use os.Hostname when creating a key so the key does not change when a user modifies their hostname using param/var
+		hostname, _ := os.Hostname()
+		return hashKey(hostname, t.ExecStart(), "systemd "+t.Timer.Name, t.RunAs())
+	}
+
+	return hashKey("v2", HostIdentity(), "systemd "+t.Timer.Name, t.ExecStart(), t.RunAs())
+}
+
+func hashKey(parts ...string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(strings.Join(parts, "-"))))
+}
@@ -0,0 +1,57 @@
+package lib
+
+import "testing"
+
+func TestCrontabKeyVersions(t *testing.T) {
+	crontab := Crontab{Filename: "/etc/cron.d/app"}
+	first := &Line{CronExpression: "0 1 * * *", CommandToRun: "/bin/backup.sh", RunAs: "root"}
+	second := &Line{CronExpression: "0 13 * * *", CommandToRun: "/bin/backup.sh", RunAs: "root"}
+	annotated := &Line{CronExpression: "0 2 * * *", CommandToRun: "/bin/billing.sh", Annotation: &Annotation{Key: "nightly-billing"}}
+	crontab.Lines = []*Line{first, second, annotated}
+
+	if crontab.Key(first, KEY_VERSION_1) != first.Key(crontab.CanonicalName()) {
+		t.Error("Version 1 keys should match the original key")
+	}
+
+	if crontab.Key(annotated, KEY_VERSION_1) != "nightly-billing" || crontab.Key(annotated, KEY_VERSION_2) != "nightly-billing" {
+		t.Error("Annotated keys should be used as-is")
+	}
+
+	v2Key := crontab.Key(first, KEY_VERSION_2)
+	if v2Key == crontab.Key(second, KEY_VERSION_2) {
+		t.Error("The same command on two lines should have different version 2 keys")
+	}
+
+	first.CronExpression = "30 1 * * *"
+	if crontab.Key(first, KEY_VERSION_2) != v2Key {
+		t.Error("Version 2 keys should not change when the schedule changes")
+	}
+}
+
+func TestCrontabKeyIncludesCrontab(t *testing.T) {
+	line := func() *Line { return &Line{CronExpression: "0 1 * * *", CommandToRun: "/usr/local/bin/backup.sh"} }
+	alice := Crontab{User: "alice", IsUserCrontab: true, Lines: []*Line{line()}}
+	bob := Crontab{User: "bob", IsUserCrontab: true, Lines: []*Line{line()}}
+	if alice.Key(alice.Lines[0], KEY_VERSION_2) == bob.Key(bob.Lines[0], KEY_VERSION_2) {
+		t.Error("The same command in two users' crontabs should have different version 2 keys")
+	}
+
+	system := Crontab{Filename: "/etc/crontab", Lines: []*Line{line()}}
+	dropIn := Crontab{Filename: "/etc/cron.d/backup", Lines: []*Line{line()}}
+	if system.Key(system.Lines[0], KEY_VERSION_2) == dropIn.Key(dropIn.Lines[0], KEY_VERSION_2) {
+		t.Error("The same command in two crontab files should have different version 2 keys")
+	}
+}
+
+func TestAnacronJobKeyVersions(t *testing.T) {
+	job := AnacronJob{Period: "1", Identifier: "daily", CommandToRun: "run-parts /etc/cron.daily"}
+	v1, v2 := job.Key(KEY_VERSION_1), job.Key(KEY_VERSION_2)
+	if v1 == v2 {
+		t.Error("Version 1 and 2 keys should differ")
+	}
+
+	job.Period = "@weekly"
+	if job.Key(KEY_VERSION_1) == v1 || job.Key(KEY_VERSION_2) != v2 {
+		t.Error("Only version 1 keys should change when the period changes")
+	}
+}
@@ -0,0 +1,281 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var kubernetesCronJobApiVersions = map[string]bool{
+	"batch/v1":      true,
+	"batch/v1beta1": true,
+}
+
+type KubernetesContainer struct {
+	Name    string   `yaml:"name"`
+	Image   string   `yaml:"image"`
+	Command []string `yaml:"command"`
+	Args    []string `yaml:"args"`
+}
+
+type kubernetesManifest struct {
+	ApiVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Schedule    string `yaml:"schedule"`
+		TimeZone    string `yaml:"timeZone"`
+		Suspend     bool   `yaml:"suspend"`
+		JobTemplate struct {
+			Spec struct {
+				Template struct {
+					Spec struct {
+						Containers []KubernetesContainer `yaml:"containers"`
+					} `yaml:"spec"`
+				} `yaml:"template"`
+			} `yaml:"spec"`
+		} `yaml:"jobTemplate"`
+	} `yaml:"spec"`
+}
+
+type KubernetesCronJob struct {
+	Filename      string
+	DocumentIndex int
+	ApiVersion    string
+	Namespace     string
+	Name          string
+	Schedule      string
+	TimeZone      string
+	Suspend       bool
+	Container     KubernetesContainer
+	Code          string
+	Mon           Monitor
+}
+
+// QualifiedName returns the namespace/name that identifies the CronJob in the cluster
+func (j KubernetesCronJob) QualifiedName() string {
+	return fmt.Sprintf("%s/%s", j.Namespace, j.Name)
+}
+
+// CommandToRun returns the container command and args as they would be run by the kubelet
+func (j KubernetesCronJob) CommandToRun() string {
+	return strings.Join(append(append([]string{}, j.Container.Command...), j.Container.Args...), " ")
+}
+
+// CanBeWrapped reports whether the container command can be prefixed with cronitor exec. When the manifest
+// relies on the image ENTRYPOINT there is no command to wrap.
+func (j KubernetesCronJob) CanBeWrapped() bool {
+	return len(j.Container.Command) > 0
+}
+
+func (j KubernetesCronJob) IsMonitorable() bool {
+	return len(j.Schedule) > 0 && len(j.Name) > 0
+}
+
+func (j KubernetesCronJob) Key() string {
+	// CronJobs are cluster resources, so the key is derived from namespace/name rather than the local hostname
+	data := []byte(fmt.Sprintf("kubernetes-%s", j.QualifiedName()))
+	return fmt.Sprintf("%x", sha1.Sum(data))
+}
+
+// KubernetesManifest is a YAML file with one or more documents, some of which may be CronJobs
+type KubernetesManifest struct {
+	Filename  string
+	CronJobs  []*KubernetesCronJob
+	documents []yaml.MapSlice
+}
+
+func (m *KubernetesManifest) Parse() error {
+	b, err := ioutil.ReadFile(m.Filename)
+	if err != nil {
+		return errors.New(fmt.Sprintf("the manifest at %s could not be read; check permissions and try again", m.Filename))
+	}
+
+	return m.parseBytes(b)
+}
+
+func (m *KubernetesManifest) parseBytes(b []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		document := yaml.MapSlice{}
+		if err := decoder.Decode(&document); err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.New(fmt.Sprintf("the manifest at %s is not valid YAML: %s", m.Filename, err.Error()))
+		}
+
+		// An empty document, e.g. after a trailing ---, would be written back as {}
+		if len(document) == 0 {
+			continue
+		}
+
+		m.documents = append(m.documents, document)
+
+		// Round-trip the document through a typed struct, the MapSlice is kept so it can be re-written in order
+		manifestBytes, _ := yaml.Marshal(document)
+		manifest := kubernetesManifest{}
+		if err := yaml.Unmarshal(manifestBytes, &manifest); err != nil || manifest.Kind != "CronJob" || !kubernetesCronJobApiVersions[manifest.ApiVersion] {
+			continue
+		}
+
+		job := KubernetesCronJob{
+			Filename:      m.Filename,
+			DocumentIndex: len(m.documents) - 1,
+			ApiVersion:    manifest.ApiVersion,
+			Namespace:     manifest.Metadata.Namespace,
+			Name:          manifest.Metadata.Name,
+			Schedule:      strings.TrimSpace(manifest.Spec.Schedule),
+			TimeZone:      manifest.Spec.TimeZone,
+			Suspend:       manifest.Spec.Suspend,
+		}
+
+		if job.Namespace == "" {
+			job.Namespace = "default"
+		}
+
+		// Before spec.timeZone existed, a CRON_TZ or TZ prefix on the schedule was the only way to set one
+		for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+			if strings.HasPrefix(job.Schedule, prefix) {
+				if fields := strings.Fields(job.Schedule); len(fields) > 1 {
+					job.TimeZone = strings.TrimPrefix(fields[0], prefix)
+					job.Schedule = strings.Join(fields[1:], " ")
+				}
+			}
+		}
+
+		if containers := manifest.Spec.JobTemplate.Spec.Template.Spec.Containers; len(containers) > 0 {
+			job.Container = containers[0]
+		}
+
+		// If this job is already being wrapped by the Cronitor client, read current code.
+		command := job.Container.Command
+		if len(command) > 3 && strings.HasSuffix(command[0], "cronitor") && command[1] == "--no-stdout" && command[2] == "exec" {
+			job.Code = command[3]
+			job.Container.Command = command[4:]
+		} else if len(command) > 2 && strings.HasSuffix(command[0], "cronitor") && command[1] == "exec" {
+			job.Code = command[2]
+			job.Container.Command = command[3:]
+		}
+
+		m.CronJobs = append(m.CronJobs, &job)
+	}
+
+	return nil
+}
+
+// Write re-creates the manifest with the first container of each newly monitored CronJob wrapped by cronitor exec.
+// Comments and formatting in the original file are not preserved.
+func (m KubernetesManifest) Write() (string, error) {
+	for _, job := range m.CronJobs {
+		if len(job.Mon.Code) == 0 || len(job.Code) > 0 || !job.CanBeWrapped() {
+			continue
+		}
+
+		command := []string{"cronitor"}
+		if job.Mon.NoStdoutPassthru {
+			command = append(command, "--no-stdout")
+		}
+		command = append(command, "exec", job.Mon.Code)
+		command = append(command, job.Container.Command...)
+
+		if !setFirstContainerCommand(m.documents[job.DocumentIndex], command) {
+			return "", errors.New(fmt.Sprintf("could not update the container command for %s", job.QualifiedName()))
+		}
+	}
+
+	var documents []string
+	for _, document := range m.documents {
+		b, err := yaml.Marshal(document)
+		if err != nil {
+			return "", err
+		}
+		documents = append(documents, string(b))
+	}
+
+	return strings.Join(documents, "---\n"), nil
+}
+
+func setFirstContainerCommand(document yaml.MapSlice, command []string) bool {
+	node := interface{}(document)
+	for _, key := range []string{"spec", "jobTemplate", "spec", "template", "spec", "containers"} {
+		mapSlice, ok := node.(yaml.MapSlice)
+		if !ok {
+			return false
+		}
+
+		node = nil
+		for _, item := range mapSlice {
+			if item.Key == key {
+				node = item.Value
+				break
+			}
+		}
+	}
+
+	containers, ok := node.([]interface{})
+	if !ok || len(containers) == 0 {
+		return false
+	}
+
+	container, ok := containers[0].(yaml.MapSlice)
+	if !ok {
+		return false
+	}
+
+	for i, item := range container {
+		if item.Key == "command" {
+			container[i].Value = command
+			return true
+		}
+	}
+
+	return false
+}
+
+func KubernetesManifestFactory(filename string) *KubernetesManifest {
+	return &KubernetesManifest{
+		Filename: filename,
+	}
+}
+
+// ReadKubernetesManifests reads a single manifest file, or every .yaml and .yml file beneath a directory
+func ReadKubernetesManifests(path string) ([]*KubernetesManifest, error) {
+	var filenames []string
+	if info, err := os.Stat(path); err != nil {
+		return nil, errors.New(fmt.Sprintf("the path %s does not exist", path))
+	} else if info.IsDir() {
+		filepath.Walk(path, func(filename string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && (strings.HasSuffix(filename, ".yaml") || strings.HasSuffix(filename, ".yml")) {
+				filenames = append(filenames, filename)
+			}
+			return nil
+		})
+	} else {
+		filenames = append(filenames, path)
+	}
+
+	var manifests []*KubernetesManifest
+	for _, filename := range filenames {
+		manifest := KubernetesManifestFactory(filename)
+		if err := manifest.Parse(); err != nil {
+			return nil, err
+		}
+
+		if len(manifest.CronJobs) > 0 {
+			manifests = append(manifests, manifest)
+		}
+	}
+
+	return manifests, nil
+}
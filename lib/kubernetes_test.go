@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKubernetesManifestParse(t *testing.T) {
+	manifest := KubernetesManifestFactory("../fixtures/kubernetes/cronjobs.yaml")
+	if err := manifest.Parse(); err != nil {
+		t.Fatalf("Unexpected error parsing manifest: %s", err)
+	}
+
+	if len(manifest.CronJobs) != 2 {
+		t.Fatalf("Expected 2 CronJobs, got %d", len(manifest.CronJobs))
+	}
+
+	invoices := manifest.CronJobs[0]
+	if invoices.QualifiedName() != "billing/nightly-invoices" || invoices.Schedule != "30 2 * * *" || invoices.TimeZone != "America/New_York" {
+		t.Errorf("CronJob parsed incorrectly, got: %+v", invoices)
+	}
+	if invoices.CommandToRun() != "/app/bin/invoices --send --batch-size 500" || !invoices.CanBeWrapped() {
+		t.Errorf("Unexpected command, got: %s", invoices.CommandToRun())
+	}
+
+	warmer := manifest.CronJobs[1]
+	if warmer.QualifiedName() != "default/cache-warmer" || warmer.Schedule != "*/15 * * * *" || warmer.TimeZone != "UTC" {
+		t.Errorf("CronJob parsed incorrectly, got: %+v", warmer)
+	}
+	if warmer.CanBeWrapped() {
+		t.Error("CronJob without a command should not be wrapped")
+	}
+}
+
+func TestKubernetesManifestWrite(t *testing.T) {
+	manifest := KubernetesManifestFactory("../fixtures/kubernetes/cronjobs.yaml")
+	manifest.Parse()
+	manifest.CronJobs[0].Mon.Code = "d3x0c1"
+	manifest.CronJobs[1].Mon.Code = "abc123"
+
+	written, err := manifest.Write()
+	if err != nil {
+		t.Fatalf("Unexpected error writing manifest: %s", err)
+	}
+
+	if strings.Count(written, "---\n") != 2 || !strings.Contains(written, "kind: ConfigMap") {
+		t.Error("Expected all documents to be written")
+	}
+
+	rewritten := KubernetesManifest{Filename: "rewritten"}
+	if err := rewritten.parseBytes([]byte(written)); err != nil {
+		t.Fatalf("Rewritten manifest is invalid: %s", err)
+	}
+
+	if job := rewritten.CronJobs[0]; job.Code != "d3x0c1" || job.CommandToRun() != "/app/bin/invoices --send --batch-size 500" {
+		t.Errorf("Expected container command to be wrapped, got code '%s' and command '%s'", job.Code, job.CommandToRun())
+	}
+
+	if job := rewritten.CronJobs[1]; job.Code != "" {
+		t.Error("Container without a command should not be wrapped")
+	}
+}
+
+func TestKubernetesManifestSkipsEmptyDocuments(t *testing.T) {
+	manifest := KubernetesManifestFactory("../fixtures/kubernetes/trailing-separator.yaml")
+	if err := manifest.Parse(); err != nil {
+		t.Fatalf("Unexpected error parsing manifest: %s", err)
+	}
+
+	if len(manifest.CronJobs) != 1 || manifest.CronJobs[0].DocumentIndex != 0 {
+		t.Fatalf("Expected 1 CronJob in the first document, got %+v", manifest.CronJobs)
+	}
+
+	manifest.CronJobs[0].Mon.Code = "d3x0c1"
+	written, err := manifest.Write()
+	if err != nil {
+		t.Fatalf("Unexpected error writing manifest: %s", err)
+	}
+
+	if strings.Contains(written, "{}") {
+		t.Errorf("Expected empty documents to be dropped, got:\n%s", written)
+	}
+}
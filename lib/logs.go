@@ -0,0 +1,274 @@
+package lib
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const logTimeFormat = "20060102T150405.000"
+
+// Log files are named for when the run started and the process that ran it, e.g. 20200601T220000.123-4242.log. While
+// the run is going its log is named 20200601T220000.123-4242.running.log, and it is renamed when the run finishes.
+var logFileRegex = regexp.MustCompile(`^(\d{8}T\d{6}\.\d{3})-(\d+)(\.running)?\.log(\.gz)?$`)
+
+const runningLogSuffix = ".running.log"
+
+// LogArchive keeps the full output of each run in its own file, in a directory for each monitor. After a run finishes
+// its log is optionally compressed and the monitor's oldest logs are removed until no more than MaxRuns logs are kept,
+// they take no more than MaxBytes, and none are older than MaxAge. A zero limit is no limit.
+type LogArchive struct {
+	Directory string
+	MaxRuns   int
+	MaxBytes  int64
+	MaxAge    time.Duration
+	Compress  bool
+}
+
+// RunLog is the log of one run
+type RunLog struct {
+	Monitor    string
+	Filename   string
+	Start      time.Time
+	Pid        int
+	Size       int64
+	Compressed bool
+	Finished   bool
+
+	// A later run was started by the same process ID, so this run ended without finishing its log
+	superseded bool
+}
+
+// Running reports whether the run is still writing to its log. A run that ended without finishing its log, e.g.
+// because it was killed, is not running once its process is gone or its process ID has started another run.
+func (l RunLog) Running() bool {
+	if l.Finished || l.superseded {
+		return false
+	}
+
+	// The log is renamed when the run finishes
+	if _, err := os.Stat(l.Filename); err != nil {
+		return false
+	}
+
+	return processRunning(l.Pid)
+}
+
+// Open returns a reader for the log, decompressing it if needed
+func (l RunLog) Open() (io.ReadCloser, error) {
+	f, err := os.Open(l.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !l.Compressed {
+		return f, nil
+	}
+
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, errors.New(fmt.Sprintf("cannot read %s: %s", l.Filename, err.Error()))
+	}
+
+	return gzipFile{reader, f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+func (a *LogArchive) monitorDirectory(monitor string) (string, error) {
+	if len(monitor) == 0 || strings.ContainsAny(monitor, `/\`) || monitor == "." || monitor == ".." {
+		return "", errors.New(fmt.Sprintf("invalid monitor code %s", monitor))
+	}
+
+	return filepath.Join(a.Directory, monitor), nil
+}
+
+// Create opens a new log for a run. Logs are only readable by their owner because job output can contain secrets.
+func (a *LogArchive) Create(monitor string, start time.Time, pid int) (*os.File, error) {
+	directory, err := a.monitorDirectory(monitor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, errors.New(fmt.Sprintf("cannot create %s: %s", directory, err.Error()))
+	}
+
+	filename := filepath.Join(directory, fmt.Sprintf("%s-%d%s", start.Format(logTimeFormat), pid, runningLogSuffix))
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("cannot create %s: %s", filename, err.Error()))
+	}
+
+	return f, nil
+}
+
+// Finish records that a log's run is done by renaming the log, compresses it if compression is on, and then rotates
+// the monitor's logs. It returns the log's final filename.
+func (a *LogArchive) Finish(monitor string, filename string) (string, error) {
+	if strings.HasSuffix(filename, runningLogSuffix) {
+		finished := strings.TrimSuffix(filename, runningLogSuffix) + ".log"
+		if err := os.Rename(filename, finished); err != nil {
+			return filename, err
+		}
+		filename = finished
+	}
+
+	if a.Compress {
+		if err := compressFile(filename, filename+".gz"); err != nil {
+			return filename, err
+		}
+		filename += ".gz"
+	}
+
+	return filename, a.Rotate(monitor, time.Now())
+}
+
+// Runs returns a monitor's logs, oldest first. A monitor that has no logs yet has no runs.
+func (a *LogArchive) Runs(monitor string) ([]RunLog, error) {
+	directory, err := a.monitorDirectory(monitor)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(directory)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("cannot read %s: %s", directory, err.Error()))
+	}
+
+	var logs []RunLog
+	for _, file := range files {
+		matches := logFileRegex.FindStringSubmatch(file.Name())
+		if matches == nil || !file.Mode().IsRegular() {
+			continue
+		}
+
+		start, err := time.ParseInLocation(logTimeFormat, matches[1], time.Local)
+		if err != nil {
+			continue
+		}
+
+		pid, _ := strconv.Atoi(matches[2])
+		logs = append(logs, RunLog{
+			Monitor:    monitor,
+			Filename:   filepath.Join(directory, file.Name()),
+			Start:      start,
+			Pid:        pid,
+			Size:       file.Size(),
+			Compressed: len(matches[4]) > 0,
+			Finished:   len(matches[3]) == 0,
+		})
+	}
+
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].Start.Before(logs[j].Start)
+	})
+
+	// A process runs one job, so when its process ID started a later run, e.g. PID 1 in a container, the earlier run
+	// ended even though the process is running
+	latestStart := map[int]time.Time{}
+	for i := len(logs) - 1; i >= 0; i-- {
+		if latest, ok := latestStart[logs[i].Pid]; ok && latest.After(logs[i].Start) {
+			logs[i].superseded = true
+		} else {
+			latestStart[logs[i].Pid] = logs[i].Start
+		}
+	}
+
+	return logs, nil
+}
+
+// Rotate removes a monitor's oldest logs that are over the limits. Logs of runs that are still running are kept and
+// do not count against the limits.
+func (a *LogArchive) Rotate(monitor string, now time.Time) error {
+	logs, err := a.Runs(monitor)
+	if err != nil {
+		return err
+	}
+
+	var finished []RunLog
+	for _, log := range logs {
+		if !log.Running() {
+			finished = append(finished, log)
+		}
+	}
+
+	var total int64
+	for _, log := range finished {
+		total += log.Size
+	}
+
+	for len(finished) > 0 {
+		oldest := finished[0]
+		tooMany := a.MaxRuns > 0 && len(finished) > a.MaxRuns
+		tooBig := a.MaxBytes > 0 && total > a.MaxBytes && len(finished) > 1
+		tooOld := a.MaxAge > 0 && oldest.Start.Before(now.Add(-a.MaxAge))
+		if !tooMany && !tooBig && !tooOld {
+			break
+		}
+
+		if err := os.Remove(oldest.Filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		total -= oldest.Size
+		finished = finished[1:]
+	}
+
+	return nil
+}
+
+// compressFile gzips source to destination and removes source
+func compressFile(source, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(destination, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.New(fmt.Sprintf("cannot compress %s: %s", source, err.Error()))
+	}
+
+	writer := gzip.NewWriter(out)
+	if _, err := io.Copy(writer, in); err != nil {
+		out.Close()
+		os.Remove(destination)
+		return errors.New(fmt.Sprintf("cannot compress %s: %s", source, err.Error()))
+	}
+
+	if err := writer.Close(); err != nil {
+		out.Close()
+		os.Remove(destination)
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(destination)
+		return err
+	}
+
+	in.Close()
+	return os.Remove(source)
+}
@@ -0,0 +1,125 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogArchive(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+
+	archive := &LogArchive{Directory: directory, MaxRuns: 2, Compress: true}
+	start := time.Date(2020, 6, 1, 22, 0, 0, 0, time.Local)
+	for i, output := range []string{"first\n", "second\n", "third\n"} {
+		f, err := archive.Create("abc", start.Add(time.Duration(i)*time.Minute), 999999999)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		f.WriteString(output)
+		f.Close()
+
+		filename, err := archive.Finish("abc", f.Name())
+		if err != nil || !strings.HasSuffix(filename, ".log.gz") {
+			t.Fatalf("Expected a compressed log, got %s %v", filename, err)
+		}
+	}
+
+	logs, err := archive.Runs("abc")
+	if err != nil || len(logs) != 2 {
+		t.Fatalf("Expected the latest 2 logs, got %+v %v", logs, err)
+	}
+
+	if !logs[0].Start.Equal(start.Add(time.Minute)) || logs[0].Pid != 999999999 || !logs[0].Compressed || logs[0].Running() {
+		t.Errorf("Unexpected log %+v", logs[0])
+	}
+
+	reader, err := logs[1].Open()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	b, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	if string(b) != "third\n" {
+		t.Errorf("Expected the latest log to be decompressed, got %q", b)
+	}
+
+	if logs, err := archive.Runs("def"); err != nil || len(logs) != 0 {
+		t.Errorf("Expected no logs for a monitor without runs, got %+v %v", logs, err)
+	}
+
+	if _, err := archive.Create("../abc", start, 1); err == nil {
+		t.Error("Expected error for a monitor code that is a path")
+	}
+}
+
+func TestLogArchiveRotate(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+
+	archive := &LogArchive{Directory: directory}
+	now := time.Now()
+	create := func(age time.Duration, pid int, output string) {
+		f, _ := archive.Create("abc", now.Add(-age), pid)
+		f.WriteString(output)
+		f.Close()
+	}
+
+	create(3*time.Hour, 999999999, strings.Repeat("x", 100))
+	create(2*time.Hour, 999999999, strings.Repeat("x", 100))
+	create(90*time.Minute, os.Getpid(), "still running")
+	create(time.Hour, 999999999, strings.Repeat("x", 100))
+
+	archive.MaxBytes = 250
+	archive.Rotate("abc", now)
+	if logs, _ := archive.Runs("abc"); len(logs) != 3 || logs[0].Start.Before(now.Add(-150*time.Minute)) {
+		t.Errorf("Expected the oldest log to be removed to fit in MaxBytes, got %+v", logs)
+	}
+
+	archive.MaxAge = 80 * time.Minute
+	archive.Rotate("abc", now)
+	logs, _ := archive.Runs("abc")
+	if len(logs) != 2 || !logs[0].Running() {
+		t.Errorf("Expected old logs to be removed except the one still running, got %+v", logs)
+	}
+}
+
+func TestLogArchiveFinish(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+
+	// The process finishing its run is still alive, so only the renamed log tells rotation the run is done
+	archive := &LogArchive{Directory: directory, MaxRuns: 2}
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		f, _ := archive.Create("abc", start.Add(time.Duration(i)*time.Minute), os.Getpid())
+		if !strings.HasSuffix(f.Name(), ".running.log") {
+			t.Errorf("Expected the log of a run in progress to be marked running, got %s", f.Name())
+		}
+		f.Close()
+
+		if filename, err := archive.Finish("abc", f.Name()); err != nil || !strings.HasSuffix(filename, "-"+strconv.Itoa(os.Getpid())+".log") {
+			t.Fatalf("Expected the log to be renamed when the run finished, got %s %v", filename, err)
+		}
+	}
+
+	logs, _ := archive.Runs("abc")
+	if len(logs) != 2 || !logs[0].Finished || logs[1].Running() {
+		t.Errorf("Expected MaxRuns finished logs, got %+v", logs)
+	}
+
+	// A run that was killed left its log unfinished, and its process ID then started another run
+	for i := 0; i < 2; i++ {
+		f, _ := archive.Create("def", start.Add(time.Duration(i)*time.Minute), os.Getpid())
+		f.Close()
+	}
+
+	logs, _ = archive.Runs("def")
+	if len(logs) != 2 || logs[0].Running() || !logs[1].Running() {
+		t.Errorf("Expected only the latest run of the process to be running, got %+v", logs)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package lib
+
+import "syscall"
+
+// processRunning reports whether a process exists. A process owned by another user is still running.
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
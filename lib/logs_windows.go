@@ -0,0 +1,18 @@
+package lib
+
+import "os"
+
+// processRunning reports whether a process exists
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	process.Release()
+	return true
+}
@@ -0,0 +1,247 @@
+package lib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const METRIC_GAUGE = "gauge"
+const METRIC_COUNTER = "counter"
+
+// Metric is a Prometheus metric family with its samples
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []MetricSample
+}
+
+// MetricSample is a single value of a metric, identified by its labels
+type MetricSample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+var metricSampleRegex = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{.*\})?\s+(\S+)`)
+var metricHelpRegex = regexp.MustCompile(`^#\s+(HELP|TYPE)\s+([a-zA-Z_:][a-zA-Z0-9_:]*)\s+(.*)$`)
+
+// WriteMetrics writes metrics in the Prometheus text exposition format
+func WriteMetrics(w io.Writer, metrics []Metric) error {
+	writer := bufio.NewWriter(w)
+	for _, metric := range metrics {
+		if len(metric.Samples) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(writer, "# HELP %s %s\n", metric.Name, escapeMetricHelp(metric.Help))
+		fmt.Fprintf(writer, "# TYPE %s %s\n", metric.Name, metric.Type)
+		for _, sample := range metric.Samples {
+			fmt.Fprintf(writer, "%s%s %s\n", metric.Name, formatMetricLabels(sample.Labels), formatMetricValue(sample.Value))
+		}
+	}
+
+	return writer.Flush()
+}
+
+func formatMetricLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	var names []string
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escapeMetricLabel(labels[name])))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatMetricValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeMetricLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func escapeMetricHelp(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(value)
+}
+
+// UpdateTextfile replaces the samples for one job in a node_exporter textfile collector file, keeping the samples
+// written by other jobs. Samples belong to a job when their label named labelName has the value labelValue. The file
+// is replaced atomically so node_exporter never reads a partial file.
+func UpdateTextfile(filename string, labelName string, labelValue string, metrics []Metric) error {
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	existing, err := readTextfile(filename)
+	if err != nil {
+		return err
+	}
+
+	jobLabel := fmt.Sprintf(`%s="%s"`, labelName, escapeMetricLabel(labelValue))
+	var merged []Metric
+	seen := map[string]bool{}
+	for _, metric := range metrics {
+		seen[metric.Name] = true
+		merged = append(merged, metric)
+	}
+
+	// Keep other jobs' samples in place, and metrics this version does not write in their own families
+	for i := range merged {
+		if old, ok := existing[merged[i].Name]; ok {
+			merged[i].Samples = append(otherJobSamples(old.lines, jobLabel), merged[i].Samples...)
+		}
+	}
+
+	var output strings.Builder
+	if err := WriteMetrics(&output, merged); err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range existing {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		lines := otherJobLines(existing[name].lines, jobLabel)
+		if len(lines) == 0 {
+			continue
+		}
+		output.WriteString(existing[name].header)
+		output.WriteString(strings.Join(lines, "\n") + "\n")
+	}
+
+	return WriteFileAtomically(filename, []byte(output.String()), 0644)
+}
+
+// A metric family read from a textfile, kept as text so samples from other jobs are written back unchanged
+type textfileMetric struct {
+	header string
+	lines  []string
+}
+
+func readTextfile(filename string) (map[string]*textfileMetric, error) {
+	metrics := map[string]*textfileMetric{}
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return metrics, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if matches := metricHelpRegex.FindStringSubmatch(line); matches != nil {
+			if _, ok := metrics[matches[2]]; !ok {
+				metrics[matches[2]] = &textfileMetric{}
+			}
+			metrics[matches[2]].header += line + "\n"
+		} else if matches := metricSampleRegex.FindStringSubmatch(line); matches != nil {
+			if _, ok := metrics[matches[1]]; !ok {
+				metrics[matches[1]] = &textfileMetric{}
+			}
+			metrics[matches[1]].lines = append(metrics[matches[1]].lines, line)
+		}
+	}
+
+	return metrics, nil
+}
+
+func otherJobLines(lines []string, jobLabel string) []string {
+	var kept []string
+	for _, line := range lines {
+		if !strings.Contains(line, jobLabel) {
+			kept = append(kept, line)
+		}
+	}
+
+	return kept
+}
+
+// otherJobSamples parses the samples written by other jobs so they can be written with the new samples
+func otherJobSamples(lines []string, jobLabel string) []MetricSample {
+	var samples []MetricSample
+	for _, line := range otherJobLines(lines, jobLabel) {
+		matches := metricSampleRegex.FindStringSubmatch(line)
+		value, err := strconv.ParseFloat(matches[3], 64)
+		if err != nil {
+			continue
+		}
+		samples = append(samples, MetricSample{Labels: parseMetricLabels(matches[2]), Value: value})
+	}
+
+	return samples
+}
+
+var metricLabelRegex = regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_]*)="((?:[^"\\]|\\.)*)"`)
+
+func parseMetricLabels(text string) map[string]string {
+	labels := map[string]string{}
+	for _, matches := range metricLabelRegex.FindAllStringSubmatch(text, -1) {
+		labels[matches[1]] = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n").Replace(matches[2])
+	}
+
+	return labels
+}
+
+// WriteFileAtomically writes to a temporary file in the same directory, which only the owner can read until it is
+// given perm, and renames it over filename
+func WriteFileAtomically(filename string, data []byte, perm os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".")
+	if err != nil {
+		return errors.New(fmt.Sprintf("cannot write %s: %s", filename, err.Error()))
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	os.Chmod(f.Name(), perm)
+	if err := os.Rename(f.Name(), filename); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	metrics := []Metric{
+		{Name: "cronitor_up", Help: "Whether the poll succeeded", Type: METRIC_GAUGE, Samples: []MetricSample{{Value: 1}}},
+		{Name: "cronitor_empty", Help: "Not written", Type: METRIC_GAUGE},
+		{Name: "cronitor_monitor_passing", Help: "Passing", Type: METRIC_GAUGE, Samples: []MetricSample{
+			{Labels: map[string]string{"name": `Say "hi"\n`, "code": "abc"}, Value: 0},
+			{Labels: map[string]string{"code": "def"}, Value: math.NaN()},
+		}},
+	}
+
+	var output strings.Builder
+	if err := WriteMetrics(&output, metrics); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := `# HELP cronitor_up Whether the poll succeeded
+# TYPE cronitor_up gauge
+cronitor_up 1
+# HELP cronitor_monitor_passing Passing
+# TYPE cronitor_monitor_passing gauge
+cronitor_monitor_passing{code="abc",name="Say \"hi\"\\n"} 0
+cronitor_monitor_passing{code="def"} NaN
+`
+	if output.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output.String())
+	}
+}
+
+func TestUpdateTextfileKeepsOtherJobs(t *testing.T) {
+	directory, _ := ioutil.TempDir("", "cronitor")
+	defer os.RemoveAll(directory)
+	filename := filepath.Join(directory, "cronitor.prom")
+
+	duration := func(code string, value float64) []Metric {
+		return []Metric{{Name: "cronitor_job_last_run_duration_seconds", Help: "Duration", Type: METRIC_GAUGE, Samples: []MetricSample{
+			{Labels: map[string]string{"monitor": code}, Value: value},
+		}}}
+	}
+
+	for _, update := range []struct {
+		code  string
+		value float64
+	}{{"abc", 1.5}, {"def", 2}, {"abc", 3}} {
+		if err := UpdateTextfile(filename, "monitor", update.code, duration(update.code, update.value)); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	// A metric family from another version of the CLI is kept as written
+	b, _ := ioutil.ReadFile(filename)
+	ioutil.WriteFile(filename, append(b, []byte("# HELP cronitor_job_old Old\n# TYPE cronitor_job_old gauge\ncronitor_job_old{monitor=\"def\"} 7\n")...), 0644)
+	if err := UpdateTextfile(filename, "monitor", "abc", duration("abc", 4)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	b, _ = ioutil.ReadFile(filename)
+	expected := `# HELP cronitor_job_last_run_duration_seconds Duration
+# TYPE cronitor_job_last_run_duration_seconds gauge
+cronitor_job_last_run_duration_seconds{monitor="def"} 2
+cronitor_job_last_run_duration_seconds{monitor="abc"} 4
+# HELP cronitor_job_old Old
+# TYPE cronitor_job_old gauge
+cronitor_job_old{monitor="def"} 7
+`
+	if string(b) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, string(b))
+	}
+
+	files, _ := filepath.Glob(filepath.Join(directory, "*.prom"))
+	if len(files) != 1 {
+		t.Errorf("Expected only the textfile to end in .prom, got %v", files)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !windows
+// +build !windows
+
+package lib
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// lockFile holds an exclusive lock on a file beside filename so that jobs finishing together do not lose each
+// other's changes to it
+func lockFile(filename string) (func(), error) {
+	f, err := os.OpenFile(filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// ProcessMaxRssBytes returns the peak resident set size of a process that has exited
+func ProcessMaxRssBytes(state *os.ProcessState) (float64, bool) {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0, false
+	}
+
+	// macOS reports bytes, Linux and the BSDs report kilobytes
+	if runtime.GOOS == "darwin" {
+		return float64(rusage.Maxrss), true
+	}
+
+	return float64(rusage.Maxrss) * 1024, true
+}
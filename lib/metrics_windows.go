@@ -0,0 +1,14 @@
+package lib
+
+import "os"
+
+// lockFile is a no-op on Windows, where jobs are not usually run from cron and node_exporter's textfile collector is
+// not used
+func lockFile(filename string) (func(), error) {
+	return func() {}, nil
+}
+
+// ProcessMaxRssBytes is not available on Windows
+func ProcessMaxRssBytes(state *os.ProcessState) (float64, bool) {
+	return 0, false
+}
@@ -0,0 +1,211 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MonitorDefinition is a monitor as written in a monitors-as-code file and as returned by the monitors API
+type MonitorDefinition struct {
+	Key           string              `json:"key" yaml:"key"`
+	Name          string              `json:"name,omitempty" yaml:"name,omitempty"`
+	Type          string              `json:"type,omitempty" yaml:"type,omitempty"`
+	Rules         []Rule              `json:"rules,omitempty" yaml:"rules,omitempty"`
+	Tags          []string            `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Timezone      string              `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	Notifications map[string][]string `json:"notifications,omitempty" yaml:"notifications,omitempty"`
+	Note          string              `json:"note,omitempty" yaml:"note,omitempty"`
+	Filename      string              `json:"-" yaml:"-"`
+}
+
+// MonitorsFile is the top level of a monitors-as-code file
+type MonitorsFile struct {
+	Monitors []*MonitorDefinition `json:"monitors" yaml:"monitors"`
+}
+
+// MonitorChange describes what applying a definition will do to the monitor with the same key
+type MonitorChange struct {
+	Definition *MonitorDefinition
+	Action     string
+	Fields     []string
+}
+
+const MONITOR_CREATE = "create"
+const MONITOR_UPDATE = "update"
+const MONITOR_UNCHANGED = "unchanged"
+
+// Changes lists the fields of the definition that differ from an existing monitor. Fields omitted from the definition are left alone.
+func (d MonitorDefinition) Changes(existing *MonitorDefinition) []string {
+	var fields []string
+	if d.Name != "" && d.Name != existing.Name {
+		fields = append(fields, "name")
+	}
+	if d.Type != "" && d.Type != existing.Type {
+		fields = append(fields, "type")
+	}
+	if len(d.Rules) > 0 && !reflect.DeepEqual(d.Rules, existing.Rules) {
+		fields = append(fields, "rules")
+	}
+	if len(d.Tags) > 0 && !reflect.DeepEqual(sortedCopy(d.Tags), sortedCopy(existing.Tags)) {
+		fields = append(fields, "tags")
+	}
+	if d.Timezone != "" && d.Timezone != existing.Timezone {
+		fields = append(fields, "timezone")
+	}
+	if len(d.Notifications) > 0 && !reflect.DeepEqual(d.Notifications, existing.Notifications) {
+		fields = append(fields, "notifications")
+	}
+	if d.Note != "" && d.Note != existing.Note {
+		fields = append(fields, "note")
+	}
+
+	return fields
+}
+
+func (d MonitorDefinition) Validate() error {
+	if strings.TrimSpace(d.Key) == "" {
+		return errors.New("key is required")
+	}
+
+	for i, rule := range d.Rules {
+		if rule.RuleType == "" || rule.Value == "" {
+			return errors.New(fmt.Sprintf("rule %d needs a rule_type and a value", i+1))
+		}
+	}
+
+	return nil
+}
+
+// PlanMonitorChanges compares definitions with existing monitors, matched by key
+func PlanMonitorChanges(definitions []*MonitorDefinition, existing []*MonitorDefinition) []MonitorChange {
+	existingByKey := map[string]*MonitorDefinition{}
+	for _, monitor := range existing {
+		existingByKey[monitor.Key] = monitor
+	}
+
+	var changes []MonitorChange
+	for _, definition := range definitions {
+		current, ok := existingByKey[definition.Key]
+		if !ok {
+			changes = append(changes, MonitorChange{Definition: definition, Action: MONITOR_CREATE})
+		} else if fields := definition.Changes(current); len(fields) > 0 {
+			changes = append(changes, MonitorChange{Definition: definition, Action: MONITOR_UPDATE, Fields: fields})
+		} else {
+			changes = append(changes, MonitorChange{Definition: definition, Action: MONITOR_UNCHANGED})
+		}
+	}
+
+	return changes
+}
+
+// ReadMonitorDefinitions reads YAML or JSON monitor files. Directories are searched for .yaml, .yml and .json files.
+func ReadMonitorDefinitions(paths []string) ([]*MonitorDefinition, error) {
+	var filenames []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			filenames = append(filenames, path)
+			continue
+		}
+
+		files, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if isMonitorsFile(file.Name()) && !file.IsDir() {
+				filenames = append(filenames, filepath.Join(path, file.Name()))
+			}
+		}
+	}
+
+	var definitions []*MonitorDefinition
+	seenKeys := map[string]string{}
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		monitorsFile, err := ParseMonitorsFile(data, strings.ToLower(filepath.Ext(filename)) == ".json")
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("%s: %s", filename, err.Error()))
+		}
+
+		for i, definition := range monitorsFile.Monitors {
+			if err := definition.Validate(); err != nil {
+				return nil, errors.New(fmt.Sprintf("%s: monitor %d: %s", filename, i+1, err.Error()))
+			}
+
+			if previous, exists := seenKeys[definition.Key]; exists {
+				return nil, errors.New(fmt.Sprintf("%s: key %s is already defined in %s", filename, definition.Key, previous))
+			}
+
+			seenKeys[definition.Key] = filename
+			definition.Filename = filename
+			definitions = append(definitions, definition)
+		}
+	}
+
+	return definitions, nil
+}
+
+func ParseMonitorsFile(data []byte, isJson bool) (*MonitorsFile, error) {
+	monitorsFile := MonitorsFile{}
+	var err error
+	if isJson {
+		// Unknown fields are refused in JSON as in YAML, so a misspelled field is not silently dropped
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(&monitorsFile); err == nil && decoder.More() {
+			err = errors.New("unexpected data after the monitors")
+		}
+	} else {
+		err = yaml.UnmarshalStrict(data, &monitorsFile)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &monitorsFile, nil
+}
+
+// WriteMonitorsFile formats monitor definitions as YAML or JSON, sorted by key so exports diff cleanly
+func WriteMonitorsFile(definitions []*MonitorDefinition, format string) ([]byte, error) {
+	sorted := append([]*MonitorDefinition{}, definitions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+	monitorsFile := MonitorsFile{Monitors: sorted}
+
+	if format == "json" {
+		data, err := json.MarshalIndent(monitorsFile, "", "  ")
+		return append(data, '\n'), err
+	}
+
+	return yaml.Marshal(monitorsFile)
+}
+
+func isMonitorsFile(filename string) bool {
+	extension := strings.ToLower(filepath.Ext(filename))
+	return extension == ".yaml" || extension == ".yml" || extension == ".json"
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
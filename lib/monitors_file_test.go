@@ -0,0 +1,85 @@
+package lib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadMonitorDefinitions(t *testing.T) {
+	definitions, err := ReadMonitorDefinitions([]string{"../fixtures/monitors"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(definitions) != 2 {
+		t.Fatalf("Expected 2 monitors, got %d", len(definitions))
+	}
+
+	billing := definitions[0]
+	if billing.Key != "nightly-billing" || billing.Rules[0].GraceSeconds != 600 || billing.Notifications["templates"][0] != "oncall" {
+		t.Errorf("YAML monitor parsed incorrectly: %+v", billing)
+	}
+
+	if definitions[1].Key != "weekly-report" || definitions[1].Rules[0].Value != "0 6 * * 1" {
+		t.Errorf("JSON monitor parsed incorrectly: %+v", definitions[1])
+	}
+
+	if _, err := ReadMonitorDefinitions([]string{"../fixtures/monitors", "../fixtures/monitors/billing.yaml"}); err == nil {
+		t.Error("Expected error for duplicate keys")
+	}
+}
+
+func TestParseMonitorsFileRejectsUnknownFields(t *testing.T) {
+	if _, err := ParseMonitorsFile([]byte("monitors:\n  - key: a\n    schedule: '* * * * *'\n"), false); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+
+	if _, err := ParseMonitorsFile([]byte(`{"monitors": [{"key": "a", "type": "job", "tag": ["prod"]}]}`), true); err == nil {
+		t.Error("Expected error for unknown field in JSON")
+	}
+}
+
+func TestPlanMonitorChanges(t *testing.T) {
+	existing := []*MonitorDefinition{
+		{Key: "a", Name: "A", Tags: []string{"x", "y"}},
+		{Key: "b", Name: "B", Rules: []Rule{{RuleType: "complete_ping_not_received", Value: "* * * * *"}}},
+	}
+	definitions := []*MonitorDefinition{
+		{Key: "a", Tags: []string{"y", "x"}},
+		{Key: "b", Name: "Renamed", Rules: []Rule{{RuleType: "complete_ping_not_received", Value: "0 * * * *"}}},
+		{Key: "c", Name: "C"},
+	}
+
+	var actions []string
+	for _, change := range PlanMonitorChanges(definitions, existing) {
+		actions = append(actions, change.Action+" "+change.Definition.Key+" "+strings.Join(change.Fields, ","))
+	}
+
+	expected := []string{"unchanged a ", "update b name,rules", "create c "}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("Unexpected plan: %v", actions)
+	}
+}
+
+func TestWriteMonitorsFileRoundTrip(t *testing.T) {
+	definitions, _ := ReadMonitorDefinitions([]string{"../fixtures/monitors"})
+	for _, format := range []string{"yaml", "json"} {
+		output, err := WriteMonitorsFile(definitions, format)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		parsed, err := ParseMonitorsFile(output, format == "json")
+		if err != nil {
+			t.Fatalf("Unexpected error reading %s export: %s", format, err)
+		}
+
+		for i := range parsed.Monitors {
+			parsed.Monitors[i].Filename = definitions[i].Filename
+			if !reflect.DeepEqual(parsed.Monitors[i], definitions[i]) {
+				t.Errorf("%s export does not round trip: %+v", format, parsed.Monitors[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NameMapping sets the name, and optionally tags and a note, of the jobs it matches. A mapping matches by monitor
+// key, by a regular expression on the command, or by crontab file and line number.
+type NameMapping struct {
+	Key     string   `yaml:"key,omitempty"`
+	Command string   `yaml:"command,omitempty"`
+	Crontab string   `yaml:"crontab,omitempty"`
+	Line    int      `yaml:"line,omitempty"`
+	Name    string   `yaml:"name,omitempty"`
+	Tags    []string `yaml:"tags,omitempty"`
+	Note    string   `yaml:"note,omitempty"`
+	pattern *regexp.Regexp
+}
+
+type NamesFile struct {
+	Filename string         `yaml:"-"`
+	Names    []*NameMapping `yaml:"names"`
+}
+
+func ReadNamesFile(filename string) (*NamesFile, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	namesFile := NamesFile{Filename: filename}
+	if err := yaml.UnmarshalStrict(b, &namesFile); err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %s", filename, err.Error()))
+	}
+
+	for i, mapping := range namesFile.Names {
+		matchers := 0
+		if len(mapping.Key) > 0 {
+			matchers++
+		}
+		if len(mapping.Command) > 0 {
+			matchers++
+			if mapping.pattern, err = regexp.Compile(mapping.Command); err != nil {
+				return nil, errors.New(fmt.Sprintf("%s: entry %d: invalid command pattern: %s", filename, i+1, err.Error()))
+			}
+		}
+		if len(mapping.Crontab) > 0 || mapping.Line > 0 {
+			matchers++
+			if len(mapping.Crontab) == 0 || mapping.Line < 1 {
+				return nil, errors.New(fmt.Sprintf("%s: entry %d: crontab and line must be used together", filename, i+1))
+			}
+		}
+
+		if matchers != 1 {
+			return nil, errors.New(fmt.Sprintf("%s: entry %d: expecting exactly one of key, command, or crontab and line", filename, i+1))
+		}
+	}
+
+	return &namesFile, nil
+}
+
+// Match returns the mapping for a job. A key match is preferred over a crontab and line match, which is preferred over
+// the first matching command pattern. Line numbers start at 1.
+func (f NamesFile) Match(key, command string, crontab *Crontab, lineNumber int) *NameMapping {
+	for _, mapping := range f.Names {
+		if len(mapping.Key) > 0 && mapping.Key == key {
+			return mapping
+		}
+	}
+
+	for _, mapping := range f.Names {
+		if mapping.Line == lineNumber && isSameCrontab(mapping.Crontab, crontab) {
+			return mapping
+		}
+	}
+
+	for _, mapping := range f.Names {
+		if mapping.pattern != nil && mapping.pattern.MatchString(command) {
+			return mapping
+		}
+	}
+
+	return nil
+}
+
+func isSameCrontab(filename string, crontab *Crontab) bool {
+	if len(filename) == 0 {
+		return false
+	}
+
+	// The user crontab has no file, so it can be matched as "user"
+	if crontab.IsUserCrontab {
+		return filename == "user" || filename == crontab.DisplayName()
+	}
+
+	if filename == crontab.Filename {
+		return true
+	}
+
+	absoluteFilename, err := filepath.Abs(filename)
+	return err == nil && absoluteFilename == crontab.CanonicalName()
+}
@@ -0,0 +1,49 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNamesFileMatch(t *testing.T) {
+	namesFile, err := ReadNamesFile("../fixtures/names.yaml")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	crontab := &Crontab{Filename: "/etc/cron.d/reports"}
+	if mapping := namesFile.Match("3c8e9f0a1b2c3d4e5f60718293a4b5c6d7e8f901", "/bin/backup.sh", crontab, 4); mapping == nil || mapping.Name != "Nightly billing" {
+		t.Errorf("Expected a key match to take precedence, got %+v", mapping)
+	}
+
+	if mapping := namesFile.Match("other", "/bin/backup.sh", crontab, 4); mapping == nil || mapping.Name != "Weekly report" {
+		t.Errorf("Expected a crontab and line match, got %+v", mapping)
+	}
+
+	if mapping := namesFile.Match("other", "/bin/backup.sh --full", crontab, 5); mapping == nil || mapping.Name != "Database backup" || mapping.Tags[0] != "backup" {
+		t.Errorf("Expected a command match, got %+v", mapping)
+	}
+
+	if mapping := namesFile.Match("other", "/bin/report.sh", &Crontab{Filename: "/etc/crontab"}, 4); mapping != nil {
+		t.Errorf("Expected no match, got %+v", mapping)
+	}
+}
+
+func TestReadNamesFileValidation(t *testing.T) {
+	for _, content := range []string{
+		"names:\n  - name: No matcher\n",
+		"names:\n  - key: abc\n    command: abc\n    name: Two matchers\n",
+		"names:\n  - crontab: /etc/crontab\n    name: Missing line\n",
+		"names:\n  - command: \"(\"\n    name: Bad pattern\n",
+	} {
+		file, _ := ioutil.TempFile("", "names")
+		file.WriteString(content)
+		file.Close()
+
+		if _, err := ReadNamesFile(file.Name()); err == nil {
+			t.Errorf("Expected error for %q", content)
+		}
+		os.Remove(file.Name())
+	}
+}
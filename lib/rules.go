@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule types that can be added to a discovered monitor alongside its schedule
+var assertionRuleTypes = map[string]bool{
+	"run_time_exceeds":           true,
+	"ran_longer_than":            true,
+	"ran_less_than":              true,
+	"not_run_in":                 true,
+	"run_ping_not_received":      true,
+	"complete_ping_not_received": true,
+}
+
+var ruleTimeUnits = map[string]string{
+	"s": "seconds", "sec": "seconds", "second": "seconds", "seconds": "seconds",
+	"m": "minutes", "min": "minutes", "minute": "minutes", "minutes": "minutes",
+	"h": "hours", "hour": "hours", "hours": "hours",
+	"d": "days", "day": "days", "days": "days",
+}
+
+// ParseRule reads a rule written as "<rule type> <value> <unit>", e.g. "run_time_exceeds 30 minutes", or with a
+// duration, e.g. "ran_less_than 5s"
+func ParseRule(text string) (Rule, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 || len(fields) > 3 {
+		return Rule{}, errors.New(fmt.Sprintf("invalid rule \"%s\", expecting e.g. \"run_time_exceeds 30 minutes\"", text))
+	}
+
+	ruleType := strings.ToLower(fields[0])
+	if !assertionRuleTypes[ruleType] {
+		return Rule{}, errors.New(fmt.Sprintf("unknown rule type \"%s\"", fields[0]))
+	}
+
+	if len(fields) == 3 {
+		value, err := strconv.ParseUint(fields[1], 10, 32)
+		unit, ok := ruleTimeUnits[strings.ToLower(fields[2])]
+		if err != nil || !ok {
+			return Rule{}, errors.New(fmt.Sprintf("invalid rule \"%s\", expecting a whole number and a unit of seconds, minutes, hours or days", text))
+		}
+
+		return Rule{RuleType: ruleType, Value: strconv.FormatUint(value, 10), TimeUnit: unit}, nil
+	}
+
+	duration, err := time.ParseDuration(fields[1])
+	if err != nil || duration <= 0 {
+		return Rule{}, errors.New(fmt.Sprintf("invalid duration \"%s\" in rule \"%s\"", fields[1], text))
+	}
+
+	// Use the largest unit that expresses the duration exactly
+	for _, unit := range []struct {
+		duration time.Duration
+		name     string
+	}{{24 * time.Hour, "days"}, {time.Hour, "hours"}, {time.Minute, "minutes"}} {
+		if duration%unit.duration == 0 {
+			return Rule{RuleType: ruleType, Value: strconv.FormatInt(int64(duration/unit.duration), 10), TimeUnit: unit.name}, nil
+		}
+	}
+
+	return Rule{RuleType: ruleType, Value: strconv.FormatInt(int64(duration/time.Second), 10), TimeUnit: "seconds"}, nil
+}
+
+// MergeRules adds rules to a list, replacing any existing rule of the same type
+func MergeRules(rules []Rule, overrides []Rule) []Rule {
+	merged := append([]Rule{}, rules...)
+	for _, override := range overrides {
+		replaced := false
+		for i := range merged {
+			if merged[i].RuleType == override.RuleType {
+				merged[i] = override
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			merged = append(merged, override)
+		}
+	}
+
+	return merged
+}
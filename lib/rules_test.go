@@ -0,0 +1,52 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := map[string]Rule{
+		"run_time_exceeds 30 minutes": {RuleType: "run_time_exceeds", Value: "30", TimeUnit: "minutes"},
+		"ran_less_than 5 second":      {RuleType: "ran_less_than", Value: "5", TimeUnit: "seconds"},
+		"run_time_exceeds 2h":         {RuleType: "run_time_exceeds", Value: "2", TimeUnit: "hours"},
+		"ran_less_than 90s":           {RuleType: "ran_less_than", Value: "90", TimeUnit: "seconds"},
+	}
+
+	for text, expected := range tests {
+		if rule, err := ParseRule(text); err != nil || rule != expected {
+			t.Errorf("ParseRule(%q) = %+v, %v", text, rule, err)
+		}
+	}
+
+	for _, text := range []string{"run_time_exceeds", "not_a_rule 5 minutes", "ran_less_than 5 fortnights", "ran_less_than -5s"} {
+		if _, err := ParseRule(text); err == nil {
+			t.Errorf("Expected error for %q", text)
+		}
+	}
+}
+
+func TestMergeRules(t *testing.T) {
+	rules := []Rule{{RuleType: "run_time_exceeds", Value: "30", TimeUnit: "minutes"}}
+	merged := MergeRules(rules, []Rule{{RuleType: "run_time_exceeds", Value: "1", TimeUnit: "hours"}, {RuleType: "ran_less_than", Value: "5", TimeUnit: "seconds"}})
+
+	expected := []Rule{{RuleType: "run_time_exceeds", Value: "1", TimeUnit: "hours"}, {RuleType: "ran_less_than", Value: "5", TimeUnit: "seconds"}}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Unexpected merged rules: %+v", merged)
+	}
+
+	if rules[0].Value != "30" {
+		t.Error("MergeRules should not modify its input")
+	}
+}
+
+func TestParseCrontabDefaults(t *testing.T) {
+	defaults, err := ParseCrontabDefaults(`# cronitor-defaults: grace=10m rule="run_time_exceeds 1 hour" tags=reports`)
+	if err != nil || defaults.GraceSeconds != 600 || len(defaults.Rules) != 1 || defaults.Tags[0] != "reports" {
+		t.Errorf("Unexpected defaults: %+v, %v", defaults, err)
+	}
+
+	if _, err := ParseCrontabDefaults("# cronitor-defaults: name=everything"); err == nil {
+		t.Error("Expected error for a name in crontab defaults")
+	}
+}
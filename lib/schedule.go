@@ -0,0 +1,222 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cronKeywords = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+var cronDayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+type cronField struct {
+	name               string
+	min, max           int
+	names              map[string]int
+	allowsQuestionMark bool
+}
+
+var cronSecondField = cronField{"second", 0, 59, nil, false}
+var cronFields = []cronField{
+	{"minute", 0, 59, nil, false},
+	{"hour", 0, 23, nil, false},
+	{"day of month", 1, 31, nil, true},
+	{"month", 1, 12, cronMonthNames, false},
+	{"day of week", 0, 7, cronDayNames, true},
+}
+
+// CronSchedule is a parsed cron expression. Each field is a bitset of the values it matches.
+type CronSchedule struct {
+	Expression  string
+	Seconds     uint64
+	Minutes     uint64
+	Hours       uint64
+	DaysOfMonth uint64
+	Months      uint64
+	DaysOfWeek  uint64
+	IsReboot    bool
+	Warnings    []string
+	domIsStar   bool
+	dowIsStar   bool
+}
+
+// ParseCronExpression validates a five field cron expression, a six field expression with leading seconds, or an @keyword
+func ParseCronExpression(expression string) (*CronSchedule, error) {
+	schedule := CronSchedule{Expression: expression, Seconds: 1}
+	expression = strings.TrimSpace(expression)
+
+	if strings.HasPrefix(expression, "@") {
+		if strings.ToLower(expression) == "@reboot" {
+			schedule.IsReboot = true
+			return &schedule, nil
+		}
+
+		translated, ok := cronKeywords[strings.ToLower(expression)]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("unknown schedule keyword %s", expression))
+		}
+		expression = translated
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) == 6 {
+		seconds, err := parseCronField(fields[0], cronSecondField, &schedule)
+		if err != nil {
+			return nil, err
+		}
+		schedule.Seconds = seconds
+		fields = fields[1:]
+	} else if len(fields) != 5 {
+		return nil, errors.New(fmt.Sprintf("expected 5 fields in schedule, found %d", len(fields)))
+	}
+
+	bitsets := []*uint64{&schedule.Minutes, &schedule.Hours, &schedule.DaysOfMonth, &schedule.Months, &schedule.DaysOfWeek}
+	for i, field := range cronFields {
+		bits, err := parseCronField(fields[i], field, &schedule)
+		if err != nil {
+			return nil, err
+		}
+		*bitsets[i] = bits
+	}
+
+	// Sunday can be written as 0 or 7
+	if schedule.DaysOfWeek&(1<<7) > 0 {
+		schedule.DaysOfWeek |= 1
+	}
+
+	schedule.domIsStar = fields[2] == "*" || fields[2] == "?"
+	schedule.dowIsStar = fields[4] == "*" || fields[4] == "?"
+	return &schedule, nil
+}
+
+func parseCronField(value string, field cronField, schedule *CronSchedule) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(value, ",") {
+		rangePart, step := item, 1
+		if slash := strings.Index(item, "/"); slash >= 0 {
+			rangePart = item[:slash]
+			parsedStep, err := strconv.Atoi(item[slash+1:])
+			if err != nil || parsedStep < 1 {
+				return 0, errors.New(fmt.Sprintf("invalid step \"%s\" in %s field", item[slash+1:], field.name))
+			}
+			step = parsedStep
+		}
+
+		low, high := field.min, field.max
+		switch {
+		case rangePart == "*", rangePart == "?" && field.allowsQuestionMark:
+			// Matches the full range of the field
+		case strings.Contains(rangePart, "-"):
+			dash := strings.Index(rangePart, "-")
+			var err error
+			if low, err = parseCronValue(rangePart[:dash], field); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(rangePart[dash+1:], field); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, errors.New(fmt.Sprintf("range %s is backwards in %s field", rangePart, field.name))
+			}
+		default:
+			var err error
+			if low, err = parseCronValue(rangePart, field); err != nil {
+				return 0, err
+			}
+
+			high = low
+			if strings.Contains(item, "/") {
+				// Implementations disagree on what "5/10" means; most treat it as "5-max/10"
+				high = field.max
+				schedule.Warnings = append(schedule.Warnings, fmt.Sprintf("\"%s\" in %s field is interpreted differently by some cron implementations, use \"%s-%d/%d\"", item, field.name, rangePart, field.max, step))
+			}
+		}
+
+		for i := low; i <= high; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(value string, field cronField) (int, error) {
+	if field.names != nil {
+		if number, ok := field.names[strings.ToLower(value)]; ok {
+			return number, nil
+		}
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("invalid value \"%s\" in %s field", value, field.name))
+	}
+
+	if number < field.min || number > field.max {
+		return 0, errors.New(fmt.Sprintf("value %d out of range %d-%d in %s field", number, field.min, field.max, field.name))
+	}
+
+	return number, nil
+}
+
+// Matches reports whether the schedule fires at the minute (and second, for six field expressions) of t
+func (s CronSchedule) Matches(t time.Time) bool {
+	if s.IsReboot {
+		return false
+	}
+
+	if s.Seconds&(1<<uint(t.Second())) == 0 || s.Minutes&(1<<uint(t.Minute())) == 0 || s.Hours&(1<<uint(t.Hour())) == 0 || s.Months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	// When both day fields are restricted cron runs the job if either one matches
+	domMatches := s.DaysOfMonth&(1<<uint(t.Day())) > 0
+	dowMatches := s.DaysOfWeek&(1<<uint(t.Weekday())) > 0
+	if !s.domIsStar && !s.dowIsStar {
+		return domMatches || dowMatches
+	}
+
+	return domMatches && dowMatches
+}
+
+// RunsBetween returns the start times of every run, to the minute, from start up to but not including end
+func (s CronSchedule) RunsBetween(start, end time.Time) []time.Time {
+	var runs []time.Time
+	if s.IsReboot {
+		return runs
+	}
+
+	for t := start.Truncate(time.Minute); t.Before(end); t = t.Add(time.Minute) {
+		if s.Minutes&(1<<uint(t.Minute())) == 0 || s.Hours&(1<<uint(t.Hour())) == 0 {
+			continue
+		}
+
+		if s.Matches(t.Add(time.Duration(firstBit(s.Seconds)) * time.Second)) {
+			runs = append(runs, t)
+		}
+	}
+
+	return runs
+}
+
+func firstBit(bits uint64) int {
+	for i := 0; i < 64; i++ {
+		if bits&(1<<uint(i)) > 0 {
+			return i
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,59 @@
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronExpression(t *testing.T) {
+	valid := []string{"* * * * *", "0 * * * *", "*/15 0-6,22-23 * * Mon-Fri", "0 0 1 jan,jul *", "30 2 * * 7", "@daily", "@reboot", "0 0 12 ? * Mon", "0 5/10 * * *"}
+	for _, expression := range valid {
+		if _, err := ParseCronExpression(expression); err != nil {
+			t.Errorf("Expected '%s' to be valid, got: %s", expression, err)
+		}
+	}
+
+	invalid := []string{"* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "@fortnightly", "0 0 L * *", "? * * * *"}
+	for _, expression := range invalid {
+		if _, err := ParseCronExpression(expression); err == nil {
+			t.Errorf("Expected '%s' to be invalid", expression)
+		}
+	}
+
+	if schedule, _ := ParseCronExpression("0 5/10 * * *"); len(schedule.Warnings) != 1 {
+		t.Error("Expected a warning for a step on a single value")
+	}
+}
+
+func TestCronScheduleMatches(t *testing.T) {
+	tables := []struct {
+		expression string
+		time       string
+		expected   bool
+	}{
+		{"0 * * * *", "2020-03-02T05:00:00Z", true},
+		{"0 * * * *", "2020-03-02T05:01:00Z", false},
+		{"*/15 9-17 * * Mon-Fri", "2020-03-02T09:45:00Z", true},
+		{"*/15 9-17 * * Mon-Fri", "2020-03-01T09:45:00Z", false},
+		{"0 0 1 * Sun", "2020-03-01T00:00:00Z", true},
+		{"0 0 1 * Sun", "2020-03-08T00:00:00Z", true},
+		{"0 0 1 * Sun", "2020-03-09T00:00:00Z", false},
+		{"30 2 * * 7", "2020-03-08T02:30:00Z", true},
+	}
+
+	for _, table := range tables {
+		schedule, _ := ParseCronExpression(table.expression)
+		at, _ := time.Parse(time.RFC3339, table.time)
+		if schedule.Matches(at) != table.expected {
+			t.Errorf("Expected '%s' matching %s to be %t", table.expression, table.time, table.expected)
+		}
+	}
+}
+
+func TestCronScheduleRunsBetween(t *testing.T) {
+	schedule, _ := ParseCronExpression("*/20 * * * *")
+	start, _ := time.Parse(time.RFC3339, "2020-03-02T05:00:00Z")
+	if runs := schedule.RunsBetween(start, start.Add(2*time.Hour)); len(runs) != 6 {
+		t.Errorf("Expected 6 runs in two hours, got %d", len(runs))
+	}
+}
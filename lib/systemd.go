@@ -0,0 +1,545 @@
+package lib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const SYSTEMD_OVERRIDE_DIRECTORY = "/etc/systemd/system"
+const SYSTEMD_OVERRIDE_FILENAME = "cronitor.conf"
+
+// Unit directories in order of precedence, a unit in an earlier directory masks one with the same name in a later directory
+var SYSTEMD_UNIT_DIRECTORIES = []string{"/etc/systemd/system", "/run/systemd/system", "/usr/local/lib/systemd/system", "/lib/systemd/system", "/usr/lib/systemd/system"}
+
+var onCalendarShorthands = map[string]string{
+	"minutely":     "* * * * *",
+	"hourly":       "0 * * * *",
+	"daily":        "0 0 * * *",
+	"weekly":       "0 0 * * 1",
+	"monthly":      "0 0 1 * *",
+	"yearly":       "0 0 1 1 *",
+	"annually":     "0 0 1 1 *",
+	"quarterly":    "0 0 1 1,4,7,10 *",
+	"semiannually": "0 0 1 1,7 *",
+}
+
+var systemdTimespanUnits = map[string]time.Duration{
+	"":        time.Second,
+	"us":      time.Microsecond,
+	"usec":    time.Microsecond,
+	"ms":      time.Millisecond,
+	"msec":    time.Millisecond,
+	"s":       time.Second,
+	"sec":     time.Second,
+	"second":  time.Second,
+	"seconds": time.Second,
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"h":       time.Hour,
+	"hr":      time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"d":       24 * time.Hour,
+	"day":     24 * time.Hour,
+	"days":    24 * time.Hour,
+	"w":       7 * 24 * time.Hour,
+	"week":    7 * 24 * time.Hour,
+	"weeks":   7 * 24 * time.Hour,
+	"M":       2629800 * time.Second,
+	"month":   2629800 * time.Second,
+	"months":  2629800 * time.Second,
+	"y":       31557600 * time.Second,
+	"year":    31557600 * time.Second,
+	"years":   31557600 * time.Second,
+}
+
+var systemdTimespanRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Z]*)`)
+var onCalendarWeekdaysRegex = regexp.MustCompile(`(?i)^(mon|tue|wed|thu|fri|sat|sun)[a-z]*((\.\.|,)(mon|tue|wed|thu|fri|sat|sun)[a-z]*)*$`)
+var onCalendarDateRegex = regexp.MustCompile(`^[0-9*][0-9*.,/~]*-[0-9*.,/~-]+$`)
+var cronitorExecStartRegex = regexp.MustCompile(`cronitor(?:\s+--no-stdout)?\s+exec\s+([A-Za-z0-9]{3,12})\s`)
+
+// SystemdUnit holds the settings of a unit file, merged with any drop-in files, keyed by section and setting name
+type SystemdUnit struct {
+	Name     string
+	Filename string
+	Sections map[string]map[string][]string
+}
+
+func (u SystemdUnit) Get(section, key string) string {
+	values := u.GetAll(section, key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[len(values)-1]
+}
+
+func (u SystemdUnit) GetAll(section, key string) []string {
+	if settings, ok := u.Sections[section]; ok {
+		return settings[key]
+	}
+
+	return nil
+}
+
+func (u *SystemdUnit) read(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return errors.New(fmt.Sprintf("the unit file at %s could not be read; check permissions and try again", filename))
+	}
+	defer file.Close()
+
+	if u.Sections == nil {
+		u.Sections = map[string]map[string][]string{}
+	}
+
+	section := ""
+	continued := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// A trailing backslash continues the setting on the next line
+		if strings.HasSuffix(line, "\\") {
+			continued += strings.TrimSpace(strings.TrimSuffix(line, "\\")) + " "
+			continue
+		}
+		line = continued + line
+		continued = ""
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			if _, ok := u.Sections[section]; !ok {
+				u.Sections[section] = map[string][]string{}
+			}
+			continue
+		}
+
+		equalsPosition := strings.Index(line, "=")
+		if section == "" || equalsPosition < 1 {
+			continue
+		}
+
+		key := strings.TrimSpace(line[:equalsPosition])
+		value := strings.TrimSpace(line[equalsPosition+1:])
+
+		// Assigning an empty value resets any list built up by earlier files
+		if value == "" {
+			u.Sections[section][key] = nil
+		} else {
+			u.Sections[section][key] = append(u.Sections[section][key], value)
+		}
+	}
+
+	return scanner.Err()
+}
+
+type SystemdTimer struct {
+	Timer             *SystemdUnit
+	Service           *SystemdUnit
+	OverrideDirectory string
+	Code              string
+	Mon               Monitor
+}
+
+// Name returns the timer unit name without its suffix, e.g. "logrotate" for logrotate.timer
+func (t SystemdTimer) Name() string {
+	return strings.TrimSuffix(t.Timer.Name, ".timer")
+}
+
+func (t SystemdTimer) DisplayName() string {
+	return t.Timer.Filename
+}
+
+func (t SystemdTimer) ExecStart() string {
+	if t.Service == nil {
+		return ""
+	}
+
+	return t.Service.Get("Service", "ExecStart")
+}
+
+func (t SystemdTimer) RunAs() string {
+	if t.Service == nil {
+		return ""
+	}
+
+	return t.Service.Get("Service", "User")
+}
+
+func (t SystemdTimer) IsPersistent() bool {
+	persistent, _ := strconv.ParseBool(t.Timer.Get("Timer", "Persistent"))
+	return persistent
+}
+
+// CronExpression converts the timer's OnCalendar setting into a cron expression and timezone, if possible
+func (t SystemdTimer) CronExpression() (string, string, bool) {
+	calendars := t.Timer.GetAll("Timer", "OnCalendar")
+	if len(calendars) != 1 {
+		return "", "", false
+	}
+
+	return ConvertOnCalendar(calendars[0])
+}
+
+// Interval returns the repeating interval of a monotonic timer from OnUnitActiveSec or OnUnitInactiveSec
+func (t SystemdTimer) Interval() (time.Duration, bool) {
+	for _, key := range []string{"OnUnitActiveSec", "OnUnitInactiveSec"} {
+		if value := t.Timer.Get("Timer", key); value != "" {
+			if interval, err := ParseSystemdTimespan(value); err == nil && interval > 0 {
+				return interval, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// RandomizedDelay returns the RandomizedDelaySec systemd may add before the service is started
+func (t SystemdTimer) RandomizedDelay() time.Duration {
+	if value := t.Timer.Get("Timer", "RandomizedDelaySec"); value != "" {
+		if delay, err := ParseSystemdTimespan(value); err == nil {
+			return delay
+		}
+	}
+
+	return 0
+}
+
+func (t SystemdTimer) IsMonitorable() bool {
+	if t.Service == nil || len(t.Service.GetAll("Service", "ExecStart")) != 1 {
+		return false
+	}
+
+	_, _, hasSchedule := t.CronExpression()
+	_, hasInterval := t.Interval()
+	return hasSchedule || hasInterval
+}
+
+func (t SystemdTimer) OverrideFilename() string {
+	return filepath.Join(t.OverrideDirectory, t.Service.Name+".d", SYSTEMD_OVERRIDE_FILENAME)
+}
+
+// WriteOverride returns a drop-in that replaces the service ExecStart with one wrapped by cronitor exec
+func (t SystemdTimer) WriteOverride(executable string) string {
+	execStart := t.ExecStart()
+
+	// Keep any special executable prefixes, e.g. "-" to ignore failures, in front of the wrapper
+	prefix := ""
+	for len(execStart) > 0 && strings.ContainsAny(execStart[:1], "-@:+!") {
+		prefix += execStart[:1]
+		execStart = execStart[1:]
+	}
+
+	// With "@" the second word is passed as argv[0], which cannot be preserved through the wrapper
+	if strings.Contains(prefix, "@") {
+		prefix = strings.Replace(prefix, "@", "", -1)
+		if parts := strings.Fields(execStart); len(parts) > 1 {
+			execStart = strings.Join(append(parts[:1], parts[2:]...), " ")
+		}
+	}
+
+	stdoutFlag := ""
+	if t.Mon.NoStdoutPassthru {
+		stdoutFlag = " --no-stdout"
+	}
+
+	return fmt.Sprintf("# Added by cronitor discover\n[Service]\nExecStart=\nExecStart=%s%s%s exec %s %s\n", prefix, executable, stdoutFlag, t.Mon.Code, execStart)
+}
+
+func (t SystemdTimer) SaveOverride(override string) error {
+	filename := t.OverrideFilename()
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return errors.New(fmt.Sprintf("cannot create drop-in directory %s; check permissions and try again", filepath.Dir(filename)))
+	}
+
+	if ioutil.WriteFile(filename, []byte(override), 0644) != nil {
+		return errors.New(fmt.Sprintf("cannot write drop-in at %s; check permissions and try again", filename))
+	}
+
+	return nil
+}
+
+// ReadSystemdTimers finds timer units in the provided directories and pairs each with the service it activates.
+// Drop-in overrides are written to overrideDirectory.
+func ReadSystemdTimers(directories []string, overrideDirectory string) []*SystemdTimer {
+	var timers []*SystemdTimer
+	seen := map[string]bool{}
+
+	for _, directory := range directories {
+		files, err := ioutil.ReadDir(directory)
+		if err != nil {
+			continue
+		}
+
+		for _, f := range files {
+			if !strings.HasSuffix(f.Name(), ".timer") || seen[f.Name()] {
+				continue
+			}
+
+			// Units linked to /dev/null are masked
+			if target, err := os.Readlink(filepath.Join(directory, f.Name())); err == nil && target == "/dev/null" {
+				seen[f.Name()] = true
+				continue
+			}
+
+			seen[f.Name()] = true
+			timerUnit, err := readSystemdUnit(f.Name(), directories)
+			if err != nil {
+				continue
+			}
+
+			timer := SystemdTimer{Timer: timerUnit, OverrideDirectory: overrideDirectory}
+
+			serviceName := timerUnit.Get("Timer", "Unit")
+			if serviceName == "" {
+				serviceName = timer.Name() + ".service"
+			}
+
+			if serviceUnit, err := readSystemdUnit(serviceName, directories); err == nil {
+				timer.Service = serviceUnit
+				timer.Code = readOverrideCode(serviceName, directories, overrideDirectory)
+			}
+
+			timers = append(timers, &timer)
+		}
+	}
+
+	sort.Slice(timers, func(i, j int) bool { return timers[i].Timer.Name < timers[j].Timer.Name })
+	return timers
+}
+
+func readSystemdUnit(name string, directories []string) (*SystemdUnit, error) {
+	unit := SystemdUnit{Name: name}
+
+	for _, directory := range directories {
+		filename := filepath.Join(directory, name)
+		if _, err := os.Stat(filename); err == nil {
+			unit.Filename = filename
+			if err := unit.read(filename); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if unit.Filename == "" {
+		return nil, errors.New(fmt.Sprintf("unit %s not found", name))
+	}
+
+	// Apply drop-ins from every directory in lexical order, skipping our own so the original ExecStart is kept
+	var dropIns []string
+	for _, directory := range directories {
+		matches, _ := filepath.Glob(filepath.Join(directory, name+".d", "*.conf"))
+		for _, match := range matches {
+			if filepath.Base(match) != SYSTEMD_OVERRIDE_FILENAME {
+				dropIns = append(dropIns, match)
+			}
+		}
+	}
+
+	sort.Slice(dropIns, func(i, j int) bool { return filepath.Base(dropIns[i]) < filepath.Base(dropIns[j]) })
+	for _, dropIn := range dropIns {
+		unit.read(dropIn)
+	}
+
+	return &unit, nil
+}
+
+func readOverrideCode(serviceName string, directories []string, overrideDirectory string) string {
+	for _, directory := range append([]string{overrideDirectory}, directories...) {
+		override := SystemdUnit{}
+		if err := override.read(filepath.Join(directory, serviceName+".d", SYSTEMD_OVERRIDE_FILENAME)); err != nil {
+			continue
+		}
+
+		if ret := cronitorExecStartRegex.FindStringSubmatch(override.Get("Service", "ExecStart") + " "); ret != nil {
+			return ret[1]
+		}
+	}
+
+	return ""
+}
+
+// ParseSystemdTimespan parses time spans like "1h 30min" or "90" (seconds) as described in systemd.time(7)
+func ParseSystemdTimespan(span string) (time.Duration, error) {
+	var total time.Duration
+	remaining := strings.TrimSpace(span)
+	if remaining == "" {
+		return 0, errors.New("empty time span")
+	}
+
+	for remaining != "" {
+		ret := systemdTimespanRegex.FindStringSubmatch(remaining)
+		if ret == nil {
+			return 0, errors.New(fmt.Sprintf("invalid time span %s", span))
+		}
+
+		unit, ok := systemdTimespanUnits[ret[2]]
+		if !ok {
+			return 0, errors.New(fmt.Sprintf("invalid time span unit %s", ret[2]))
+		}
+
+		value, _ := strconv.ParseFloat(ret[1], 64)
+		total += time.Duration(value * float64(unit))
+		remaining = strings.TrimSpace(remaining[len(ret[0]):])
+	}
+
+	return total, nil
+}
+
+// ConvertOnCalendar converts a systemd OnCalendar expression into a five field cron expression and a timezone.
+// Expressions that cron cannot represent, like those with seconds, years or last-day-of-month, are not converted.
+func ConvertOnCalendar(calendar string) (string, string, bool) {
+	calendar = strings.TrimSpace(calendar)
+	if expression, ok := onCalendarShorthands[strings.ToLower(calendar)]; ok {
+		return expression, "", true
+	}
+
+	tokens := strings.Fields(calendar)
+	weekdays, date, clock, timezone := "*", "*-*-*", "00:00:00", ""
+
+	for i, token := range tokens {
+		switch {
+		case i == 0 && onCalendarWeekdaysRegex.MatchString(token):
+			weekdays = token
+		case strings.Contains(token, ":"):
+			clock = token
+		case onCalendarDateRegex.MatchString(token):
+			date = token
+		case i == len(tokens)-1:
+			if _, err := time.LoadLocation(token); err != nil {
+				return "", "", false
+			}
+			timezone = token
+		default:
+			return "", "", false
+		}
+	}
+
+	dateParts := strings.Split(date, "-")
+	if len(dateParts) == 2 {
+		dateParts = append([]string{"*"}, dateParts...)
+	}
+	if len(dateParts) != 3 || dateParts[0] != "*" || strings.Contains(date, "~") {
+		return "", "", false
+	}
+
+	clockParts := strings.Split(clock, ":")
+	if len(clockParts) == 2 {
+		clockParts = append(clockParts, "00")
+	}
+	if len(clockParts) != 3 {
+		return "", "", false
+	}
+	if seconds, err := strconv.Atoi(clockParts[2]); err != nil || seconds != 0 {
+		return "", "", false
+	}
+
+	fields := []struct {
+		value    string
+		min, max int
+	}{
+		{clockParts[1], 0, 59},
+		{clockParts[0], 0, 23},
+		{dateParts[2], 1, 31},
+		{dateParts[1], 1, 12},
+	}
+
+	var cronFields []string
+	for _, field := range fields {
+		converted, ok := convertCalendarComponent(field.value, field.min, field.max)
+		if !ok {
+			return "", "", false
+		}
+		cronFields = append(cronFields, converted)
+	}
+
+	convertedWeekdays, ok := convertCalendarWeekdays(weekdays)
+	if !ok {
+		return "", "", false
+	}
+	cronFields = append(cronFields, convertedWeekdays)
+
+	return strings.Join(cronFields, " "), timezone, true
+}
+
+func convertCalendarComponent(component string, min, max int) (string, bool) {
+	var items []string
+	for _, item := range strings.Split(component, ",") {
+		start, step := item, ""
+		if slash := strings.Index(item, "/"); slash >= 0 {
+			start, step = item[:slash], item[slash+1:]
+			if _, err := strconv.Atoi(step); err != nil {
+				return "", false
+			}
+		}
+
+		var converted string
+		if start == "*" {
+			converted = "*"
+		} else if bounds := strings.Split(start, ".."); len(bounds) == 2 {
+			low, lowErr := strconv.Atoi(bounds[0])
+			high, highErr := strconv.Atoi(bounds[1])
+			if lowErr != nil || highErr != nil || low < min || high > max || low > high {
+				return "", false
+			}
+			converted = fmt.Sprintf("%d-%d", low, high)
+		} else if value, err := strconv.Atoi(start); err == nil && value >= min && value <= max {
+			converted = strconv.Itoa(value)
+			// In systemd "5/15" repeats from 5 until the end of the range, cron needs the range spelled out
+			if step != "" {
+				converted = fmt.Sprintf("%d-%d", value, max)
+			}
+		} else {
+			return "", false
+		}
+
+		if step != "" {
+			converted = fmt.Sprintf("%s/%s", converted, step)
+		}
+
+		items = append(items, converted)
+	}
+
+	return strings.Join(items, ","), true
+}
+
+func convertCalendarWeekdays(weekdays string) (string, bool) {
+	if weekdays == "*" {
+		return "*", true
+	}
+
+	var items []string
+	for _, item := range strings.Split(weekdays, ",") {
+		var days []string
+		for _, day := range strings.Split(item, "..") {
+			if len(day) < 3 {
+				return "", false
+			}
+			days = append(days, strings.Title(strings.ToLower(day[:3])))
+		}
+
+		if len(days) > 2 {
+			return "", false
+		}
+		items = append(items, strings.Join(days, "-"))
+	}
+
+	return strings.Join(items, ","), true
+}
@@ -0,0 +1,104 @@
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConvertOnCalendar(t *testing.T) {
+	tables := []struct {
+		calendar   string
+		expression string
+		timezone   string
+		ok         bool
+	}{
+		{"daily", "0 0 * * *", "", true},
+		{"weekly", "0 0 * * 1", "", true},
+		{"*-*-* 04:00:00", "0 4 * * *", "", true},
+		{"Mon..Fri *-*-* 10:15", "15 10 * * Mon-Fri", "", true},
+		{"Sat,Sun 10:00", "0 10 * * Sat,Sun", "", true},
+		{"*:0/15", "0-59/15 * * * *", "", true},
+		{"*-*-01 06:00 Europe/Berlin", "0 6 1 * *", "Europe/Berlin", true},
+		{"*-01,07-01 00:00:00", "0 0 1 1,7 *", "", true},
+		{"2024-01-01 00:00:00", "", "", false},
+		{"*-*-* 04:00:30", "", "", false},
+		{"*-*~01 00:00", "", "", false},
+		{"not a calendar", "", "", false},
+	}
+
+	for _, table := range tables {
+		expression, timezone, ok := ConvertOnCalendar(table.calendar)
+		if expression != table.expression || timezone != table.timezone || ok != table.ok {
+			t.Errorf("Converting '%s' failed, got: '%s' '%s' %t, expected: '%s' '%s' %t", table.calendar, expression, timezone, ok, table.expression, table.timezone, table.ok)
+		}
+	}
+}
+
+func TestParseSystemdTimespan(t *testing.T) {
+	tables := []struct {
+		span     string
+		expected time.Duration
+	}{
+		{"90", 90 * time.Second},
+		{"15min", 15 * time.Minute},
+		{"1h 30min", 90 * time.Minute},
+		{"1h30m", 90 * time.Minute},
+		{"2d", 48 * time.Hour},
+		{"1w", 7 * 24 * time.Hour},
+	}
+
+	for _, table := range tables {
+		if span, err := ParseSystemdTimespan(table.span); err != nil || span != table.expected {
+			t.Errorf("Parsing '%s' failed, got: %s, expected: %s", table.span, span, table.expected)
+		}
+	}
+
+	if _, err := ParseSystemdTimespan("5 fortnights"); err == nil {
+		t.Error("Expected error for unknown time span unit")
+	}
+}
+
+func TestReadSystemdTimers(t *testing.T) {
+	timers := ReadSystemdTimers([]string{"../fixtures/systemd"}, "../fixtures/systemd")
+	byName := map[string]*SystemdTimer{}
+	for _, timer := range timers {
+		byName[timer.Name()] = timer
+	}
+
+	if len(timers) != 4 {
+		t.Fatalf("Expected 4 timers, got %d", len(timers))
+	}
+
+	backup := byName["backup"]
+	if backup.ExecStart() != "/usr/local/bin/backup.sh --all --compress" {
+		t.Errorf("Drop-in ExecStart not applied, got: %s", backup.ExecStart())
+	}
+	if expression, timezone, ok := backup.CronExpression(); !ok || expression != "30 2 * * Mon-Fri" || timezone != "UTC" {
+		t.Errorf("Unexpected schedule for backup timer: %s %s", expression, timezone)
+	}
+	if !backup.IsPersistent() || backup.RunAs() != "postgres" || backup.RandomizedDelay() != 5*time.Minute {
+		t.Error("Unexpected timer settings for backup timer")
+	}
+
+	cleanup := byName["cleanup"]
+	if interval, ok := cleanup.Interval(); !ok || interval != 90*time.Minute {
+		t.Errorf("Unexpected interval for cleanup timer: %s", interval)
+	}
+	if !cleanup.IsMonitorable() || cleanup.Service.Name != "tmp-cleanup.service" {
+		t.Error("Expected cleanup timer to be paired with tmp-cleanup.service")
+	}
+
+	cleanup.Mon.Code = "abc123"
+	expected := "# Added by cronitor discover\n[Service]\nExecStart=\nExecStart=-/usr/bin/cronitor exec abc123 /usr/bin/find /tmp -mtime +7 -delete\n"
+	if override := cleanup.WriteOverride("/usr/bin/cronitor"); override != expected {
+		t.Errorf("Unexpected override, got: %s", override)
+	}
+
+	if report := byName["report"]; report.Code != "d3x0c1" || report.ExecStart() != "/opt/reports/monthly.sh" {
+		t.Errorf("Existing integration not detected, got code '%s' and ExecStart '%s'", report.Code, report.ExecStart())
+	}
+
+	if orphan := byName["orphan"]; orphan.Service != nil || orphan.IsMonitorable() {
+		t.Error("Timer without a service should not be monitorable")
+	}
+}